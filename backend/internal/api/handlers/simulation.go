@@ -1,22 +1,42 @@
 package handlers
 
 import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/yourusername/visualization-backend/internal/analytics"
+	"github.com/yourusername/visualization-backend/internal/catalog"
 	"github.com/yourusername/visualization-backend/internal/simulation"
 )
 
+// maxTraceUploadBytes caps the size of an uploaded workload trace
+const maxTraceUploadBytes = 10 << 20
+
+// maxSimulationDurationSeconds caps the simulated time of a run: a day of ticks
+const maxSimulationDurationSeconds = 24 * 60 * 60
+
 type SimulationHandler struct {
 	analyticsService *analytics.Service
+	catalogRepo      *catalog.Repository
+	hardware         simulation.HardwareProvider // Specs and prices simulations run with
 }
 
-func NewSimulationHandler(analyticsService *analytics.Service) *SimulationHandler {
-	return &SimulationHandler{
+func NewSimulationHandler(analyticsService *analytics.Service, catalogRepo *catalog.Repository) *SimulationHandler {
+	h := &SimulationHandler{
 		analyticsService: analyticsService,
+		catalogRepo:      catalogRepo,
 	}
+	if catalogRepo != nil {
+		// Size and price simulated hardware from the catalog, cached in memory
+		h.hardware = simulation.NewCatalogProvider(catalogRepo, simulation.DefaultCatalogTTL)
+	}
+	return h
 }
 
 // RunSimulation handles POST /api/simulation/run
@@ -29,36 +49,25 @@ func (h *SimulationHandler) RunSimulation(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate input
-	if len(input.Nodes) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No nodes provided",
-		})
-	}
-
-	if input.Workload.RPS <= 0 {
+	// Validate input and fill in workload defaults
+	if message := h.prepareSimulationInput(&input); message != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "RPS must be greater than 0",
+			"error": message,
 		})
 	}
-
-	if input.Workload.DurationSeconds <= 0 {
-		input.Workload.DurationSeconds = 30 // Default 30 seconds
-	}
-
-	// Set defaults
-	if input.Workload.Mode == "" {
-		input.Workload.Mode = "constant"
-	}
-
-	if input.Workload.ReadWriteRatio.Read == 0 && input.Workload.ReadWriteRatio.Write == 0 {
-		input.Workload.ReadWriteRatio.Read = 80
-		input.Workload.ReadWriteRatio.Write = 20
+	validation := h.validateSimulationInput(&input)
+	if !validation.Valid {
+		return invalidInput(c, validation)
 	}
 
 	// Create and run simulation engine
 	startTime := time.Now()
 	engine := simulation.NewEngine(&input)
+
+	// Pin the resolved seed on the workload so the saved run can be replayed exactly
+	seed := engine.Seed()
+	input.Workload.Seed = &seed
+
 	output, err := engine.Run()
 
 	if err != nil {
@@ -66,6 +75,7 @@ func (h *SimulationHandler) RunSimulation(c *fiber.Ctx) error {
 			"error": err.Error(),
 		})
 	}
+	output.Warnings = append(diagnosticMessages(validation.Warnings), output.Warnings...)
 
 	// Auto-save simulation run to analytics (if user is authenticated and architecture_id is provided)
 	if h.analyticsService != nil {
@@ -86,6 +96,335 @@ func (h *SimulationHandler) RunSimulation(c *fiber.Ctx) error {
 	return c.JSON(output)
 }
 
+// ValidateSimulation handles POST /api/simulation/validate
+// Checks the input without simulating it and returns every error and warning
+// found, each pointing at its node, edge and field. Edges are also checked
+// against the catalog's connection rules.
+func (h *SimulationHandler) ValidateSimulation(c *fiber.Ctx) error {
+	var input simulation.SimulationInput
+
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	return c.JSON(h.validateSimulationInput(&input))
+}
+
+// validateSimulationInput checks an input against the catalog's connection
+// rules, warning when they can't be loaded
+func (h *SimulationHandler) validateSimulationInput(input *simulation.SimulationInput) *simulation.ValidationResult {
+	rules, err := h.connectionRules()
+	result := simulation.ValidateInput(input, rules)
+	if err != nil {
+		result.Warnings = append(result.Warnings, simulation.Diagnostic{
+			Severity: simulation.SeverityWarning,
+			Code:     "connection_rules_unavailable",
+			Message:  "Connection rules could not be loaded; edges were not checked against them",
+		})
+	}
+	return result
+}
+
+// invalidInput responds 400 with the diagnostics of an input that failed validation
+func invalidInput(c *fiber.Ctx, result *simulation.ValidationResult) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":    result.Errors[0].Message,
+		"errors":   result.Errors,
+		"warnings": result.Warnings,
+	})
+}
+
+// diagnosticMessages lists the messages of diagnostics, for output warnings
+func diagnosticMessages(diagnostics []simulation.Diagnostic) []string {
+	messages := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		messages = append(messages, d.Message)
+	}
+	return messages
+}
+
+// connectionRules loads the catalog's allowed connections
+func (h *SimulationHandler) connectionRules() ([]simulation.ConnectionRule, error) {
+	if h.catalogRepo == nil {
+		return nil, nil
+	}
+	catalogRules, err := h.catalogRepo.GetConnectionRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]simulation.ConnectionRule, 0, len(catalogRules))
+	for _, rule := range catalogRules {
+		if rule.IsAllowed {
+			rules = append(rules, simulation.ConnectionRule{SourceType: rule.SourceComponentType, TargetType: rule.TargetComponentType})
+		}
+	}
+	return rules, nil
+}
+
+// prepareSimulationInput validates a simulation request and fills in the
+// workload defaults and the hardware to simulate with. Returns the error
+// message for an invalid request.
+func (h *SimulationHandler) prepareSimulationInput(input *simulation.SimulationInput) string {
+	if len(input.Nodes) == 0 {
+		return "No nodes provided"
+	}
+	input.Hardware = h.hardware
+
+	// Scheduled workloads carry their own RPS in shape.points
+	scheduled := input.Workload.Mode == simulation.WorkloadPiecewise || input.Workload.Mode == simulation.WorkloadTrace
+	if scheduled && input.Workload.Shape.LastTick() == 0 {
+		return "Workload mode " + input.Workload.Mode + " needs shape.points"
+	}
+
+	if input.Workload.RPS <= 0 && !scheduled {
+		return "RPS must be greater than 0"
+	}
+
+	if input.Workload.DurationSeconds <= 0 {
+		input.Workload.DurationSeconds = 30 // Default 30 seconds
+		if scheduled {
+			input.Workload.DurationSeconds = input.Workload.Shape.LastTick() // Replay the whole schedule
+		}
+	}
+	if input.Workload.DurationSeconds > maxSimulationDurationSeconds {
+		return fmt.Sprintf("Duration must be at most %d seconds", maxSimulationDurationSeconds)
+	}
+
+	// Set defaults
+	if input.Workload.Mode == "" {
+		input.Workload.Mode = "constant"
+	}
+
+	if input.Workload.ReadWriteRatio.Read == 0 && input.Workload.ReadWriteRatio.Write == 0 {
+		input.Workload.ReadWriteRatio.Read = 80
+		input.Workload.ReadWriteRatio.Write = 20
+	}
+	return ""
+}
+
+// blastRadiusRequest is a simulation input plus the nodes to fail
+type blastRadiusRequest struct {
+	simulation.SimulationInput
+	NodeIDs []string `json:"nodeIds"` // Nodes to fail one at a time (default: every non-entry node)
+}
+
+// BlastRadius handles POST /api/simulation/blast-radius
+// Fails each node (or the chosen ones) in turn and returns them ranked by
+// the damage done, with the single points of failure in the graph
+func (h *SimulationHandler) BlastRadius(c *fiber.Ctx) error {
+	var request blastRadiusRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if message := h.prepareSimulationInput(&request.SimulationInput); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if validation := h.validateSimulationInput(&request.SimulationInput); !validation.Valid {
+		return invalidInput(c, validation)
+	}
+
+	known := make(map[string]bool, len(request.Nodes))
+	for _, node := range request.Nodes {
+		known[node.ID] = true
+	}
+	for _, nodeID := range request.NodeIDs {
+		if !known[nodeID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown node " + nodeID,
+			})
+		}
+	}
+
+	analysis, err := simulation.AnalyzeBlastRadius(&request.SimulationInput, request.NodeIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(analysis)
+}
+
+// monteCarloRequest is a simulation input plus the batch settings
+type monteCarloRequest struct {
+	simulation.SimulationInput
+	MonteCarlo simulation.MonteCarloConfig `json:"monteCarlo"`
+}
+
+// MonteCarlo handles POST /api/simulation/monte-carlo
+// Runs a batch of randomized simulations and returns the distributions of
+// the key metrics with confidence intervals and SLA breach probabilities
+func (h *SimulationHandler) MonteCarlo(c *fiber.Ctx) error {
+	var request monteCarloRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if message := h.prepareSimulationInput(&request.SimulationInput); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if validation := h.validateSimulationInput(&request.SimulationInput); !validation.Valid {
+		return invalidInput(c, validation)
+	}
+
+	if err := request.MonteCarlo.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := simulation.RunMonteCarlo(&request.SimulationInput, request.MonteCarlo)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// optimizeRequest is a simulation input plus the knobs the search may turn
+type optimizeRequest struct {
+	simulation.SimulationInput
+	Space simulation.OptimizationSpace `json:"space"`
+}
+
+// Optimize handles POST /api/simulation/optimize
+// Searches replica counts, instance types and optional caches for the
+// cheapest designs meeting the SLA and returns the cost/latency/error frontier
+func (h *SimulationHandler) Optimize(c *fiber.Ctx) error {
+	var request optimizeRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if message := h.prepareSimulationInput(&request.SimulationInput); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if validation := h.validateSimulationInput(&request.SimulationInput); !validation.Valid {
+		return invalidInput(c, validation)
+	}
+
+	if request.SLAConfig == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "slaConfig is required to optimize against",
+		})
+	}
+
+	result, err := simulation.Optimize(&request.SimulationInput, request.Space)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// breakingPointRequest is a simulation input plus the search settings
+type breakingPointRequest struct {
+	simulation.SimulationInput
+	Options simulation.BreakingPointOptions `json:"options"`
+}
+
+// BreakingPoint handles POST /api/simulation/breaking-point
+// Searches for the RPS at which the design stops meeting its SLA and reports
+// the first node to saturate and the headroom at the current load
+func (h *SimulationHandler) BreakingPoint(c *fiber.Ctx) error {
+	var request breakingPointRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if message := h.prepareSimulationInput(&request.SimulationInput); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if validation := h.validateSimulationInput(&request.SimulationInput); !validation.Valid {
+		return invalidInput(c, validation)
+	}
+
+	result, err := simulation.FindBreakingPoint(&request.SimulationInput, request.Options)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// ParseTrace handles POST /api/simulation/trace
+// Accepts a recorded RPS trace as CSV or JSON, either as a multipart "file"
+// upload or as the raw request body, and returns it as workload shape points
+// ready to use with mode "trace"
+func (h *SimulationHandler) ParseTrace(c *fiber.Ctx) error {
+	format := c.Query("format")
+	data := c.Body()
+
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read uploaded trace",
+			})
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(io.LimitReader(file, maxTraceUploadBytes)); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read uploaded trace",
+			})
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	}
+
+	points, err := simulation.ParseWorkloadTrace(data, format)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trace: " + err.Error(),
+		})
+	}
+
+	shape := &simulation.WorkloadShape{Points: points}
+	peakRPS := 0.0
+	for _, point := range points {
+		peakRPS = math.Max(peakRPS, point.RPS)
+	}
+
+	return c.JSON(fiber.Map{
+		"mode":            simulation.WorkloadTrace,
+		"shape":           shape,
+		"durationSeconds": shape.LastTick(),
+		"samples":         len(points),
+		"peakRps":         peakRPS,
+	})
+}
+
 // EstimateCost handles POST /api/simulation/estimate-cost
 // Returns estimated monthly cost BEFORE running simulation
 func (h *SimulationHandler) EstimateCost(c *fiber.Ctx) error {
@@ -97,12 +436,16 @@ func (h *SimulationHandler) EstimateCost(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate input
-	if len(input.Nodes) == 0 {
+	// Validate input and fill in workload defaults
+	if message := h.prepareSimulationInput(&input); message != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No nodes provided",
+			"error": message,
 		})
 	}
+	validation := h.validateSimulationInput(&input)
+	if !validation.Valid {
+		return invalidInput(c, validation)
+	}
 
 	// Create engine to access cost calculation methods
 	engine := simulation.NewEngine(&input)
@@ -152,12 +495,12 @@ func (h *SimulationHandler) EstimateCost(c *fiber.Ctx) error {
 		}
 
 		// Categorize cost
-		switch {
-		case nodeType == "api_server" || nodeType == "web_server" || nodeType == "microservice" || nodeType == "worker":
+		switch simulation.ComponentCategory(nodeType) {
+		case simulation.ComponentCompute:
 			computeCost += nodeCost
-		case nodeType == "database_sql" || nodeType == "database_nosql" || nodeType == "object_storage" || nodeType == "cache_redis":
+		case simulation.ComponentDatabase, simulation.ComponentCache, simulation.ComponentStorage:
 			storageCost += nodeCost
-		case nodeType == "load_balancer" || nodeType == "api_gateway" || nodeType == "cdn":
+		case simulation.ComponentNetwork, simulation.ComponentCDN:
 			networkCost += nodeCost
 		default:
 			otherCost += nodeCost
@@ -182,6 +525,7 @@ func (h *SimulationHandler) EstimateCost(c *fiber.Ctx) error {
 		"componentCosts": breakdown,
 		"nodeCount":      len(input.Nodes),
 		"edgeCount":      len(input.Edges),
+		"warnings":       diagnosticMessages(validation.Warnings),
 	})
 }
 
@@ -268,6 +612,119 @@ func (h *SimulationHandler) GetSimulationPresets(c *fiber.Ctx) error {
 				},
 			},
 		},
+		{
+			"id":          "ramp-up",
+			"name":        "Ramp Up",
+			"description": "Traffic climbs in steps to find where the design starts to struggle",
+			"workload": fiber.Map{
+				"rps":             40000,
+				"mode":            simulation.WorkloadRamp,
+				"durationSeconds": 120,
+				"readWriteRatio":  fiber.Map{"read": 80, "write": 20},
+				"regions":         []string{"us-east"},
+				"shape": fiber.Map{
+					"startRps":    2000,
+					"rampSeconds": 100,
+					"steps":       5,
+				},
+			},
+		},
+		{
+			"id":          "diurnal-cycle",
+			"name":        "Diurnal Cycle",
+			"description": "A compressed day/night cycle swinging 70% around the mean",
+			"workload": fiber.Map{
+				"rps":             10000,
+				"mode":            simulation.WorkloadDiurnal,
+				"durationSeconds": 240,
+				"readWriteRatio":  fiber.Map{"read": 85, "write": 15},
+				"regions":         []string{"us-east", "eu-central"},
+				"shape": fiber.Map{
+					"periodSeconds": 240,
+					"amplitude":     0.7,
+				},
+			},
+		},
+		{
+			"id":          "poisson-arrivals",
+			"name":        "Poisson Arrivals",
+			"description": "Realistic per-second jitter around a steady mean",
+			"workload": fiber.Map{
+				"rps":             5000,
+				"mode":            simulation.WorkloadPoisson,
+				"durationSeconds": 60,
+				"readWriteRatio":  fiber.Map{"read": 80, "write": 20},
+				"regions":         []string{"us-east"},
+			},
+		},
+		{
+			"id":          "global-users",
+			"name":        "Global Users",
+			"description": "Users spread over three continents; pair with a DNS node for latency-based routing",
+			"workload": fiber.Map{
+				"rps":             20000,
+				"mode":            "constant",
+				"durationSeconds": 60,
+				"readWriteRatio":  fiber.Map{"read": 85, "write": 15},
+				"regions":         []string{"us-east", "eu-west", "ap-southeast"},
+				"userDistribution": fiber.Map{
+					"us-east":      60,
+					"eu-west":      30,
+					"ap-southeast": 10,
+				},
+			},
+		},
+		{
+			"id":          "black-friday",
+			"name":        "Black Friday",
+			"description": "Replays a Black Friday curve: doors-open surge, sustained peak and evening tail",
+			"workload": fiber.Map{
+				"rps":             8000,
+				"mode":            simulation.WorkloadPiecewise,
+				"durationSeconds": 180,
+				"readWriteRatio":  fiber.Map{"read": 90, "write": 10},
+				"regions":         []string{"us-east"},
+				"shape": fiber.Map{
+					"interpolate": true,
+					"points": []fiber.Map{
+						{"tick": 1, "rps": 8000},
+						{"tick": 20, "rps": 12000},
+						{"tick": 30, "rps": 60000},
+						{"tick": 45, "rps": 45000},
+						{"tick": 90, "rps": 40000},
+						{"tick": 120, "rps": 25000},
+						{"tick": 180, "rps": 10000},
+					},
+				},
+			},
+		},
+		{
+			"id":          "game-day",
+			"name":        "Game Day",
+			"description": "Chaos experiment: recurring AZ outages that fire half the time plus random network degradation",
+			"workload": fiber.Map{
+				"rps":             5000,
+				"mode":            "constant",
+				"durationSeconds": 300,
+				"readWriteRatio":  fiber.Map{"read": 80, "write": 20},
+				"regions":         []string{"us-east"},
+				"failures": []fiber.Map{
+					{
+						"type":             simulation.FailureAZOutage,
+						"region":           "us-east",
+						"availabilityZone": "us-east-1a",
+						"probability":      0.5,
+						"schedule":         fiber.Map{"type": simulation.ScheduleRecurring, "intervalSeconds": 60, "durationSeconds": 15, "jitterSeconds": 10},
+					},
+					{
+						"type":     simulation.FailureNetworkDelay,
+						"region":   "us-east",
+						"delayMs":  150,
+						"schedule": fiber.Map{"type": simulation.ScheduleRandom, "intervalSeconds": 90, "durationSeconds": 20},
+					},
+				},
+			},
+		},
 	}
 
 	return c.JSON(fiber.Map{
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/visualization-backend/internal/simulation"
+)
+
+// Stream pacing: defaultStreamTickInterval plays a run in real time, one
+// simulated second per second; requested intervals are kept within the bounds
+// so an unpaced stream can't flood the socket and a slow one still ends
+const (
+	defaultStreamTickInterval = time.Second
+	minStreamTickInterval     = 10 * time.Millisecond
+	maxStreamTickInterval     = 10 * time.Second
+)
+
+// streamPauseTimeout ends a stream paused this long without a message from
+// its client, so abandoned streams don't hold their engine forever
+var streamPauseTimeout = 10 * time.Minute
+
+// streamControl is a message from the client of a simulation stream. Start
+// carries the input; the change types carry a simulation.Change.
+type streamControl struct {
+	Type           string                      `json:"type"` // "start", "pause", "resume", "stop" or a change type
+	Input          *simulation.SimulationInput `json:"input,omitempty"`
+	TickIntervalMs *int                        `json:"tickIntervalMs,omitempty"` // Wall-clock time between ticks (10ms to 10s; 0 = as fast as allowed)
+}
+
+// StreamSimulation handles the /ws/simulation WebSocket
+// The client sends {"type":"start","input":{...}}; every tick is then sent as
+// {"type":"tick","point":{...}} while the client may pause, resume, stop,
+// change the RPS, inject or clear failures and change replicas. The run ends
+// with {"type":"complete","output":{...}} (aggregates, without the ticks).
+// A stream paused for streamPauseTimeout without a message is stopped.
+func (h *SimulationHandler) StreamSimulation(c *websocket.Conn) {
+	// Read client messages on their own goroutine so ticks keep flowing
+	messages := make(chan []byte)
+	closed := make(chan struct{})
+	defer func() {
+		// Unblock the reader and wait for it before the connection is released
+		close(closed)
+		c.Close()
+		for range messages {
+		}
+	}()
+	go func() {
+		defer close(messages)
+		for {
+			messageType, data, err := c.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+					log.Printf("⚠️  Simulation stream error: %v", err)
+				}
+				return
+			}
+			if messageType != websocket.TextMessage {
+				continue
+			}
+			select {
+			case messages <- data:
+			case <-closed:
+				return
+			}
+		}
+	}()
+
+	send := func(message fiber.Map) bool {
+		return c.WriteJSON(message) == nil
+	}
+	sendError := func(message string) bool {
+		return send(fiber.Map{"type": "error", "error": message})
+	}
+
+	// Wait for the input
+	var start streamControl
+	for start.Input == nil {
+		data, ok := <-messages
+		if !ok {
+			return
+		}
+		if err := json.Unmarshal(data, &start); err != nil || start.Type != "start" || start.Input == nil {
+			start = streamControl{}
+			if !sendError("Send {\"type\":\"start\",\"input\":{...}} first") {
+				return
+			}
+		}
+	}
+
+	input := start.Input
+	if message := h.prepareSimulationInput(input); message != "" {
+		sendError(message)
+		return
+	}
+	validation := h.validateSimulationInput(input)
+	if !validation.Valid {
+		send(fiber.Map{"type": "error", "error": validation.Errors[0].Message, "errors": validation.Errors, "warnings": validation.Warnings})
+		return
+	}
+	interval := defaultStreamTickInterval
+	if start.TickIntervalMs != nil && *start.TickIntervalMs >= 0 {
+		interval = time.Duration(*start.TickIntervalMs) * time.Millisecond
+		if interval < minStreamTickInterval {
+			interval = minStreamTickInterval
+		}
+		if interval > maxStreamTickInterval {
+			interval = maxStreamTickInterval
+		}
+	}
+
+	engine := simulation.NewEngine(input)
+	if err := engine.Start(); err != nil {
+		sendError(err.Error())
+		return
+	}
+	if !send(fiber.Map{"type": "started", "seed": engine.Seed(), "durationSeconds": input.Workload.DurationSeconds}) {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	idle := time.NewTimer(streamPauseTimeout)
+	idle.Stop()
+	defer idle.Stop()
+
+	paused, stopped, tick := false, false, 0
+	for !engine.Done() && !stopped {
+		// While paused, no ticks fire and the idle timer runs instead
+		next, timeout := ticker.C, (<-chan time.Time)(nil)
+		if paused {
+			next, timeout = nil, idle.C
+		}
+
+		select {
+		case data, ok := <-messages:
+			if !ok {
+				return // Client went away
+			}
+			if paused {
+				resetTimer(idle, streamPauseTimeout)
+			}
+			var control streamControl
+			if err := json.Unmarshal(data, &control); err != nil {
+				if !sendError("Invalid message") {
+					return
+				}
+				continue
+			}
+
+			var reply fiber.Map
+			switch control.Type {
+			case "pause":
+				if !paused {
+					resetTimer(idle, streamPauseTimeout)
+				}
+				paused = true
+				reply = fiber.Map{"type": "paused", "tick": tick}
+			case "resume":
+				if paused {
+					// Drop a tick buffered before the pause so the next one is a full interval away
+					idle.Stop()
+					ticker.Reset(interval)
+					select {
+					case <-ticker.C:
+					default:
+					}
+				}
+				paused = false
+				reply = fiber.Map{"type": "resumed", "tick": tick}
+			case "stop":
+				stopped = true
+				reply = fiber.Map{"type": "stopped", "tick": tick}
+			default:
+				var change simulation.Change
+				if err := json.Unmarshal(data, &change); err != nil {
+					reply = fiber.Map{"type": "error", "error": "Invalid change"}
+				} else if err := engine.ApplyChange(change); err != nil {
+					reply = fiber.Map{"type": "error", "error": err.Error()}
+				} else {
+					reply = fiber.Map{"type": "applied", "change": change, "tick": tick}
+				}
+			}
+			if !send(reply) {
+				return
+			}
+
+		case <-timeout:
+			stopped = true
+			if !send(fiber.Map{"type": "stopped", "tick": tick, "reason": "paused too long"}) {
+				return
+			}
+
+		case <-next:
+			point, err := engine.Step()
+			if err != nil {
+				sendError(err.Error())
+				return
+			}
+			tick = point.Tick
+			if !send(fiber.Map{"type": "tick", "point": point}) {
+				return
+			}
+		}
+	}
+
+	if tick == 0 {
+		send(fiber.Map{"type": "complete"})
+		return
+	}
+	// The client already has every tick; send only the aggregates
+	output, err := engine.Finalize()
+	if err != nil {
+		sendError(err.Error())
+		return
+	}
+	output.TimeSeries = nil
+	output.Warnings = append(diagnosticMessages(validation.Warnings), output.Warnings...)
+	send(fiber.Map{"type": "complete", "output": output})
+}
+
+// resetTimer restarts a timer, dropping an expiry nobody received
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
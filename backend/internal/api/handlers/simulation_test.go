@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fws "github.com/fasthttp/websocket"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/visualization-backend/internal/simulation"
+)
+
+// Designs the simulation endpoints are tried with
+const (
+	validDesign = `"nodes":[{"id":"client","data":{"nodeType":"client","config":{}}},{"id":"api","data":{"nodeType":"api_server","config":{}}}],
+		"edges":[{"id":"e1","source":"client","target":"api"}],
+		"workload":{"rps":100,"durationSeconds":5,"mode":"constant","seed":1}`
+	danglingDesign = `"nodes":[{"id":"client","data":{"nodeType":"client","config":{}}},{"id":"api","data":{"nodeType":"api_server","config":{}}}],
+		"edges":[{"id":"e1","source":"client","target":"api"},{"id":"e2","source":"api","target":"missing"}],
+		"workload":{"rps":100,"durationSeconds":5,"mode":"constant","seed":1}`
+	unknownTypeDesign = `"nodes":[{"id":"client","data":{"nodeType":"client","config":{}}},{"id":"api","data":{"nodeType":"api_servr","config":{}}}],
+		"edges":[{"id":"e1","source":"client","target":"api"}],
+		"workload":{"rps":100,"durationSeconds":5,"mode":"constant","seed":1}`
+)
+
+// simulationEndpoints are the endpoints that simulate an input, with what
+// each needs besides the design
+var simulationEndpoints = []struct {
+	path  string
+	extra string
+}{
+	{"/run", ""},
+	{"/estimate-cost", ""},
+	{"/blast-radius", ""},
+	{"/monte-carlo", `,"monteCarlo":{"iterations":2}`},
+	{"/optimize", `,"slaConfig":{"p95LatencyMs":500},"space":{"replicas":{"api":{"min":1,"max":2}}}`},
+	{"/breaking-point", `,"options":{"maxRps":1000}`},
+}
+
+func newSimulationApp() *fiber.App {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	h := NewSimulationHandler(nil, nil)
+	app.Post("/run", h.RunSimulation)
+	app.Post("/estimate-cost", h.EstimateCost)
+	app.Post("/blast-radius", h.BlastRadius)
+	app.Post("/monte-carlo", h.MonteCarlo)
+	app.Post("/optimize", h.Optimize)
+	app.Post("/breaking-point", h.BreakingPoint)
+	app.Post("/validate", h.ValidateSimulation)
+	return app
+}
+
+// post sends a JSON body and decodes the JSON response
+func post(t *testing.T, app *fiber.App, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, 10000)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	var decoded map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("%s: decoding response: %v", path, err)
+	}
+	return resp.StatusCode, decoded
+}
+
+// diagnosticCodes lists the codes of a response's errors or warnings
+func diagnosticCodes(response map[string]interface{}, key string) []string {
+	codes := []string{}
+	diagnostics, _ := response[key].([]interface{})
+	for _, d := range diagnostics {
+		if diagnostic, ok := d.(map[string]interface{}); ok {
+			codes = append(codes, diagnostic["code"].(string))
+		}
+	}
+	return codes
+}
+
+func TestSimulationEndpointsRejectInvalidInput(t *testing.T) {
+	app := newSimulationApp()
+	for _, endpoint := range simulationEndpoints {
+		status, response := post(t, app, endpoint.path, "{"+danglingDesign+endpoint.extra+"}")
+		if status != fiber.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", endpoint.path, status)
+			continue
+		}
+		if codes := diagnosticCodes(response, "errors"); len(codes) != 1 || codes[0] != simulation.DiagUnknownEdgeTarget {
+			t.Errorf("%s: error codes %v, want [%s]", endpoint.path, codes, simulation.DiagUnknownEdgeTarget)
+		}
+	}
+
+	// /validate reports the same diagnostics with a 200
+	status, response := post(t, app, "/validate", "{"+danglingDesign+"}")
+	if codes := diagnosticCodes(response, "errors"); status != fiber.StatusOK || response["valid"] != false ||
+		len(codes) != 1 || codes[0] != simulation.DiagUnknownEdgeTarget {
+		t.Errorf("/validate: status %d, response %v", status, response)
+	}
+}
+
+func TestSimulationEndpointsAcceptValidInput(t *testing.T) {
+	app := newSimulationApp()
+	for _, endpoint := range simulationEndpoints {
+		if status, response := post(t, app, endpoint.path, "{"+validDesign+endpoint.extra+"}"); status != fiber.StatusOK {
+			t.Errorf("%s: status %d (%v), want 200", endpoint.path, status, response["error"])
+		}
+	}
+}
+
+// Warnings don't block a run; they come back with its output
+func TestRunReportsValidationWarnings(t *testing.T) {
+	app := newSimulationApp()
+	status, response := post(t, app, "/run", "{"+unknownTypeDesign+"}")
+	if status != fiber.StatusOK {
+		t.Fatalf("status %d (%v), want 200", status, response["error"])
+	}
+	warnings, _ := response["warnings"].([]interface{})
+	found := false
+	for _, warning := range warnings {
+		found = found || strings.Contains(warning.(string), "api_servr")
+	}
+	if !found {
+		t.Errorf("warnings %v, want the unknown node type", warnings)
+	}
+}
+
+// dialStream serves the simulation stream and connects to it
+func dialStream(t *testing.T) *fws.Conn {
+	t.Helper()
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", websocket.New(NewSimulationHandler(nil, nil).StreamSimulation))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	conn, _, err := fws.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readFrame reads the next stream frame, failing after a few seconds
+func readFrame(t *testing.T, conn *fws.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame map[string]interface{}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestStreamRejectsInvalidInput(t *testing.T) {
+	conn := dialStream(t)
+	if err := conn.WriteMessage(fws.TextMessage, []byte(`{"type":"start","input":{`+danglingDesign+`}}`)); err != nil {
+		t.Fatal(err)
+	}
+	frame := readFrame(t, conn)
+	if codes := diagnosticCodes(frame, "errors"); frame["type"] != "error" || len(codes) != 1 || codes[0] != simulation.DiagUnknownEdgeTarget {
+		t.Errorf("frame %v, want an error frame with %s", frame, simulation.DiagUnknownEdgeTarget)
+	}
+}
+
+func TestSimulationDurationIsCapped(t *testing.T) {
+	app := newSimulationApp()
+	tooLong := strings.Replace(validDesign, `"durationSeconds":5`, `"durationSeconds":86401`, 1)
+	for _, endpoint := range simulationEndpoints {
+		if status, response := post(t, app, endpoint.path, "{"+tooLong+endpoint.extra+"}"); status != fiber.StatusBadRequest {
+			t.Errorf("%s: status %d (%v), want 400", endpoint.path, status, response["error"])
+		}
+	}
+
+	conn := dialStream(t)
+	if err := conn.WriteMessage(fws.TextMessage, []byte(`{"type":"start","tickIntervalMs":0,"input":{`+tooLong+`}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if frame := readFrame(t, conn); frame["type"] != "error" {
+		t.Errorf("stream: frame %v, want an error", frame)
+	}
+}
+
+// A stream left paused stops and reports what it has
+func TestStreamStopsWhenPausedTooLong(t *testing.T) {
+	defer func(timeout time.Duration) { streamPauseTimeout = timeout }(streamPauseTimeout)
+	streamPauseTimeout = 100 * time.Millisecond
+
+	conn := dialStream(t)
+	slow := strings.Replace(validDesign, `"durationSeconds":5`, `"durationSeconds":1000`, 1)
+	if err := conn.WriteMessage(fws.TextMessage, []byte(`{"type":"start","tickIntervalMs":5000,"input":{`+slow+`}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if frame := readFrame(t, conn); frame["type"] != "started" {
+		t.Fatalf("frame %v, want started", frame)
+	}
+	if err := conn.WriteMessage(fws.TextMessage, []byte(`{"type":"pause"}`)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"paused", "stopped", "complete"} {
+		if frame := readFrame(t, conn); frame["type"] != want {
+			t.Fatalf("frame %v, want %s", frame, want)
+		}
+	}
+}
@@ -33,7 +33,8 @@ func Setup(app *fiber.App, repo *database.Repository, jwtService *auth.JWTServic
 	
 	// Initialize analytics service (used by simulation handler)
 	analyticsService := analytics.NewService(repo.DB())
-	simulationHandler := handlers.NewSimulationHandler(analyticsService)
+	catalogRepo := catalog.NewRepository(repo.DB())
+	simulationHandler := handlers.NewSimulationHandler(analyticsService, catalogRepo)
 	
 	collaborationHandler := handlers.NewCollaborationHandler(hub, repo)
 	exportHandler := handlers.NewExportHandler()
@@ -41,7 +42,6 @@ func Setup(app *fiber.App, repo *database.Repository, jwtService *auth.JWTServic
 	stripeHandler := handlers.NewStripeHandler(repo, stripe, cfg.Stripe.WebhookSecret, cfg.Stripe.FrontendURL)
 
 	// Initialize catalog handler
-	catalogRepo := catalog.NewRepository(repo.DB())
 	catalogHandler := catalog.NewHandler(catalogRepo)
 
 	// Initialize gallery service and handler
@@ -106,6 +106,12 @@ func Setup(app *fiber.App, repo *database.Repository, jwtService *auth.JWTServic
 	simulationGroup.Post("/run", simulationHandler.RunSimulation)
 	simulationGroup.Post("/estimate-cost", simulationHandler.EstimateCost)
 	simulationGroup.Get("/presets", simulationHandler.GetSimulationPresets)
+	simulationGroup.Post("/trace", simulationHandler.ParseTrace)
+	simulationGroup.Post("/blast-radius", simulationHandler.BlastRadius)
+	simulationGroup.Post("/monte-carlo", simulationHandler.MonteCarlo)
+	simulationGroup.Post("/optimize", simulationHandler.Optimize)
+	simulationGroup.Post("/breaking-point", simulationHandler.BreakingPoint)
+	simulationGroup.Post("/validate", simulationHandler.ValidateSimulation)
 
 	// Subscription plans routes (public)
 	subscriptionGroup := api.Group("/subscription")
@@ -126,6 +132,9 @@ func Setup(app *fiber.App, repo *database.Repository, jwtService *auth.JWTServic
 	// Real-time Collaboration WebSocket (NEW)
 	app.Get("/ws/collaborate", websocket.New(collaborationHandler.HandleWebSocket))
 
+	// Live simulation stream with pause/resume and mid-run changes
+	app.Get("/ws/simulation", websocket.New(simulationHandler.StreamSimulation))
+
 	// Collaboration session info endpoint
 	collaborationGroup := api.Group("/collaboration")
 	collaborationGroup.Get("/sessions/:sessionId", collaborationHandler.GetSessionInfo)
@@ -0,0 +1,273 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Metrics an autoscaling policy can track
+const (
+	ScalingMetricCPU        = "cpu"         // Average CPU % across replicas
+	ScalingMetricRPS        = "rps"         // Incoming RPS per replica
+	ScalingMetricQueueDepth = "queue_depth" // Backlog per replica in the queues the node consumes from
+	ScalingMetricLoad       = "load"        // Incoming RPS / effective capacity (the global workload policy)
+)
+
+// DefaultProvisioningDelayTicks is how long a new replica boots before it serves traffic
+const DefaultProvisioningDelayTicks = 3
+
+// targetTrackingTolerance keeps target tracking from flapping around the target
+const targetTrackingTolerance = 0.1
+
+// scalingState is a node's resolved autoscaling policy plus its scaling history
+type scalingState struct {
+	policy       AutoScalingPolicy
+	pending      []int // Ticks at which booting replicas start serving
+	lastScaleOut int
+	lastScaleIn  int
+	hasScaledOut bool
+	hasScaledIn  bool
+}
+
+// resolveScalingPolicies gives every node its autoscaling policy: the node's
+// own "autoscaling" config when present, otherwise the workload-wide policy
+// for scalable types when that one is enabled
+func (e *Engine) resolveScalingPolicies() {
+	for _, node := range e.input.Nodes {
+		state := e.state.NodeStates[node.ID]
+		if state == nil {
+			continue
+		}
+		state.scaling = nil
+
+		if raw, ok := node.Data.Config["autoscaling"]; ok {
+			policy := AutoScalingPolicy{Enabled: true}
+			if encoded, err := json.Marshal(raw); err == nil && json.Unmarshal(encoded, &policy) == nil && policy.Enabled {
+				state.scaling = &scalingState{policy: policy.withDefaults()}
+			}
+			continue
+		}
+
+		if global := e.config.AutoScaling; global != nil && global.Enabled && canScale(state.Type) {
+			state.scaling = &scalingState{policy: global.toPolicy()}
+		}
+	}
+}
+
+// toPolicy expresses the workload-wide thresholds as a step scaling policy on load
+func (c *AutoScalingConfig) toPolicy() AutoScalingPolicy {
+	up, down := c.UpThreshold, c.DownThreshold
+	return AutoScalingPolicy{
+		Enabled:         true,
+		Metric:          ScalingMetricLoad,
+		MinReplicas:     c.MinReplicas,
+		MaxReplicas:     c.MaxReplicas,
+		CooldownSeconds: c.CooldownSeconds,
+		Steps: []ScalingStep{
+			{LowerBound: &up, Adjustment: 1},
+			{UpperBound: &down, Adjustment: -1},
+		},
+	}.withDefaults()
+}
+
+// withDefaults fills in unset policy fields
+func (p AutoScalingPolicy) withDefaults() AutoScalingPolicy {
+	if p.Metric == "" {
+		p.Metric = ScalingMetricCPU
+	}
+	if p.MinReplicas <= 0 {
+		p.MinReplicas = 1
+	}
+	if p.ScaleInCooldownSeconds <= 0 {
+		p.ScaleInCooldownSeconds = p.CooldownSeconds
+	}
+	if p.ProvisioningDelayTicks == nil {
+		delay := DefaultProvisioningDelayTicks
+		p.ProvisioningDelayTicks = &delay
+	}
+	return p
+}
+
+// activateReplicas brings replicas whose provisioning delay has passed into
+// service. Runs at the start of a tick so they serve that tick's traffic.
+func (e *Engine) activateReplicas(tick int) []AutoscalingEvent {
+	events := []AutoscalingEvent{}
+	for _, node := range e.orderedNodes() {
+		if node.scaling == nil || len(node.scaling.pending) == 0 {
+			continue
+		}
+
+		booting := node.scaling.pending[:0]
+		ready := 0
+		for _, readyTick := range node.scaling.pending {
+			if readyTick <= tick {
+				ready++
+			} else {
+				booting = append(booting, readyTick)
+			}
+		}
+		node.scaling.pending = booting
+		if ready == 0 {
+			continue
+		}
+
+		oldReplicas := node.Replicas
+		node.Replicas += ready
+		events = append(events, AutoscalingEvent{
+			Tick:     tick,
+			NodeID:   node.ID,
+			OldValue: oldReplicas,
+			NewValue: node.Replicas,
+			Reason:   fmt.Sprintf("%d replica(s) finished provisioning and now serve traffic", ready),
+		})
+	}
+	return events
+}
+
+// applyAutoScaling evaluates every node's policy at the end of a tick.
+// Scale-outs respect the cooldown and only add capacity once provisioned;
+// scale-ins cancel booting replicas first and are skipped under scale-in protection.
+func (e *Engine) applyAutoScaling(tick int) []AutoscalingEvent {
+	events := []AutoscalingEvent{}
+
+	for _, node := range e.orderedNodes() {
+		if node.scaling == nil || node.Failed {
+			continue
+		}
+		scaling := node.scaling
+		policy := scaling.policy
+
+		// Decide from the replicas that produced the metric; booting ones
+		// already count toward the current size so they aren't requested twice
+		metricValue := e.scalingMetric(node, policy.Metric)
+		current := node.Replicas + len(scaling.pending)
+		desired, reason := policy.desiredReplicas(node.Replicas, metricValue)
+		if reason == "" {
+			continue
+		}
+
+		if policy.MaxReplicas > 0 && desired > policy.MaxReplicas {
+			desired = policy.MaxReplicas
+		}
+		if desired < policy.MinReplicas {
+			desired = policy.MinReplicas
+		}
+
+		switch {
+		case desired > current:
+			if scaling.hasScaledOut && tick-scaling.lastScaleOut < policy.CooldownSeconds {
+				continue
+			}
+			readyTick := tick + 1 + *policy.ProvisioningDelayTicks
+			for i := current; i < desired; i++ {
+				scaling.pending = append(scaling.pending, readyTick)
+			}
+			scaling.lastScaleOut, scaling.hasScaledOut = tick, true
+			events = append(events, AutoscalingEvent{
+				Tick:        tick,
+				NodeID:      node.ID,
+				OldValue:    current,
+				NewValue:    desired,
+				Reason:      reason + " - scaling out",
+				Metric:      policy.Metric,
+				MetricValue: math.Round(metricValue*100) / 100,
+				ReadyTick:   readyTick,
+			})
+
+		case desired < current:
+			if policy.ScaleInProtection {
+				continue
+			}
+			lastScale := scaling.lastScaleIn
+			if scaling.hasScaledOut && scaling.lastScaleOut > lastScale {
+				lastScale = scaling.lastScaleOut
+			}
+			if (scaling.hasScaledIn || scaling.hasScaledOut) && tick-lastScale < policy.ScaleInCooldownSeconds {
+				continue
+			}
+
+			remove := current - desired
+			cancelled := minInt(remove, len(scaling.pending))
+			scaling.pending = scaling.pending[:len(scaling.pending)-cancelled]
+			node.Replicas -= remove - cancelled
+			scaling.lastScaleIn, scaling.hasScaledIn = tick, true
+			events = append(events, AutoscalingEvent{
+				Tick:        tick,
+				NodeID:      node.ID,
+				OldValue:    current,
+				NewValue:    desired,
+				Reason:      reason + " - scaling in",
+				Metric:      policy.Metric,
+				MetricValue: math.Round(metricValue*100) / 100,
+			})
+		}
+	}
+
+	return events
+}
+
+// desiredReplicas applies step scaling when steps are configured, target
+// tracking otherwise. An empty reason means the metric calls for no change.
+func (p AutoScalingPolicy) desiredReplicas(serving int, metricValue float64) (int, string) {
+	if len(p.Steps) > 0 {
+		for _, step := range p.Steps {
+			if step.LowerBound != nil && metricValue < *step.LowerBound {
+				continue
+			}
+			if step.UpperBound != nil && metricValue >= *step.UpperBound {
+				continue
+			}
+			return serving + step.Adjustment, fmt.Sprintf("%s %.2f matched step %+d", p.Metric, metricValue, step.Adjustment)
+		}
+		return serving, ""
+	}
+
+	if p.TargetValue <= 0 || math.Abs(metricValue-p.TargetValue) <= p.TargetValue*targetTrackingTolerance {
+		return serving, ""
+	}
+	// Per-replica metrics scale linearly with the replica count
+	desired := int(math.Ceil(float64(serving) * metricValue / p.TargetValue))
+	return desired, fmt.Sprintf("%s %.2f vs target %.2f", p.Metric, metricValue, p.TargetValue)
+}
+
+// scalingMetric reads the metric a policy tracks for a node
+func (e *Engine) scalingMetric(node *NodeState, metric string) float64 {
+	replicas := float64(node.Replicas)
+	if replicas <= 0 {
+		replicas = 1
+	}
+
+	switch metric {
+	case ScalingMetricRPS:
+		return node.RPSIn / replicas
+	case ScalingMetricQueueDepth:
+		// Consumers scale on backlog per replica in the queues feeding them
+		depth := 0
+		if isQueue(node.Type) {
+			depth = node.QueueDepth
+		}
+		for _, parentID := range e.topology.parents[node.ID] {
+			if parent := e.state.NodeStates[parentID]; parent != nil && isQueue(parent.Type) {
+				depth += parent.QueueDepth
+			}
+		}
+		return float64(depth) / replicas
+	case ScalingMetricLoad:
+		effectiveCapacity := node.CapacityRPS * float64(node.Replicas)
+		if effectiveCapacity <= 0 {
+			return 0
+		}
+		return node.CurrentLoad / effectiveCapacity
+	default:
+		return node.CPUUsage
+	}
+}
+
+// pendingReplicas returns how many replicas are still provisioning
+func (n *NodeState) pendingReplicas() int {
+	if n.scaling == nil {
+		return 0
+	}
+	return len(n.scaling.pending)
+}
@@ -0,0 +1,115 @@
+package simulation
+
+import "testing"
+
+// scalingInput sends 2000 RPS to an API starting on one replica with an
+// autoscaling policy
+func scalingInput(policy map[string]interface{}) *SimulationInput {
+	input := validationInput()
+	input.Nodes = input.Nodes[:2]
+	input.Edges = input.Edges[:1]
+	input.Nodes[1].Data.Config = map[string]interface{}{"replicas": 1, "autoscaling": policy}
+	seed := int64(1)
+	input.Workload = WorkloadConfig{RPS: 2000, Mode: "constant", DurationSeconds: 20, Seed: &seed}
+	return input
+}
+
+// New replicas take the provisioning delay to serve; the decision records
+// the metric that triggered it
+func TestScaleOutWaitsForProvisioning(t *testing.T) {
+	output, err := NewEngine(scalingInput(map[string]interface{}{
+		"metric": ScalingMetricRPS, "targetValue": 500, "provisioningDelayTicks": 3, "cooldownSeconds": 30,
+	})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := output.Metrics.AutoscalingEvents
+	if len(events) != 2 {
+		t.Fatalf("got events %+v, want a scale-out and its activation", events)
+	}
+	if out := events[0]; out.Tick != 1 || out.NewValue != 4 || out.Metric != ScalingMetricRPS || out.MetricValue != 2000 || out.ReadyTick != 5 {
+		t.Errorf("scale-out %+v, want 1 -> 4 replicas at tick 1 on 2000 rps, ready at tick 5", out)
+	}
+	if ready := events[1]; ready.Tick != 5 || ready.OldValue != 1 || ready.NewValue != 4 {
+		t.Errorf("activation %+v, want 1 -> 4 serving at tick 5", ready)
+	}
+	for _, point := range output.TimeSeries {
+		api := tickMetrics(t, point, "api")
+		serving, booting := 1, 3
+		if point.Tick >= 5 {
+			serving, booting = 4, 0
+		}
+		if api.Replicas != serving || api.Provisioning != booting {
+			t.Errorf("tick %d: %d serving and %d booting, want %d and %d", point.Tick, api.Replicas, api.Provisioning, serving, booting)
+		}
+	}
+}
+
+// Step scaling adds a replica at most once per cooldown
+func TestScaleOutRespectsCooldown(t *testing.T) {
+	output, err := NewEngine(scalingInput(map[string]interface{}{
+		"metric": ScalingMetricRPS, "provisioningDelayTicks": 0, "cooldownSeconds": 5,
+		"steps": []interface{}{map[string]interface{}{"lowerBound": 600, "adjustment": 1}},
+	})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaleOuts := []int{}
+	for _, event := range output.Metrics.AutoscalingEvents {
+		if event.ReadyTick > 0 {
+			scaleOuts = append(scaleOuts, event.Tick)
+		}
+	}
+	// 2000 RPS stays above 600 per replica until the fourth replica
+	want := []int{1, 6, 11}
+	if len(scaleOuts) != len(want) {
+		t.Fatalf("scaled out at ticks %v, want %v", scaleOuts, want)
+	}
+	for i := range want {
+		if scaleOuts[i] != want[i] {
+			t.Fatalf("scaled out at ticks %v, want %v", scaleOuts, want)
+		}
+	}
+}
+
+func TestScaleInProtection(t *testing.T) {
+	policy := map[string]interface{}{"metric": ScalingMetricRPS, "targetValue": 5000, "scaleInProtection": true}
+	input := scalingInput(policy)
+	input.Nodes[1].Data.Config["replicas"] = 4
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := output.Metrics.AutoscalingEvents; len(events) != 0 {
+		t.Errorf("got events %+v, want protected replicas kept", events)
+	}
+
+	delete(policy, "scaleInProtection")
+	output, err = NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api := lastTickMetrics(t, output, "api"); api.Replicas != 1 {
+		t.Errorf("unprotected: %d replicas, want 1 for 2000 RPS at 5000 a replica", api.Replicas)
+	}
+}
+
+func TestTargetTrackingTolerance(t *testing.T) {
+	policy := AutoScalingPolicy{Metric: ScalingMetricCPU, TargetValue: 50}
+	for _, tt := range []struct {
+		metric  float64
+		desired int
+	}{
+		{52, 4},  // Within 10% of the target: no change
+		{100, 8}, // Twice the target: twice the replicas
+		{20, 2},  // Well under: scale in, rounded up
+	} {
+		desired, _ := policy.desiredReplicas(4, tt.metric)
+		if desired != tt.desired {
+			t.Errorf("cpu %.0f: want %d replicas, got %d", tt.metric, tt.desired, desired)
+		}
+	}
+	if _, reason := policy.desiredReplicas(4, 52); reason != "" {
+		t.Errorf("within tolerance: got reason %q, want none", reason)
+	}
+}
@@ -0,0 +1,277 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Breaking-point search defaults
+const (
+	DefaultBreakingErrorRatePercent = 1.0
+	DefaultBreakingMaxRPS           = 10000000
+	breakingPrecisionFraction       = 0.01 // Stop when the bracket is within 1% of the breaking RPS
+)
+
+// BreakingPointOptions tunes the breaking-point search
+type BreakingPointOptions struct {
+	ErrorRatePercent float64 `json:"errorRatePercent,omitempty"` // Error rate that counts as broken (default: the SLA's, else 1%)
+	MaxRPS           int     `json:"maxRps,omitempty"`           // Give up above this RPS (default 10M)
+	PrecisionRPS     int     `json:"precisionRps,omitempty"`     // Stop when the bracket is this narrow (default 1% of the breaking RPS)
+}
+
+// NodeHeadroom is how close a node ran to its capacity
+type NodeHeadroom struct {
+	NodeID          string  `json:"nodeId"`
+	PeakUtilization float64 `json:"peakUtilizationPercent"` // Busiest tick: incoming RPS over the capacity of the replicas serving
+	HeadroomPercent float64 `json:"headroomPercent"`        // Extra load it could take at that peak, assuming linear scaling
+}
+
+// BreakingPoint is the load at which a design stops meeting its targets
+type BreakingPoint struct {
+	CurrentRPS         int            `json:"currentRps"`
+	SustainableRPS     int            `json:"sustainableRps"`           // Highest RPS found to hold
+	BreakingRPS        int            `json:"breakingRps,omitempty"`    // Lowest RPS found to break (0 = held up to maxRps)
+	HeadroomPercent    float64        `json:"headroomPercent"`          // Sustainable RPS over the current one, minus 100%
+	BrokenBy           []string       `json:"brokenBy,omitempty"`       // What failed at the breaking RPS
+	FirstSaturated     string         `json:"firstSaturated,omitempty"` // Node that ran out of capacity first at the breaking RPS
+	FirstSaturatedTick int            `json:"firstSaturatedTick,omitempty"`
+	ScalingLimited     []string       `json:"scalingLimited,omitempty"` // Nodes at their autoscaling maximum at the breaking RPS
+	Nodes              []NodeHeadroom `json:"nodes"`                    // At the current RPS, busiest first
+	Runs               int            `json:"runs"`
+	Seed               int64          `json:"seed"` // Every run uses this seed
+}
+
+// loadRun is the outcome of one run of the search
+type loadRun struct {
+	rps      int
+	holds    bool
+	brokenBy []string
+	engine   *Engine
+	output   *SimulationOutput
+}
+
+// FindBreakingPoint searches the workload's RPS for the point where the
+// design breaks: SLA violations, or an error rate over the threshold. It
+// doubles the load until something breaks, then bisects. Runs keep the
+// input's autoscaling, so nodes only scale out as far as their maximum.
+func FindBreakingPoint(input *SimulationInput, options BreakingPointOptions) (*BreakingPoint, error) {
+	mode := input.Workload.Mode
+	if mode == WorkloadPiecewise || mode == WorkloadTrace {
+		return nil, fmt.Errorf("workload mode %s takes its RPS from shape.points; use an RPS-driven mode", mode)
+	}
+	if input.Workload.RPS <= 0 {
+		return nil, fmt.Errorf("RPS must be greater than 0")
+	}
+	if options.ErrorRatePercent <= 0 {
+		options.ErrorRatePercent = DefaultBreakingErrorRatePercent
+		if sla := input.SLAConfig; sla != nil && sla.ErrorRatePercent > 0 {
+			options.ErrorRatePercent = sla.ErrorRatePercent
+		}
+	}
+	if options.MaxRPS <= 0 {
+		options.MaxRPS = DefaultBreakingMaxRPS
+	}
+
+	seed := NewEngine(input).Seed()
+	result := &BreakingPoint{CurrentRPS: input.Workload.RPS, Seed: seed}
+	run := func(rps int) (*loadRun, error) {
+		result.Runs++
+		return runAtLoad(input, seed, rps, options.ErrorRatePercent)
+	}
+
+	current, err := run(input.Workload.RPS)
+	if err != nil {
+		return nil, err
+	}
+	result.Nodes = nodeHeadroom(current)
+
+	// Bracket the breaking point: low holds, high breaks
+	var low, high *loadRun
+	if current.holds {
+		low = current
+		for high == nil && low.rps < options.MaxRPS {
+			next, err := run(minInt(low.rps*2, options.MaxRPS))
+			if err != nil {
+				return nil, err
+			}
+			if next.holds {
+				low = next
+			} else {
+				high = next
+			}
+		}
+	} else {
+		high = current
+		for low == nil && high.rps > 1 {
+			next, err := run(high.rps / 2)
+			if err != nil {
+				return nil, err
+			}
+			if next.holds {
+				low = next
+			} else {
+				high = next
+			}
+		}
+	}
+
+	for low != nil && high != nil {
+		precision := options.PrecisionRPS
+		if precision <= 0 {
+			precision = int(math.Max(1, float64(high.rps)*breakingPrecisionFraction))
+		}
+		if high.rps-low.rps <= precision {
+			break
+		}
+		next, err := run((low.rps + high.rps) / 2)
+		if err != nil {
+			return nil, err
+		}
+		if next.holds {
+			low = next
+		} else {
+			high = next
+		}
+	}
+
+	if low != nil {
+		result.SustainableRPS = low.rps
+	}
+	result.HeadroomPercent = math.Round((float64(result.SustainableRPS)/float64(result.CurrentRPS)-1)*10000) / 100
+	if high != nil {
+		result.BreakingRPS = high.rps
+		result.BrokenBy = high.brokenBy
+		result.FirstSaturated, result.FirstSaturatedTick = firstSaturated(high)
+		result.ScalingLimited = scalingLimited(high.engine)
+	}
+	return result, nil
+}
+
+// runAtLoad runs the input at an RPS and judges whether the design held
+func runAtLoad(input *SimulationInput, seed int64, rps int, errorRatePercent float64) (*loadRun, error) {
+	loaded := *input
+	loaded.Workload.RPS = rps
+	loaded.Workload.Seed = &seed
+	engine := NewEngine(&loaded)
+
+	// Share the batch slots, so searches count against the server-wide cap
+	var output *SimulationOutput
+	err := runBatchCall(0, func(int) (err error) {
+		output, err = engine.Run()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("simulating %d RPS: %w", rps, err)
+	}
+
+	// Requests a client couldn't send never reached the design: past a
+	// client's own capacity the load stops growing, it doesn't break anything
+	errorRate, violations := output.Metrics.ErrorRate, output.SLAViolations
+	if unsent := clientRejected(engine); unsent > 0 {
+		errorRate = 0
+		if sent := engine.state.TotalRequests - unsent; sent > 0 {
+			errorRate = math.Max(0, float64(engine.state.FailedRequests-unsent)/float64(sent))
+		}
+		_, violations = engine.checkSLAStatus(output.Metrics.Latency, errorRate, output.Metrics.Throughput)
+	}
+
+	brokenBy := append([]string{}, violations...)
+	if errorRate*100 > errorRatePercent {
+		brokenBy = append(brokenBy, fmt.Sprintf("Error rate (%.2f%%) exceeds threshold (%.2f%%)", errorRate*100, errorRatePercent))
+	}
+	return &loadRun{rps: rps, holds: len(brokenBy) == 0, brokenBy: brokenBy, engine: engine, output: output}, nil
+}
+
+// clientRejected is how many requests the clients of a run failed themselves
+func clientRejected(engine *Engine) int {
+	rejected := 0
+	for _, node := range engine.orderedNodes() {
+		if isClientType(node.Type) {
+			rejected += node.ErrorCount
+		}
+	}
+	return rejected
+}
+
+// utilization is a node's incoming RPS over the capacity of its serving
+// replicas at one tick (0 for clients and nodes without capacity)
+func utilization(engine *Engine, metrics NodeMetrics) float64 {
+	node := engine.state.NodeStates[metrics.NodeID]
+	if node == nil || isClientType(node.Type) || node.BaseCapacityRPS <= 0 {
+		return 0
+	}
+	return metrics.RPSIn / (node.BaseCapacityRPS * math.Max(1, float64(metrics.Replicas)))
+}
+
+// nodeHeadroom reports every node's peak utilization over a run, busiest first
+func nodeHeadroom(run *loadRun) []NodeHeadroom {
+	peaks := make(map[string]float64)
+	for _, point := range run.output.TimeSeries {
+		for _, metrics := range point.NodeMetrics {
+			peaks[metrics.NodeID] = math.Max(peaks[metrics.NodeID], utilization(run.engine, metrics))
+		}
+	}
+
+	nodes := []NodeHeadroom{}
+	for _, nodeID := range run.engine.state.NodeOrder {
+		peak, ok := peaks[nodeID]
+		if !ok || isClientType(run.engine.state.NodeStates[nodeID].Type) {
+			continue
+		}
+		headroom := 0.0
+		if peak > 0 {
+			headroom = math.Round((1/peak-1)*10000) / 100
+		}
+		nodes = append(nodes, NodeHeadroom{
+			NodeID:          nodeID,
+			PeakUtilization: math.Round(peak*10000) / 100,
+			HeadroomPercent: headroom,
+		})
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].PeakUtilization > nodes[j].PeakUtilization })
+	return nodes
+}
+
+// firstSaturated finds the node that reached full utilization earliest in a
+// run (the busiest one on ties). When none did, the busiest node is the
+// bottleneck, at the tick of its peak.
+func firstSaturated(run *loadRun) (string, int) {
+	bestID, bestTick, bestPeak := "", 0, 0.0
+	saturated := false
+	for _, point := range run.output.TimeSeries {
+		for _, nodeID := range run.engine.state.NodeOrder {
+			metrics, ok := point.NodeMetrics[nodeID]
+			if !ok {
+				continue
+			}
+			u := utilization(run.engine, metrics)
+			switch {
+			case u >= 1 && !saturated:
+				bestID, bestTick, bestPeak, saturated = nodeID, point.Tick, u, true
+			case saturated && point.Tick == bestTick && u > bestPeak:
+				bestID, bestPeak = nodeID, u
+			case !saturated && u > bestPeak:
+				bestID, bestTick, bestPeak = nodeID, point.Tick, u
+			}
+		}
+	}
+	return bestID, bestTick
+}
+
+// scalingLimited lists the autoscaled nodes that ended a run at their maximum
+func scalingLimited(engine *Engine) []string {
+	limited := []string{}
+	for _, node := range engine.orderedNodes() {
+		if node.scaling == nil {
+			continue
+		}
+		if limit := node.scaling.policy.MaxReplicas; limit > 0 && node.Replicas >= limit {
+			limited = append(limited, node.ID)
+		}
+	}
+	if len(limited) == 0 {
+		return nil
+	}
+	return limited
+}
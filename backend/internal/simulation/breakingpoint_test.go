@@ -0,0 +1,33 @@
+package simulation
+
+import "testing"
+
+// Requests over a client's own capacity are never sent: they don't break the
+// design, and the client isn't reported as its bottleneck
+func TestBreakingPointIgnoresClientSaturation(t *testing.T) {
+	seed := int64(1)
+	client := SimNode{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}}
+	workload := WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 10, Seed: &seed}
+
+	// Nothing but a client: nothing can break
+	result, err := FindBreakingPoint(&SimulationInput{Nodes: []SimNode{client}, Workload: workload}, BreakingPointOptions{MaxRPS: 4000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BreakingRPS != 0 || result.SustainableRPS != 4000000 {
+		t.Errorf("client only: breaking %d, sustainable %d; want unbounded up to maxRps", result.BreakingRPS, result.SustainableRPS)
+	}
+
+	// An API server breaks well below the client's capacity
+	result, err = FindBreakingPoint(&SimulationInput{
+		Nodes:    []SimNode{client, {ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}}},
+		Edges:    []SimEdge{{ID: "e1", Source: "client", Target: "api"}},
+		Workload: workload,
+	}, BreakingPointOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BreakingRPS == 0 || result.BreakingRPS > 1000000 || result.FirstSaturated != "api" {
+		t.Errorf("client to api: breaking %d at %q; want the api to break", result.BreakingRPS, result.FirstSaturated)
+	}
+}
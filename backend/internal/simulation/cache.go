@@ -0,0 +1,254 @@
+package simulation
+
+import "math"
+
+// Cache eviction policies
+const (
+	EvictionLRU    = "lru"    // Least recently used (Redis allkeys-lru, Memcached)
+	EvictionLFU    = "lfu"    // Least frequently used: keeps the hottest keys
+	EvictionFIFO   = "fifo"   // First in, first out
+	EvictionRandom = "random" // Random eviction (Redis allkeys-random)
+)
+
+// Cache model defaults
+const (
+	DefaultCacheWorkingSetKeys = 1000000 // Distinct keys the workload reads
+	DefaultCacheItemSizeKB     = 1.0
+	DefaultCDNWorkingSetKeys   = 100000 // Distinct objects behind a CDN
+	DefaultCDNItemSizeKB       = 500.0
+	DefaultCDNCacheSizeGB      = 10000.0 // Edge capacity is effectively unlimited
+	DefaultZipfSkew            = 0.99    // Typical key popularity skew (YCSB default)
+	cacheReservedMemory        = 0.25    // ElastiCache reserves ~25% of memory for overhead
+)
+
+// zipfBucketRatio sets how finely key ranks are grouped: each bucket spans
+// ranks up to this factor wider than the previous one
+const zipfBucketRatio = 1.1
+
+// zipfBucket is a range of key ranks with similar popularity
+type zipfBucket struct {
+	keys      float64 // Keys in the bucket
+	mass      float64 // Share of reads going to these keys
+	occupancy float64 // Probability one of these keys survives eviction at steady state
+}
+
+// cacheModel derives a cache's hit ratio from its size relative to the
+// working set, key popularity (Zipf), TTL and eviction policy. The cache
+// starts cold and fills as keys are read; a flush (cacheFail) empties it.
+type cacheModel struct {
+	buckets      []zipfBucket
+	capacityKeys float64 // Keys that fit in memory
+	ttlSeconds   float64 // 0 = keys never expire
+	warmReads    float64 // Reads served since the cache was last empty
+}
+
+// isCaching reports whether a node serves reads from a cache (in-memory or CDN)
+func isCaching(nodeType string) bool {
+	category := ComponentCategory(nodeType)
+	return category == ComponentCache || category == ComponentCDN
+}
+
+// isCache reports whether a node is an in-memory cache
+func isCache(nodeType string) bool {
+	return ComponentCategory(nodeType) == ComponentCache
+}
+
+// buildCacheModels sets up the cache model of every cache and CDN node
+func (e *Engine) buildCacheModels() {
+	for _, node := range e.input.Nodes {
+		state := e.state.NodeStates[node.ID]
+		if state == nil || !isCaching(state.Type) {
+			continue
+		}
+		config := node.Data.Config
+
+		workingSet, itemSizeKB := DefaultCacheWorkingSetKeys, DefaultCacheItemSizeKB
+		sizeGB := GetInstancePerformance(e.hardware, getString(config, "instanceType", "cache.t3.micro")).MemoryGB * (1 - cacheReservedMemory)
+		if !isCache(state.Type) {
+			workingSet, itemSizeKB, sizeGB = DefaultCDNWorkingSetKeys, DefaultCDNItemSizeKB, DefaultCDNCacheSizeGB
+		}
+		workingSet = getInt(config, "workingSetKeys", workingSet)
+		itemSizeKB = getFloat(config, "itemSizeKB", itemSizeKB)
+		sizeGB = getFloat(config, "cacheSizeGB", sizeGB) * math.Max(1, float64(getInt(config, "shards", 1)))
+
+		model := &cacheModel{
+			buckets:      zipfBuckets(math.Max(1, float64(workingSet)), getFloat(config, "zipfSkew", DefaultZipfSkew)),
+			capacityKeys: sizeGB * 1024 * 1024 / math.Max(itemSizeKB, 0.001),
+			ttlSeconds:   float64(state.TTL) / 1000,
+		}
+		model.evict(getString(config, "evictionPolicy", EvictionLRU))
+		if getBool(config, "prewarmed", false) {
+			model.warmReads = math.Inf(1)
+		}
+		state.cache = model
+		state.CacheHitRate = 0
+	}
+}
+
+// zipfBuckets groups ranks 1..n into geometrically growing buckets and
+// gives each its share of reads under a Zipf distribution with exponent s
+func zipfBuckets(n, s float64) []zipfBucket {
+	if s < 0 {
+		s = 0
+	}
+	// Sum of rank^-s over [from, to], exact for short ranges and by the
+	// integral otherwise
+	weight := func(from, to float64) float64 {
+		if to-from < 64 {
+			sum := 0.0
+			for rank := from; rank <= to; rank++ {
+				sum += math.Pow(rank, -s)
+			}
+			return sum
+		}
+		a, b := from-0.5, to+0.5
+		if math.Abs(s-1) < 1e-9 {
+			return math.Log(b / a)
+		}
+		return (math.Pow(b, 1-s) - math.Pow(a, 1-s)) / (1 - s)
+	}
+
+	buckets := []zipfBucket{}
+	total := 0.0
+	for from := 1.0; from <= n; {
+		to := math.Min(n, math.Max(from, math.Floor(from*zipfBucketRatio)))
+		mass := weight(from, to)
+		buckets = append(buckets, zipfBucket{keys: to - from + 1, mass: mass})
+		total += mass
+		from = to + 1
+	}
+	for i := range buckets {
+		buckets[i].mass /= total
+	}
+	return buckets
+}
+
+// evict works out which keys a full cache holds at steady state. LFU keeps
+// the hottest keys; LRU follows Che's approximation and FIFO/random its
+// analogue, both solving for the characteristic time a key stays cached.
+func (m *cacheModel) evict(policy string) {
+	keys := 0.0
+	for _, b := range m.buckets {
+		keys += b.keys
+	}
+	if m.capacityKeys >= keys {
+		for i := range m.buckets {
+			m.buckets[i].occupancy = 1
+		}
+		return
+	}
+
+	if policy == EvictionLFU {
+		room := m.capacityKeys
+		for i := range m.buckets {
+			held := math.Min(room, m.buckets[i].keys)
+			m.buckets[i].occupancy = held / m.buckets[i].keys
+			room -= held
+		}
+		return
+	}
+
+	// Per-key probability of being cached after t requests
+	occupancy := func(rate, t float64) float64 {
+		if policy == EvictionFIFO || policy == EvictionRandom {
+			return rate * t / (1 + rate*t)
+		}
+		return 1 - math.Exp(-rate*t)
+	}
+	cached := func(t float64) float64 {
+		sum := 0.0
+		for _, b := range m.buckets {
+			sum += b.keys * occupancy(b.mass/b.keys, t)
+		}
+		return sum
+	}
+
+	// Bisect the characteristic time on a log scale
+	low, high := 0.0, 60.0 // ln(t)
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if cached(math.Exp(mid)) < m.capacityKeys {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	t := math.Exp(low)
+	for i := range m.buckets {
+		m.buckets[i].occupancy = occupancy(m.buckets[i].mass/m.buckets[i].keys, t)
+	}
+}
+
+// hitRatio is the share of reads that hit at readRPS: a key hits when it is
+// cached at steady state, has been read since the cache was last empty and
+// hasn't expired since it was loaded. A skewed clock expires keys early.
+func (m *cacheModel) hitRatio(readRPS, skewMS float64) float64 {
+	ttl := m.ttlSeconds
+	if ttl > 0 && skewMS != 0 {
+		ttl -= math.Abs(skewMS) / 1000
+		if ttl <= 0 {
+			return 0 // Every key is already expired when it's written
+		}
+	}
+	hits := 0.0
+	for _, b := range m.buckets {
+		perKey := b.mass / b.keys
+		seen := 1.0
+		if !math.IsInf(m.warmReads, 1) {
+			seen = 1 - math.Exp(-perKey*m.warmReads)
+		}
+		fresh := 1.0
+		if ttl > 0 && readRPS > 0 {
+			rate := readRPS * perKey * ttl
+			fresh = rate / (1 + rate)
+		}
+		hits += b.mass * b.occupancy * seen * fresh
+	}
+	return math.Min(1, hits)
+}
+
+// updateCacheHitRates sets every cache's hit ratio for the tick from the
+// reads it saw last tick. Runs after failures, which can flush a cache.
+func (e *Engine) updateCacheHitRates() {
+	e.tickCacheHits, e.tickCacheReads = 0, 0
+	for _, node := range e.orderedNodes() {
+		if node.cache == nil {
+			continue
+		}
+		node.CacheHitRate = 0
+		if !node.faults.cacheDown {
+			node.CacheHitRate = node.cache.hitRatio(node.ReadRPS, node.faults.skewMS)
+		}
+		if e.cacheHitVariance > 0 {
+			node.CacheHitRate = math.Max(0, math.Min(1, node.CacheHitRate*(1+e.cacheHitVariance*e.rand.NormFloat64())))
+		}
+	}
+}
+
+// flushCache empties a cache: every read misses until it warms up again
+func (n *NodeState) flushCache() {
+	n.CacheHitRate = 0
+	n.faults.cacheDown = true
+	if n.cache != nil {
+		n.cache.warmReads = 0
+	}
+}
+
+// recordCacheAccess counts the hits and misses of the reads a cache served
+// this tick; misses load their keys, warming the cache
+func (e *Engine) recordCacheAccess(node *NodeState, served trafficMix) {
+	if node.cache == nil || served.Reads <= 0 {
+		return
+	}
+	if !node.faults.cacheDown {
+		node.cache.warmReads += served.Reads
+	}
+	if !isCache(node.Type) {
+		return
+	}
+	hits := served.Reads * node.CacheHitRate
+	e.state.CacheHits += int(math.Round(hits))
+	e.state.CacheMisses += int(math.Round(served.Reads - hits))
+	e.tickCacheHits += hits
+	e.tickCacheReads += served.Reads
+}
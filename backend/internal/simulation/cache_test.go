@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// cacheInput reads through a cold cache in front of a database
+func cacheInput(failures ...FailureInjection) *SimulationInput {
+	input := readWriteInput(
+		SimNode{ID: "cache", Data: SimNodeData{NodeType: "cache_redis", Config: map[string]interface{}{"workingSetKeys": 20000}}},
+		SimNode{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{"replicas": 4}}},
+	)
+	input.Edges = append(input.Edges, SimEdge{ID: "e2", Source: "api", Target: "cache"}, SimEdge{ID: "e3", Source: "cache", Target: "db"})
+	input.Workload.DurationSeconds = 40
+	input.Workload.Failures = failures
+	return input
+}
+
+func TestCacheWarmsUp(t *testing.T) {
+	output, err := NewEngine(cacheInput()).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := -1.0
+	for _, point := range output.TimeSeries {
+		if point.CacheHitRatio < previous {
+			t.Fatalf("tick %d: hit ratio fell from %.3f to %.3f without a failure", point.Tick, previous, point.CacheHitRatio)
+		}
+		previous = point.CacheHitRatio
+	}
+	if first := output.TimeSeries[0].CacheHitRatio; first != 0 || previous < 0.5 {
+		t.Errorf("hit ratio went from %.3f to %.3f, want a cold start warming past 0.5", first, previous)
+	}
+}
+
+// A flushed cache misses everything, loading the database with every read,
+// then warms up again
+func TestCacheFailureHerdsReadsToTheDatabase(t *testing.T) {
+	output, err := NewEngine(cacheInput(FailureInjection{Type: FailureCacheFail, NodeID: "cache", StartTick: 20, EndTick: 22})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := output.TimeSeries[18]
+	during := output.TimeSeries[20]
+	last := output.TimeSeries[len(output.TimeSeries)-1]
+
+	if during.CacheHitRatio != 0 {
+		t.Errorf("tick %d: hit ratio %.3f while the cache is down, want 0", during.Tick, during.CacheHitRatio)
+	}
+	if db := tickMetrics(t, during, "db"); math.Abs(db.ReadRPS-800) > 1 {
+		t.Errorf("tick %d: db got %.1f reads, want all 800", during.Tick, db.ReadRPS)
+	}
+	if db := tickMetrics(t, before, "db"); db.ReadRPS >= 400 {
+		t.Errorf("tick %d: db got %.1f reads before the failure, want the cache taking most", before.Tick, db.ReadRPS)
+	}
+	if last.CacheHitRatio < 0.5 {
+		t.Errorf("hit ratio %.3f at the end, want it recovered", last.CacheHitRatio)
+	}
+}
+
+func TestCacheModelHitRatio(t *testing.T) {
+	model := func(capacityKeys, ttlSeconds float64, policy string) *cacheModel {
+		m := &cacheModel{buckets: zipfBuckets(100000, DefaultZipfSkew), capacityKeys: capacityKeys, ttlSeconds: ttlSeconds}
+		m.evict(policy)
+		m.warmReads = math.Inf(1)
+		return m
+	}
+
+	if got := model(200000, 0, EvictionLRU).hitRatio(1000, 0); math.Abs(got-1) > 1e-9 {
+		t.Errorf("working set fits: hit ratio %.3f, want 1", got)
+	}
+	small, large := model(1000, 0, EvictionLRU).hitRatio(1000, 0), model(10000, 0, EvictionLRU).hitRatio(1000, 0)
+	if small <= 0 || large <= small {
+		t.Errorf("hit ratio %.3f with 1k keys and %.3f with 10k, want it growing with size", small, large)
+	}
+	if lfu := model(1000, 0, EvictionLFU).hitRatio(1000, 0); lfu < small {
+		t.Errorf("LFU %.3f below LRU %.3f: LFU keeps the hottest keys", lfu, small)
+	}
+	if ttl := model(10000, 1, EvictionLRU).hitRatio(1000, 0); ttl >= large {
+		t.Errorf("1s TTL: hit ratio %.3f, want below %.3f without expiry", ttl, large)
+	}
+	if skewed := model(10000, 1, EvictionLRU).hitRatio(1000, 2000); skewed != 0 {
+		t.Errorf("clock skewed past the TTL: hit ratio %.3f, want 0", skewed)
+	}
+	cold := model(10000, 0, EvictionLRU)
+	cold.warmReads = 0
+	if got := cold.hitRatio(1000, 0); got != 0 {
+		t.Errorf("cold cache: hit ratio %.3f, want 0", got)
+	}
+}
@@ -0,0 +1,335 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yourusername/visualization-backend/internal/catalog"
+)
+
+// DefaultCatalogTTL is how long a catalog load is reused before reloading
+const DefaultCatalogTTL = 5 * time.Minute
+
+// catalogMissReload is how old a load must be before a lookup of a type it
+// doesn't have reloads it, so types added to the catalog simulate right away
+const catalogMissReload = 10 * time.Second
+
+// maxCatalogMisses bounds the remembered misses; type names come from requests
+const maxCatalogMisses = 1024
+
+// CatalogSource is the part of the catalog repository the provider reads
+type CatalogSource interface {
+	GetAllComponents() ([]catalog.ComponentType, error)
+	GetInstanceTypes(componentTypeID string) ([]catalog.InstanceType, error)
+	GetStorageTypes() ([]catalog.StorageType, error)
+	GetLoadBalancerTypes() ([]catalog.LoadBalancerType, error)
+	GetQueueTypes() ([]catalog.QueueType, error)
+	GetCDNTypes() ([]catalog.CDNType, error)
+	GetSearchTypes() ([]catalog.SearchType, error)
+}
+
+// CatalogProvider serves specs and prices from the component catalog. A load
+// is cached in memory for a TTL; when the catalog can't be read the last good
+// load stays in use, and types the catalog lacks come from the built-in tables.
+type CatalogProvider struct {
+	source   CatalogSource
+	ttl      time.Duration
+	fallback HardwareProvider
+
+	loadMu sync.Mutex // Serializes reloads
+
+	mu       sync.RWMutex // Guards the fields below
+	snapshot *catalogSnapshot
+	loadedAt time.Time
+	lastErr  error
+	misses   map[string]time.Time // Types a reload didn't find -> when; not reloaded for again within the TTL
+}
+
+// catalogSnapshot is one load of the catalog
+type catalogSnapshot struct {
+	instances     map[string]InstancePerformance
+	instanceTypes map[string][]string // Catalog component -> its instance types
+	storage       map[string]StoragePerformance
+	loadBalancers map[string]LoadBalancerPerformance
+	queues        map[string]QueuePerformance
+	cdns          map[string]CDNPerformance
+	search        map[string]SearchPerformance
+}
+
+// NewCatalogProvider creates a provider reading source, reloading it every
+// ttl (DefaultCatalogTTL when ttl <= 0). Nothing is read until first use.
+func NewCatalogProvider(source CatalogSource, ttl time.Duration) *CatalogProvider {
+	if ttl <= 0 {
+		ttl = DefaultCatalogTTL
+	}
+	return &CatalogProvider{source: source, ttl: ttl, fallback: OfflineHardware(), misses: make(map[string]time.Time)}
+}
+
+// Refresh reloads the catalog now. On failure the previous load stays in use.
+func (p *CatalogProvider) Refresh() error {
+	p.loadMu.Lock()
+	defer p.loadMu.Unlock()
+	return p.reload()
+}
+
+// Invalidate makes the next lookup reload the catalog; call it after changing it
+func (p *CatalogProvider) Invalidate() {
+	p.mu.Lock()
+	p.loadedAt = time.Time{}
+	p.misses = make(map[string]time.Time)
+	p.mu.Unlock()
+}
+
+// LastError is the error of the latest load, nil when it succeeded
+func (p *CatalogProvider) LastError() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastErr
+}
+
+// reload reads the whole catalog; the caller holds loadMu. A failed load
+// also counts as a load, so an unreachable catalog isn't hit on every lookup.
+func (p *CatalogProvider) reload() error {
+	snapshot, err := loadCatalogSnapshot(p.source)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loadedAt = time.Now()
+	p.lastErr = err
+	if err == nil {
+		p.snapshot = snapshot
+	}
+	return err
+}
+
+// cached returns the current load and whether it is younger than maxAge
+func (p *CatalogProvider) cached(maxAge time.Duration) (*catalogSnapshot, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.snapshot, !p.loadedAt.IsZero() && time.Since(p.loadedAt) <= maxAge
+}
+
+// current returns the load to answer from, reloading first when it is older than maxAge
+func (p *CatalogProvider) current(maxAge time.Duration) *catalogSnapshot {
+	if snapshot, fresh := p.cached(maxAge); fresh {
+		return snapshot
+	}
+	p.loadMu.Lock()
+	defer p.loadMu.Unlock()
+	if snapshot, fresh := p.cached(maxAge); fresh {
+		return snapshot // Another lookup reloaded it while this one waited
+	}
+	p.reload()
+	snapshot, _ := p.cached(maxAge)
+	return snapshot
+}
+
+// find looks a type of a kind up in the catalog. A load that lacks it is
+// reloaded unless the load is very recent or a reload already missed it
+// within the TTL; empty names are never looked up.
+func (p *CatalogProvider) find(kind, name string, lookup func(*catalogSnapshot) bool) bool {
+	if name == "" {
+		return false
+	}
+	if snapshot := p.current(p.ttl); snapshot != nil && lookup(snapshot) {
+		return true
+	}
+	key := kind + "/" + name
+	if p.missedRecently(key) {
+		return false
+	}
+	if snapshot := p.current(catalogMissReload); snapshot != nil && lookup(snapshot) {
+		return true
+	}
+	p.recordMiss(key)
+	return false
+}
+
+// missedRecently reports whether a reload missed a type within the TTL
+func (p *CatalogProvider) missedRecently(key string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	missedAt, ok := p.misses[key]
+	return ok && time.Since(missedAt) <= p.ttl
+}
+
+// recordMiss remembers that the catalog lacks a type
+func (p *CatalogProvider) recordMiss(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.misses) >= maxCatalogMisses {
+		p.misses = make(map[string]time.Time)
+	}
+	p.misses[key] = time.Now()
+}
+
+func (p *CatalogProvider) Instance(instanceType string) (InstancePerformance, bool) {
+	var perf InstancePerformance
+	if p.find("instance", instanceType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.instances[instanceType]; return }) {
+		return perf, true
+	}
+	return p.fallback.Instance(instanceType)
+}
+
+// InstanceTypes lists the catalog's instance types of a component together
+// with the built-in ones, cheapest first
+func (p *CatalogProvider) InstanceTypes(componentType string) []string {
+	specs := make(map[string]InstancePerformance)
+	for _, instanceType := range p.fallback.InstanceTypes(componentType) {
+		specs[instanceType], _ = p.fallback.Instance(instanceType)
+	}
+	if snapshot := p.current(p.ttl); snapshot != nil {
+		for _, instanceType := range snapshot.instanceTypes[componentType] {
+			specs[instanceType] = snapshot.instances[instanceType]
+		}
+	}
+	types := make([]string, 0, len(specs))
+	for instanceType := range specs {
+		types = append(types, instanceType)
+	}
+	sortByHourlyCost(types, specs)
+	return types
+}
+
+func (p *CatalogProvider) Storage(storageType string) (StoragePerformance, bool) {
+	var perf StoragePerformance
+	if p.find("storage", storageType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.storage[storageType]; return }) {
+		return perf, true
+	}
+	return p.fallback.Storage(storageType)
+}
+
+func (p *CatalogProvider) LoadBalancer(lbType string) (LoadBalancerPerformance, bool) {
+	var perf LoadBalancerPerformance
+	if p.find("lb", lbType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.loadBalancers[lbType]; return }) {
+		return perf, true
+	}
+	return p.fallback.LoadBalancer(lbType)
+}
+
+func (p *CatalogProvider) Queue(queueType string) (QueuePerformance, bool) {
+	var perf QueuePerformance
+	if p.find("queue", queueType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.queues[queueType]; return }) {
+		return perf, true
+	}
+	return p.fallback.Queue(queueType)
+}
+
+func (p *CatalogProvider) CDN(cdnType string) (CDNPerformance, bool) {
+	var perf CDNPerformance
+	if p.find("cdn", cdnType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.cdns[cdnType]; return }) {
+		return perf, true
+	}
+	return p.fallback.CDN(cdnType)
+}
+
+func (p *CatalogProvider) Search(searchType string) (SearchPerformance, bool) {
+	var perf SearchPerformance
+	if p.find("search", searchType, func(s *catalogSnapshot) (ok bool) { perf, ok = s.search[searchType]; return }) {
+		return perf, true
+	}
+	return p.fallback.Search(searchType)
+}
+
+// loadCatalogSnapshot reads every hardware table of the catalog, leaving out
+// retired (inactive) entries
+func loadCatalogSnapshot(source CatalogSource) (*catalogSnapshot, error) {
+	s := &catalogSnapshot{
+		instances:     make(map[string]InstancePerformance),
+		instanceTypes: make(map[string][]string),
+		storage:       make(map[string]StoragePerformance),
+		loadBalancers: make(map[string]LoadBalancerPerformance),
+		queues:        make(map[string]QueuePerformance),
+		cdns:          make(map[string]CDNPerformance),
+		search:        make(map[string]SearchPerformance),
+	}
+
+	components, err := source.GetAllComponents()
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		if !component.IsActive {
+			continue
+		}
+		instances, err := source.GetInstanceTypes(component.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range instances {
+			if !it.IsActive {
+				continue
+			}
+			s.instances[it.ID] = InstancePerformance{
+				VCPU:          it.VCPU,
+				MemoryGB:      it.MemoryGB,
+				NetworkGbps:   it.NetworkGbps,
+				CPUCredits:    instanceClass(it.ID) == "t", // T-family instances burst on credits
+				HourlyCostUSD: it.CostPerHourUSD,
+			}
+			s.instanceTypes[component.ID] = append(s.instanceTypes[component.ID], it.ID)
+		}
+	}
+
+	storageTypes, err := source.GetStorageTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range storageTypes {
+		if !st.IsActive {
+			continue
+		}
+		// Provisioned IOPS scale with size: quote them at 1 TB. HDD volumes
+		// provision none and are bound by throughput instead.
+		iops := st.IOPSPerGB * 1000
+		if iops == 0 {
+			iops = st.ThroughputMBps
+		}
+		s.storage[st.ID] = StoragePerformance{IOPS: iops, ThroughputMBps: st.ThroughputMBps, LatencyMs: st.LatencyMs, CostPerGBMonthUSD: st.CostPerGBMonthUSD}
+	}
+
+	lbTypes, err := source.GetLoadBalancerTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range lbTypes {
+		if !lb.IsActive {
+			continue
+		}
+		s.loadBalancers[lb.ID] = LoadBalancerPerformance{CapacityRPS: float64(lb.CapacityRPS), LatencyMs: lb.LatencyMs, HourlyCostUSD: lb.CostPerHourUSD}
+	}
+
+	queueTypes, err := source.GetQueueTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range queueTypes {
+		if !q.IsActive {
+			continue
+		}
+		s.queues[q.ID] = QueuePerformance{ThroughputRPS: float64(q.ThroughputMsgsPerSec), LatencyMs: q.LatencyMs, CostPerMillionUSD: q.CostPerMillionRequestsUSD}
+	}
+
+	cdnTypes, err := source.GetCDNTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, cdn := range cdnTypes {
+		if !cdn.IsActive {
+			continue
+		}
+		s.cdns[cdn.ID] = CDNPerformance{LatencyMs: cdn.LatencyMs, CostPerGBUSD: cdn.CostPerGBUSD}
+	}
+
+	searchTypes, err := source.GetSearchTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range searchTypes {
+		if !st.IsActive {
+			continue
+		}
+		s.search[st.ID] = SearchPerformance{QueriesPerSec: float64(st.QueriesPerSec), LatencyMs: st.LatencyMs, HourlyCostUSD: st.CostPerHourUSD}
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+package simulation
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/visualization-backend/internal/catalog"
+)
+
+// fakeCatalog is a catalog with one component that counts its loads
+type fakeCatalog struct {
+	mu        sync.Mutex
+	loads     int
+	instances []catalog.InstanceType
+}
+
+func (f *fakeCatalog) GetAllComponents() ([]catalog.ComponentType, error) {
+	f.mu.Lock()
+	f.loads++
+	f.mu.Unlock()
+	return []catalog.ComponentType{{ID: "api_server", IsActive: true}}, nil
+}
+
+func (f *fakeCatalog) GetInstanceTypes(string) ([]catalog.InstanceType, error) {
+	return f.instances, nil
+}
+
+func (f *fakeCatalog) GetStorageTypes() ([]catalog.StorageType, error)           { return nil, nil }
+func (f *fakeCatalog) GetLoadBalancerTypes() ([]catalog.LoadBalancerType, error) { return nil, nil }
+func (f *fakeCatalog) GetQueueTypes() ([]catalog.QueueType, error)               { return nil, nil }
+func (f *fakeCatalog) GetCDNTypes() ([]catalog.CDNType, error)                   { return nil, nil }
+func (f *fakeCatalog) GetSearchTypes() ([]catalog.SearchType, error)             { return nil, nil }
+
+func (f *fakeCatalog) loadCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loads
+}
+
+func newFakeCatalog() *fakeCatalog {
+	return &fakeCatalog{instances: []catalog.InstanceType{
+		{ID: "x9.large", VCPU: 4, MemoryGB: 16, CostPerHourUSD: 0.2, IsActive: true},
+		{ID: "x9.retired", VCPU: 4, MemoryGB: 16, CostPerHourUSD: 0.1, IsActive: false},
+	}}
+}
+
+func TestCatalogProviderSkipsInactiveTypes(t *testing.T) {
+	provider := NewCatalogProvider(newFakeCatalog(), time.Minute)
+	if perf, ok := provider.Instance("x9.large"); !ok || perf.VCPU != 4 {
+		t.Errorf("x9.large: got %+v, %v, want the catalog's specs", perf, ok)
+	}
+	if _, ok := provider.Instance("x9.retired"); ok {
+		t.Error("x9.retired is inactive, want it unknown")
+	}
+	for _, instanceType := range provider.InstanceTypes("api_server") {
+		if instanceType == "x9.retired" {
+			t.Error("inactive x9.retired listed")
+		}
+	}
+}
+
+// Lookups of types the catalog lacks don't reload it over and over
+func TestCatalogProviderCachesMisses(t *testing.T) {
+	source := newFakeCatalog()
+	provider := NewCatalogProvider(source, time.Minute)
+
+	if _, ok := provider.Instance(""); ok || source.loadCount() != 0 {
+		t.Fatalf("empty type: found %v after %d loads, want no lookup", ok, source.loadCount())
+	}
+	for i := 0; i < 3; i++ {
+		provider.Instance("x9.missing")
+	}
+	if loads := source.loadCount(); loads != 1 {
+		t.Fatalf("got %d loads, want 1", loads)
+	}
+
+	// Past catalogMissReload the known miss still doesn't reload, a new one does
+	provider.mu.Lock()
+	provider.loadedAt = provider.loadedAt.Add(-2 * catalogMissReload)
+	provider.mu.Unlock()
+	provider.Instance("x9.missing")
+	if loads := source.loadCount(); loads != 1 {
+		t.Errorf("known miss: got %d loads, want 1", loads)
+	}
+	provider.Instance("x9.new")
+	if loads := source.loadCount(); loads != 2 {
+		t.Errorf("new miss: got %d loads, want 2", loads)
+	}
+
+	// Invalidating forgets the misses
+	provider.Invalidate()
+	provider.Instance("x9.missing")
+	if loads := source.loadCount(); loads != 3 {
+		t.Errorf("after Invalidate: got %d loads, want 3", loads)
+	}
+}
@@ -0,0 +1,487 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Failure types
+const (
+	FailureNodeFail         FailureType = "nodeFail"
+	FailureRegionFail       FailureType = "regionFail"
+	FailureAZOutage         FailureType = "azOutage" // regionFail limited to region + availabilityZone
+	FailureCacheFail        FailureType = "cacheFail"
+	FailureDBFail           FailureType = "dbFail"
+	FailureNetworkDelay     FailureType = "networkDelay"
+	FailureNodeLatency      FailureType = "nodeLatency"
+	FailureThrottle         FailureType = "throttle"
+	FailurePartition        FailureType = "partition"
+	FailurePacketLoss       FailureType = "packetLoss"
+	FailureSlowDisk         FailureType = "slowDisk"
+	FailureMemoryLeak       FailureType = "memoryLeak"
+	FailureCPUSteal         FailureType = "cpuSteal"
+	FailureDependencyErrors FailureType = "dependencyErrors"
+	FailureDNS              FailureType = "dnsFailure"
+	FailureClockSkew        FailureType = "clockSkew"
+)
+
+// Failure schedule types
+const (
+	ScheduleRecurring = "recurring"
+	ScheduleRandom    = "random"
+)
+
+// DefaultFaultSeverity is the severity (0-100) used when a failure sets none
+var DefaultFaultSeverity = map[FailureType]float64{
+	FailureThrottle:         50,  // % of capacity removed
+	FailurePacketLoss:       10,  // % of requests on the edge lost
+	FailureSlowDisk:         50,  // % of disk throughput lost; I/O latency grows up to 10x
+	FailureMemoryLeak:       2,   // % of memory leaked per second
+	FailureCPUSteal:         30,  // % of CPU time taken by noisy neighbours
+	FailureDependencyErrors: 50,  // % of requests answered with an error
+	FailureDNS:              100, // % of lookups failing
+	FailureClockSkew:        100, // % of requests rejected once the skew exceeds the tolerance
+}
+
+// Fault model defaults
+const (
+	DefaultFaultDurationSeconds = 10     // Length of each scheduled occurrence
+	DefaultClockSkewMs          = 600000 // 10 minutes
+	DefaultClockSkewToleranceMs = 300000 // Kerberos / most token validators allow 5 minutes
+	DefaultOOMRestartSeconds    = 10     // An OOM-killed process is down this long
+	gcPressureThreshold         = 85.0   // Memory % above which GC pauses stretch latency
+)
+
+// nodeFaults holds the fault effects injected into a node this tick
+type nodeFaults struct {
+	errorRate float64 // Share of served requests answered with an error
+	leak      float64 // Memory % held by a leak
+	skewMS    float64 // Clock offset
+	cacheDown bool    // Cache flushed/unavailable: every read misses and nothing is cached
+}
+
+// faultWindow is one occurrence of a configured failure
+type faultWindow struct {
+	failure   int // Index into the workload's failures
+	start     int
+	end       int
+	injected  bool // False when skipped by the failure's probability
+	leakSince int  // memoryLeak: tick the current leak started
+	oomUntil  int  // memoryLeak: the process restarts after an OOM kill until this tick
+	events    []string
+}
+
+// faultSample is what the blast radius of a fault is measured from: one tick
+// of system and per-node health
+type faultSample struct {
+	faulted   bool // Some injected fault was active
+	failed    int
+	total     int
+	p99       float64
+	nodeError map[string]float64 // Share of the node's requests that failed
+	latency   map[string]float64
+	rps       map[string]float64
+	down      map[string]bool
+}
+
+// nodeBaseline is the average per-node health over the fault-free ticks
+type nodeBaseline struct {
+	errorRate map[string]float64
+	latency   map[string]float64
+	rps       map[string]float64
+}
+
+// severity returns the failure's severity as a fraction, falling back to the
+// type's default. Throttles also accept the legacy delayMs severity.
+func (f FailureInjection) severity() float64 {
+	severity := f.Severity
+	if severity <= 0 && f.Type == FailureThrottle {
+		severity = float64(f.DelayMs)
+	}
+	if severity <= 0 {
+		severity = DefaultFaultSeverity[f.Type]
+	}
+	return math.Min(100, severity) / 100
+}
+
+// target describes what a failure hits, for the fault timeline
+func (f FailureInjection) target() string {
+	switch {
+	case f.EdgeID != "":
+		return f.EdgeID
+	case f.NodeID != "":
+		return f.NodeID
+	case f.Zone != "":
+		return f.Region + "/" + f.Zone
+	default:
+		return f.Region
+	}
+}
+
+// buildFaultWindows expands every failure into its occurrences: one window
+// between startTick and endTick, or a game-day schedule of them. Each
+// occurrence is injected with the failure's probability.
+func (e *Engine) buildFaultWindows() []*faultWindow {
+	windows := []*faultWindow{}
+	for i := range e.config.Failures {
+		windows = append(windows, e.failureWindows(i)...)
+	}
+	return windows
+}
+
+// failureWindows expands one of the workload's failures into its occurrences
+func (e *Engine) failureWindows(i int) []*faultWindow {
+	failure := e.config.Failures[i]
+	first := maxInt(1, failure.StartTick)
+	last := e.config.DurationSeconds
+	if failure.EndTick > 0 && failure.EndTick < last {
+		last = failure.EndTick
+	}
+
+	spans := [][2]int{{first, last}}
+	if schedule := failure.Schedule; schedule != nil && schedule.IntervalSeconds > 0 {
+		spans = e.scheduleSpans(schedule, first, last)
+	}
+
+	windows := []*faultWindow{}
+	for _, span := range spans {
+		injected := true
+		if p := failure.Probability; p > 0 && p < 1 {
+			injected = e.rand.Float64() < p
+		}
+		windows = append(windows, &faultWindow{failure: i, start: span[0], end: span[1], injected: injected})
+	}
+	return windows
+}
+
+// scheduleSpans lays a schedule's occurrences out between first and last.
+// Random schedules draw exponential gaps, so starts form a Poisson process.
+func (e *Engine) scheduleSpans(schedule *FailureSchedule, first, last int) [][2]int {
+	length := schedule.DurationSeconds
+	if length <= 0 {
+		length = DefaultFaultDurationSeconds
+	}
+
+	spans := [][2]int{}
+	next := float64(first)
+	if schedule.Type == ScheduleRandom {
+		next += e.rand.ExpFloat64() * float64(schedule.IntervalSeconds)
+	}
+	for int(next) <= last {
+		start := int(next)
+		if schedule.Type != ScheduleRandom && schedule.JitterSeconds > 0 {
+			start += e.rand.Intn(schedule.JitterSeconds + 1)
+		}
+		if start > last {
+			break
+		}
+		spans = append(spans, [2]int{start, minInt(last, start+length-1)})
+		if schedule.Occurrences > 0 && len(spans) >= schedule.Occurrences {
+			break
+		}
+
+		if schedule.Type == ScheduleRandom {
+			next = float64(start+length) + e.rand.ExpFloat64()*float64(schedule.IntervalSeconds)
+		} else {
+			next += float64(schedule.IntervalSeconds)
+		}
+	}
+	return spans
+}
+
+// activeFaults returns the injected occurrences active at a tick
+func (e *Engine) activeFaults(tick int) []*faultWindow {
+	active := []*faultWindow{}
+	for _, window := range e.faultWindows {
+		if window.injected && tick >= window.start && tick <= window.end {
+			active = append(active, window)
+		}
+	}
+	return active
+}
+
+// applyPacketLoss drops a share of the traffic on an edge, or on every edge
+// into a node
+func (e *Engine) applyPacketLoss(failure FailureInjection) {
+	loss := failure.severity()
+	for _, edge := range e.input.Edges {
+		matches := edge.ID == failure.EdgeID
+		if failure.EdgeID == "" {
+			matches = edge.Target == failure.NodeID
+		}
+		if !matches {
+			continue
+		}
+		if e.edgeLoss[edge.Source] == nil {
+			e.edgeLoss[edge.Source] = make(map[string]float64)
+		}
+		// Independent losses compound
+		kept := (1 - e.edgeLoss[edge.Source][edge.Target]) * (1 - loss)
+		e.edgeLoss[edge.Source][edge.Target] = 1 - kept
+	}
+}
+
+// countPacketLoss fails the requests lost on the wire this tick
+func (e *Engine) countPacketLoss(traffic *tickTraffic) {
+	for _, sourceID := range e.state.NodeOrder {
+		for _, targetID := range e.state.EdgeMap[sourceID] {
+			e.state.FailedRequests += int(traffic.lost[sourceID][targetID].Total())
+			delete(traffic.lost[sourceID], targetID) // Parallel edges share one flow
+		}
+	}
+}
+
+// applySlowDisk models EBS degradation: disk throughput drops by the
+// severity and I/O latency grows up to 10x
+func (e *Engine) applySlowDisk(nodeID string, severity float64) {
+	if node := e.state.NodeStates[nodeID]; node != nil {
+		node.CapacityRPS *= 1 - severity*0.5
+		node.LatencyMS += node.BaseLatencyMS * 9 * severity
+	}
+}
+
+// applyCPUSteal takes a share of the node's CPU away: less capacity, and
+// every request takes proportionally longer
+func (e *Engine) applyCPUSteal(nodeID string, severity float64) {
+	if node := e.state.NodeStates[nodeID]; node != nil {
+		severity = math.Min(severity, 0.95)
+		node.CapacityRPS *= 1 - severity
+		node.LatencyMS += node.BaseLatencyMS * (1/(1-severity) - 1)
+	}
+}
+
+// applyErrorRate makes a node answer a share of its requests with errors
+func (e *Engine) applyErrorRate(node *NodeState, rate float64) {
+	node.faults.errorRate = 1 - (1-node.faults.errorRate)*(1-math.Min(1, rate))
+}
+
+// applyDNSFailure fails name resolution: lookups through the DNS / traffic
+// manager nodes (or the one named) error, or, without any, requests fail at
+// the entry nodes before reaching the system
+func (e *Engine) applyDNSFailure(nodeID string, severity float64) {
+	targets := []string{}
+	if nodeID != "" {
+		targets = append(targets, nodeID)
+	} else {
+		for _, id := range e.state.NodeOrder {
+			if isGlobalTrafficManager(e.state.NodeStates[id].Type) {
+				targets = append(targets, id)
+			}
+		}
+		if len(targets) == 0 {
+			targets = e.findEntryNodes()
+		}
+	}
+	for _, id := range targets {
+		if node := e.state.NodeStates[id]; node != nil {
+			e.applyErrorRate(node, severity)
+		}
+	}
+}
+
+// applyClockSkew shifts a node's clock. Past the tolerance (node config
+// clockSkewToleranceMs) it rejects signed requests and tokens; any skew
+// makes a cache expire its keys early.
+func (e *Engine) applyClockSkew(failure FailureInjection) {
+	node := e.state.NodeStates[failure.NodeID]
+	if node == nil {
+		return
+	}
+	skew := float64(failure.SkewMs)
+	if skew == 0 {
+		skew = DefaultClockSkewMs
+	}
+	node.faults.skewMS = skew
+	if math.Abs(skew) > e.clockSkewTolerance(node.ID) {
+		e.applyErrorRate(node, failure.severity())
+	}
+}
+
+// clockSkewTolerance is how much skew a node accepts before rejecting requests
+func (e *Engine) clockSkewTolerance(nodeID string) float64 {
+	for _, node := range e.input.Nodes {
+		if node.ID == nodeID {
+			return getFloat(node.Data.Config, "clockSkewToleranceMs", DefaultClockSkewToleranceMs)
+		}
+	}
+	return DefaultClockSkewToleranceMs
+}
+
+// applyMemoryLeak grows a node's leaked memory every tick of the window.
+// Above gcPressureThreshold GC pauses stretch latency; when memory runs out
+// the process is OOM-killed, stays down while it restarts, and leaks again.
+func (e *Engine) applyMemoryLeak(window *faultWindow, failure FailureInjection, tick int) {
+	node := e.state.NodeStates[failure.NodeID]
+	if node == nil {
+		return
+	}
+	if tick < window.oomUntil {
+		node.Failed = true
+		return
+	}
+	if window.leakSince == 0 {
+		window.leakSince = tick
+	}
+
+	leak := failure.severity() * 100 * float64(tick-window.leakSince+1)
+	if node.memory+leak >= 100 {
+		node.Failed = true
+		window.oomUntil = tick + DefaultOOMRestartSeconds
+		window.leakSince = window.oomUntil
+		window.events = append(window.events, fmt.Sprintf("tick %d: %s OOM-killed, restarting", tick, node.ID))
+		return
+	}
+
+	node.faults.leak = leak
+	if used := node.memory + leak; used > gcPressureThreshold {
+		node.LatencyMS += node.BaseLatencyMS * 2 * (used - gcPressureThreshold) / (100 - gcPressureThreshold)
+	}
+}
+
+// recordFaultSample keeps this tick's health for blast-radius reports
+func (e *Engine) recordFaultSample(tick int, point TimeSeriesPoint) {
+	if len(e.faultWindows) == 0 {
+		return
+	}
+	sample := faultSample{
+		faulted:   len(e.activeFaults(tick)) > 0,
+		failed:    e.state.FailedRequests - e.sampledFailed,
+		total:     e.state.TotalRequests - e.sampledTotal,
+		p99:       point.Latency.P99,
+		nodeError: make(map[string]float64),
+		latency:   make(map[string]float64),
+		rps:       make(map[string]float64),
+		down:      make(map[string]bool),
+	}
+	e.sampledFailed, e.sampledTotal = e.state.FailedRequests, e.state.TotalRequests
+
+	for _, node := range e.orderedNodes() {
+		if node.RPSIn > 0 {
+			sample.nodeError[node.ID] = math.Min(1, node.RejectedRPS/node.RPSIn)
+		}
+		sample.latency[node.ID] = node.LatencyMS
+		sample.rps[node.ID] = node.RPSIn
+		sample.down[node.ID] = node.Failed
+	}
+	e.faultSamples = append(e.faultSamples, sample)
+}
+
+// faultReports builds the fault timeline, measuring each injected
+// occurrence against the ticks no fault was active. Overlapping faults
+// share the blame for what happened while both were active.
+func (e *Engine) faultReports() []FaultReport {
+	if len(e.faultWindows) == 0 {
+		return nil
+	}
+
+	// Fault-free baseline; an idle system when every tick had a fault
+	baselineError, baselineP99, baselineTicks := 0.0, 0.0, 0
+	baseline := nodeBaseline{
+		errorRate: make(map[string]float64),
+		latency:   make(map[string]float64),
+		rps:       make(map[string]float64),
+	}
+	for _, sample := range e.faultSamples {
+		if sample.faulted {
+			continue
+		}
+		baselineTicks++
+		baselineError += tickErrorRate(sample)
+		baselineP99 += sample.p99
+		for _, nodeID := range e.state.NodeOrder {
+			baseline.errorRate[nodeID] += sample.nodeError[nodeID]
+			baseline.latency[nodeID] += sample.latency[nodeID]
+			baseline.rps[nodeID] += sample.rps[nodeID]
+		}
+	}
+	for _, nodeID := range e.state.NodeOrder {
+		if baselineTicks > 0 {
+			baseline.errorRate[nodeID] /= float64(baselineTicks)
+			baseline.latency[nodeID] /= float64(baselineTicks)
+			baseline.rps[nodeID] /= float64(baselineTicks)
+		} else {
+			baseline.latency[nodeID] = e.state.NodeStates[nodeID].BaseLatencyMS
+		}
+	}
+	if baselineTicks > 0 {
+		baselineError /= float64(baselineTicks)
+		baselineP99 /= float64(baselineTicks)
+	}
+
+	reports := make([]FaultReport, 0, len(e.faultWindows))
+	for _, window := range e.faultWindows {
+		failure := e.config.Failures[window.failure]
+		report := FaultReport{
+			Type:      failure.Type,
+			Target:    failure.target(),
+			StartTick: window.start,
+			EndTick:   window.end,
+			Injected:  window.injected,
+			Events:    window.events,
+		}
+		if _, ok := DefaultFaultSeverity[failure.Type]; ok {
+			report.Severity = math.Round(failure.severity()*1000) / 10
+		}
+		if window.injected {
+			report.BlastRadius = e.blastRadius(window, baselineError, baselineP99, baseline)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// blastRadius compares the ticks of one fault occurrence with the baseline
+func (e *Engine) blastRadius(window *faultWindow, baselineError, baselineP99 float64, baseline nodeBaseline) *BlastRadius {
+	radius := &BlastRadius{AffectedNodes: []string{}}
+	end := minInt(window.end, len(e.faultSamples))
+	ticks := end - window.start + 1
+	if ticks <= 0 {
+		return radius
+	}
+
+	errorRate, p99 := 0.0, 0.0
+	windowError := make(map[string]float64)
+	windowLatency := make(map[string]float64)
+	windowRPS := make(map[string]float64)
+	down := make(map[string]bool)
+	for tick := window.start; tick <= end; tick++ {
+		sample := e.faultSamples[tick-1]
+		errorRate += tickErrorRate(sample)
+		p99 += sample.p99
+		radius.FailedRequests += sample.failed
+		for _, nodeID := range e.state.NodeOrder {
+			windowError[nodeID] += sample.nodeError[nodeID] / float64(ticks)
+			windowLatency[nodeID] += sample.latency[nodeID] / float64(ticks)
+			windowRPS[nodeID] += sample.rps[nodeID] / float64(ticks)
+			down[nodeID] = down[nodeID] || sample.down[nodeID]
+		}
+	}
+
+	for _, nodeID := range e.state.NodeOrder {
+		erroring := windowError[nodeID]-baseline.errorRate[nodeID] >= 0.01
+		slower := windowLatency[nodeID] > baseline.latency[nodeID]*1.2 && windowLatency[nodeID]-baseline.latency[nodeID] >= 1
+		starved := windowRPS[nodeID] < baseline.rps[nodeID]*0.9 // Cut off by a failure upstream
+		if down[nodeID] || erroring || slower || starved {
+			radius.AffectedNodes = append(radius.AffectedNodes, nodeID)
+		}
+	}
+	radius.ErrorRateIncrease = math.Round((errorRate/float64(ticks)-baselineError)*10000) / 100
+	radius.P99LatencyIncrease = math.Round((p99/float64(ticks)-baselineP99)*100) / 100
+	return radius
+}
+
+// tickErrorRate is the share of a tick's requests that failed
+func tickErrorRate(sample faultSample) float64 {
+	if sample.total <= 0 {
+		return 0
+	}
+	return math.Min(1, float64(sample.failed)/float64(sample.total))
+}
+
+// maxInt returns the larger of two ints
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
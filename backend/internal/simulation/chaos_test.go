@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chaosInput runs the client -> api -> db chain for 30s with the given faults
+func chaosInput(failures ...FailureInjection) *SimulationInput {
+	seed := int64(1)
+	input := validationInput()
+	input.Workload.DurationSeconds = 30
+	input.Workload.Seed = &seed
+	input.Workload.Failures = failures
+	return input
+}
+
+func TestRecurringSchedule(t *testing.T) {
+	schedule := &FailureSchedule{Type: ScheduleRecurring, IntervalSeconds: 10, DurationSeconds: 3}
+	output, err := NewEngine(chaosInput(FailureInjection{Type: FailureNodeFail, NodeID: "db", StartTick: 2, Schedule: schedule})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := [][2]int{}
+	for _, fault := range output.Faults {
+		windows = append(windows, [2]int{fault.StartTick, fault.EndTick})
+	}
+	if want := [][2]int{{2, 4}, {12, 14}, {22, 24}}; !reflect.DeepEqual(windows, want) {
+		t.Fatalf("faults at %v, want %v", windows, want)
+	}
+	for _, point := range output.TimeSeries {
+		down := tickMetrics(t, point, "db").Status == "failed"
+		if want := point.Tick >= 2 && (point.Tick-2)%10 < 3; down != want {
+			t.Errorf("tick %d: db failed = %v", point.Tick, down)
+		}
+	}
+
+	schedule.Occurrences = 2
+	if output, err = NewEngine(chaosInput(FailureInjection{Type: FailureNodeFail, NodeID: "db", StartTick: 2, Schedule: schedule})).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Faults) != 2 {
+		t.Errorf("got %d occurrences, want 2", len(output.Faults))
+	}
+}
+
+// Each occurrence is injected with the failure's probability; skipped ones
+// leave the system alone and have no blast radius
+func TestFaultProbability(t *testing.T) {
+	schedule := &FailureSchedule{Type: ScheduleRecurring, IntervalSeconds: 1, DurationSeconds: 1}
+	output, err := NewEngine(chaosInput(FailureInjection{Type: FailureNodeFail, NodeID: "db", Probability: 0.5, Schedule: schedule})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	injected := 0
+	for _, fault := range output.Faults {
+		db := tickMetrics(t, output.TimeSeries[fault.StartTick-1], "db")
+		if fault.Injected != (db.Status == "failed") || fault.Injected != (fault.BlastRadius != nil) {
+			t.Errorf("tick %d: injected = %v with db %s and blast radius %+v", fault.StartTick, fault.Injected, db.Status, fault.BlastRadius)
+		}
+		if fault.Injected {
+			injected++
+		}
+	}
+	if len(output.Faults) != 30 || injected < 5 || injected > 25 {
+		t.Errorf("%d of %d occurrences injected, want about half of 30", injected, len(output.Faults))
+	}
+}
+
+// Severity sets the share of requests a dependency answers with errors, and
+// the blast radius measures it against the fault-free ticks
+func TestDependencyErrorsBlastRadius(t *testing.T) {
+	output, err := NewEngine(chaosInput(FailureInjection{Type: FailureDependencyErrors, NodeID: "db", Severity: 25, StartTick: 11, EndTick: 20})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fault := output.Faults[0]
+	if fault.Severity != 25 || fault.Target != "db" || fault.BlastRadius == nil {
+		t.Fatalf("got fault %+v", fault)
+	}
+	radius := fault.BlastRadius
+	if !reflect.DeepEqual(radius.AffectedNodes, []string{"db"}) {
+		t.Errorf("affected %v, want only db", radius.AffectedNodes)
+	}
+	if math.Abs(radius.ErrorRateIncrease-25) > 1 || math.Abs(float64(radius.FailedRequests)-250) > 5 {
+		t.Errorf("error rate up %.2f points with %d failures, want 25 and 250", radius.ErrorRateIncrease, radius.FailedRequests)
+	}
+}
+
+// A leak grows until the process is OOM-killed; it restarts and leaks again
+func TestMemoryLeakOOMKills(t *testing.T) {
+	output, err := NewEngine(chaosInput(FailureInjection{Type: FailureMemoryLeak, NodeID: "api", Severity: 10})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := output.Faults[0].Events
+	if len(events) < 2 || !strings.Contains(events[0], "api OOM-killed") {
+		t.Fatalf("events %v, want repeated OOM kills", events)
+	}
+	restarting, rising := 0, false
+	for i, point := range output.TimeSeries {
+		api := tickMetrics(t, point, "api")
+		if api.Status == "failed" {
+			restarting++
+		} else if i > 0 && api.MemPercent > tickMetrics(t, output.TimeSeries[i-1], "api").MemPercent {
+			rising = true
+		}
+	}
+	if restarting < DefaultOOMRestartSeconds || !rising {
+		t.Errorf("api down %d ticks with rising memory = %v, want a restart after leaking", restarting, rising)
+	}
+}
+
+// Skew within the node's tolerance is harmless; past it requests are rejected
+func TestClockSkewTolerance(t *testing.T) {
+	for _, tt := range []struct {
+		skewMs   int
+		failures bool
+	}{
+		{skewMs: 60000},
+		{skewMs: DefaultClockSkewMs, failures: true},
+		{skewMs: -DefaultClockSkewMs, failures: true},
+	} {
+		output, err := NewEngine(chaosInput(FailureInjection{Type: FailureClockSkew, NodeID: "api", SkewMs: tt.skewMs})).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failed := output.Metrics.FailedRequests > 0; failed != tt.failures {
+			t.Errorf("skew %dms: %d failed requests", tt.skewMs, output.Metrics.FailedRequests)
+		}
+	}
+}
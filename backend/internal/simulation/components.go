@@ -0,0 +1,654 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+)
+
+// Component categories: what a node is, whatever its exact type
+const (
+	ComponentClient     = "client"     // Generates the workload
+	ComponentNetwork    = "network"    // Load balancers, gateways, DNS
+	ComponentCompute    = "compute"    // Application servers and workers
+	ComponentDatabase   = "database"   // Stores data; reads stop here, writes emit change events
+	ComponentCache      = "cache"      // In-memory cache in front of a database
+	ComponentCDN        = "cdn"        // Edge cache
+	ComponentQueue      = "queue"      // Buffers messages between producers and consumers
+	ComponentStorage    = "storage"    // Object/file storage and search
+	ComponentTelemetry  = "telemetry"  // Only observes traffic (monitoring, logging)
+	ComponentServerless = "serverless" // Functions
+	ComponentML         = "ml"         // Model serving endpoints
+	ComponentOther      = "other"
+)
+
+// Types a queue or CDN is simulated as when its config names none
+const (
+	defaultQueueType = "sqs-standard"
+	defaultCDNType   = "cloudfront-basic"
+)
+
+// Cost buckets of CostMetrics
+const (
+	costCompute = "compute"
+	costStorage = "storage"
+	costNetwork = "network"
+)
+
+// ComponentModel is how one kind of node behaves in the simulation. Every
+// node type maps to one through the registry, so all code paths agree on it.
+type ComponentModel interface {
+	// Name is the canonical node type; aliases share it
+	Name() string
+	// Category is the broad kind of component
+	Category() string
+	// Performance is the capacity (RPS per replica) and base latency of a node with this config
+	Performance(hw HardwareProvider, config map[string]interface{}) (capacityRPS, latencyMS float64)
+	// ResourceUsage is the CPU, memory, disk and network use at a load ratio (incoming RPS over capacity)
+	ResourceUsage(node *NodeState, loadRatio float64) ResourceUsage
+	// Outgoing is the traffic a healthy node sends downstream for what it received
+	Outgoing(e *Engine, node *NodeState, incoming trafficMix) trafficMix
+	// Cost is what the node costs over a run of durationSeconds
+	Cost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem
+	// Scalable reports whether replicas can be added and removed
+	Scalable() bool
+}
+
+// costItem is one line of a node's bill over a run
+type costItem struct {
+	bucket   string // costCompute, costStorage or costNetwork
+	category string // Line in that bucket, e.g. "database_storage"
+	usd      float64
+}
+
+// instanceTable sizes a component by the instanceType of its config. The
+// instance types it lists keep their measured sizes; for any other type the
+// hardware provider knows, capacity and latency follow from its specs.
+type instanceTable struct {
+	catalogType    string                   // Catalog component listing the table's instance types
+	defaultType    string                   // Used when the config sets none
+	fallback       string                   // Size an unknown instance type is simulated as
+	sizes          map[string]instanceSize  // Measured sizes, by instance type
+	perVCPU        float64                  // RPS per vCPU of a fixed-performance instance
+	perGB          float64                  // RPS per GB of a burstable instance: CPU credits, not cores, bound it
+	latencyMS      float64                  // Latency of a 2 vCPU fixed-performance instance
+	burstLatencyMS float64                  // Latency of a 4 GB burstable instance
+	families       map[string]familyProfile // By instance class (c, r, ...)
+}
+
+// burstableCeiling is the share of a fixed-performance instance's capacity a
+// burstable one with as many vCPUs sustains at most
+const burstableCeiling = 0.8
+
+// instanceSize is the capacity (RPS per replica) and latency of an instance type
+type instanceSize struct {
+	capacityRPS float64
+	latencyMS   float64
+}
+
+// familyProfile scales an instance family against a general purpose one
+type familyProfile struct {
+	capacity float64
+	latency  float64
+}
+
+// Instance tables of the compute, database and cache components
+var (
+	computeInstances = &instanceTable{
+		catalogType: "api_server",
+		defaultType: "t3.medium",
+		fallback:    "t3.medium",
+		sizes: map[string]instanceSize{
+			"t3.micro":   {500, 50},
+			"t3.small":   {1000, 30},
+			"t3.medium":  {2000, 20},
+			"t3.large":   {4000, 15},
+			"m5.large":   {5000, 10},
+			"m5.xlarge":  {10000, 8},
+			"m5.2xlarge": {20000, 5},
+			"c5.large":   {6000, 8},
+			"c5.xlarge":  {12000, 6},
+			"c5.2xlarge": {25000, 4},
+		},
+		perVCPU:        2500,
+		perGB:          500,
+		latencyMS:      10,
+		burstLatencyMS: 20,
+		families:       map[string]familyProfile{"c": {capacity: 1.2, latency: 0.8}},
+	}
+	databaseInstances = &instanceTable{
+		catalogType: "database_sql",
+		defaultType: "db.t3.medium",
+		fallback:    "db.t3.medium",
+		sizes: map[string]instanceSize{
+			"db.t3.micro":  {100, 50},
+			"db.t3.small":  {300, 30},
+			"db.t3.medium": {800, 20},
+			"db.m5.large":  {2000, 10},
+			"db.m5.xlarge": {5000, 8},
+			"db.r5.large":  {3000, 12},
+			"db.r5.xlarge": {7000, 8},
+		},
+		perVCPU:        1000,
+		perGB:          200,
+		latencyMS:      10,
+		burstLatencyMS: 20,
+		families:       map[string]familyProfile{"r": {capacity: 1.5, latency: 1.2}},
+	}
+	cacheInstances = &instanceTable{
+		catalogType: "cache_redis",
+		defaultType: "cache.t3.micro",
+		fallback:    "cache.t3.small",
+		sizes: map[string]instanceSize{
+			"cache.t3.micro":  {5000, 5},
+			"cache.t3.small":  {10000, 3},
+			"cache.m5.large":  {25000, 2},
+			"cache.m5.xlarge": {50000, 1},
+			"cache.r5.large":  {40000, 2},
+			"cache.r5.xlarge": {80000, 1},
+		},
+		perVCPU:        12500,
+		perGB:          10000,
+		latencyMS:      2,
+		burstLatencyMS: 1.77,
+		families:       map[string]familyProfile{"r": {capacity: 1.6, latency: 1}},
+	}
+)
+
+// instanceTypes lists the instance types the table knows, cheapest first
+func (t *instanceTable) instanceTypes(hw HardwareProvider) []string {
+	return hw.InstanceTypes(t.catalogType)
+}
+
+// size returns the capacity (RPS per replica) and latency of an instance type
+func (t *instanceTable) size(hw HardwareProvider, instanceType string) (float64, float64) {
+	spec, ok := hw.Instance(instanceType)
+	if !ok {
+		instanceType = t.fallback
+		spec = GetInstancePerformance(hw, instanceType)
+	}
+	if size, ok := t.sizes[instanceType]; ok {
+		return size.capacityRPS, size.latencyMS
+	}
+
+	vcpu := float64(maxInt(spec.VCPU, 1))
+	capacityRPS := t.perVCPU * vcpu
+	latencyMS := t.latencyMS * math.Sqrt(2/vcpu)
+	if spec.CPUCredits {
+		// Credits cap a burstable instance below a fixed one with the same cores
+		memoryGB := math.Max(spec.MemoryGB, 0.25)
+		capacityRPS = math.Min(t.perGB*memoryGB, capacityRPS*burstableCeiling)
+		latencyMS = math.Max(t.burstLatencyMS*math.Sqrt(4/memoryGB), latencyMS)
+	}
+	if profile, ok := t.families[instanceClass(instanceType)]; ok {
+		capacityRPS *= profile.capacity
+		latencyMS *= profile.latency
+	}
+	return math.Round(capacityRPS), math.Round(latencyMS*10) / 10
+}
+
+// component is a ComponentModel assembled from one function per aspect;
+// unset aspects take the defaults of an unknown node type
+type component struct {
+	name        string
+	category    string
+	scalable    bool
+	instances   *instanceTable // Sizes the node when set, unless performance is
+	performance func(hw HardwareProvider, config map[string]interface{}) (float64, float64)
+	resources   func(node *NodeState, loadRatio float64) ResourceUsage
+	outgoing    func(e *Engine, node *NodeState, incoming trafficMix) trafficMix
+	cost        func(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem
+}
+
+func (c *component) Name() string     { return c.name }
+func (c *component) Category() string { return c.category }
+func (c *component) Scalable() bool   { return c.scalable }
+
+func (c *component) Performance(hw HardwareProvider, config map[string]interface{}) (float64, float64) {
+	switch {
+	case c.performance != nil:
+		return c.performance(hw, config)
+	case c.instances != nil:
+		return c.instances.size(hw, getString(config, "instanceType", c.instances.defaultType))
+	default:
+		return 1000.0, 10.0 // Conservative defaults
+	}
+}
+
+func (c *component) ResourceUsage(node *NodeState, loadRatio float64) ResourceUsage {
+	if c.resources == nil {
+		return calculateDefaultResources(loadRatio)
+	}
+	return c.resources(node, loadRatio)
+}
+
+func (c *component) Outgoing(e *Engine, node *NodeState, incoming trafficMix) trafficMix {
+	if c.outgoing == nil {
+		return incoming // Pass everything on; downstream capacity limits it
+	}
+	return c.outgoing(e, node, incoming)
+}
+
+func (c *component) Cost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	if c.cost == nil {
+		return nil
+	}
+	return c.cost(hw, node, durationSeconds)
+}
+
+// componentModels maps every node type, aliases included, to its model
+var componentModels = make(map[string]ComponentModel)
+
+// defaultComponent models node types nothing is registered for
+var defaultComponent ComponentModel = &component{category: ComponentOther}
+
+// registerComponent makes a model handle its own node type and any aliases
+func registerComponent(model ComponentModel, aliases ...string) {
+	for _, nodeType := range append([]string{model.Name()}, aliases...) {
+		componentModels[nodeType] = model
+	}
+}
+
+// componentModel returns the model of a node type
+func componentModel(nodeType string) ComponentModel {
+	if model, ok := componentModels[nodeType]; ok {
+		return model
+	}
+	return defaultComponent
+}
+
+// isKnownNodeType reports whether a model is registered for a node type
+func isKnownNodeType(nodeType string) bool {
+	_, ok := componentModels[nodeType]
+	return ok
+}
+
+// knownNodeTypes lists every registered node type, aliases included
+func knownNodeTypes() []string {
+	types := make([]string, 0, len(componentModels))
+	for nodeType := range componentModels {
+		types = append(types, nodeType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// isClientType reports whether a node type generates the workload
+func isClientType(nodeType string) bool {
+	return ComponentCategory(nodeType) == ComponentClient
+}
+
+// ComponentCategory returns the category of a node type (ComponentOther when unknown)
+func ComponentCategory(nodeType string) string {
+	return componentModel(nodeType).Category()
+}
+
+// unsizedTypes are the node types the simulator didn't size before they
+// shared a model with the types they alias. Until its config names an
+// instance or queue type, a node of one keeps the conservative defaults it
+// always had, so existing designs simulate as before. Client aliases and
+// serverless functions are sized by their models on purpose.
+var unsizedTypes = map[string]bool{
+	"worker": true, "web_server": true, "microservice": true,
+	"database_sql": true, "database_nosql": true, "database_graph": true, "database_timeseries": true,
+	"queue": true, "message_broker": true, "event_bus": true,
+	"file_storage": true,
+}
+
+// nodePerformance is the capacity (RPS per replica) and latency of a node
+func nodePerformance(hw HardwareProvider, nodeType string, config map[string]interface{}) (float64, float64) {
+	if unsizedTypes[nodeType] && getString(config, "instanceType", "") == "" && getString(config, "queueType", "") == "" {
+		return defaultComponent.Performance(hw, config)
+	}
+	return componentModel(nodeType).Performance(hw, config)
+}
+
+// instanceTableOf returns the instance table sizing a node type, if any
+func instanceTableOf(nodeType string) *instanceTable {
+	if c, ok := componentModel(nodeType).(*component); ok && c.performance == nil {
+		return c.instances
+	}
+	return nil
+}
+
+func init() {
+	// ==================== CLIENTS ====================
+	registerComponent(&component{
+		name:        "client",
+		category:    ComponentClient,
+		performance: fixedPerformance(1000000.0, 0.0), // Clients generate unlimited traffic
+	}, "mobile_app", "web_browser")
+
+	// ==================== NETWORK ====================
+	registerComponent(&component{
+		name:        "load_balancer",
+		category:    ComponentNetwork,
+		scalable:    true,
+		performance: loadBalancerPerformance,
+		resources:   loadOnly(calculateLoadBalancerResources),
+		cost:        loadBalancerCost,
+	}, "api_gateway", "reverse_proxy")
+	registerComponent(&component{
+		name:        "subnet",
+		category:    ComponentNetwork,
+		performance: fixedPerformance(1000000000.0, 0.0), // Logical container: passthrough
+	})
+	registerComponent(&component{
+		name:        "dns",
+		category:    ComponentNetwork,
+		performance: fixedPerformance(1000000.0, 2.0), // Resolvers cache answers; the lookup is a tiny share of a request
+	}, "global_traffic_manager")
+
+	// ==================== COMPUTE ====================
+	registerComponent(&component{
+		name:      "api_server",
+		category:  ComponentCompute,
+		scalable:  true,
+		instances: computeInstances,
+		resources: loadOnly(calculateComputeResources),
+		cost:      instanceCost(costCompute, "compute_instances", 0.096), // Fallback: m5.large
+	}, "compute", "web_server", "microservice")
+	registerComponent(&component{
+		name:      "worker",
+		category:  ComponentCompute,
+		scalable:  true,
+		instances: computeInstances,
+		resources: loadOnly(calculateWorkerResources),
+		cost:      instanceCost(costCompute, "compute_instances", 0.096),
+	})
+
+	// ==================== DATABASES ====================
+	registerComponent(newDatabase("database_sql", calculateSQLDatabaseResources), "database_postgres", "database_mysql")
+	registerComponent(newDatabase("database_nosql", calculateNoSQLDatabaseResources), "database_mongodb")
+	registerComponent(newDatabase("database_graph", loadOnly(calculateGraphDatabaseResources)))
+	registerComponent(newDatabase("database_timeseries", loadOnly(calculateTimeSeriesDatabaseResources)))
+
+	// ==================== CACHES ====================
+	registerComponent(newCache("cache_redis"))
+	registerComponent(newCache("cache_memcached"))
+	registerComponent(&component{
+		name:        "cdn",
+		category:    ComponentCDN,
+		performance: cdnPerformance, // Higher latency due to edge locations
+		resources:   loadOnly(calculateCDNResources),
+		outgoing:    cacheOutgoing,
+		cost:        cdnCost,
+	}, "cdn_cloudfront")
+
+	// ==================== QUEUES & MESSAGE BROKERS ====================
+	registerComponent(&component{
+		name:        "queue",
+		category:    ComponentQueue,
+		performance: queuePerformance,
+		resources:   calculateQueueResources,
+		outgoing:    queueOutgoing,
+		cost:        queueCost,
+	}, "queue_sqs", "queue_kafka", "queue_rabbitmq", "message_broker", "event_bus")
+	for _, name := range []string{"azure_service_bus", "gcp_pub_sub"} {
+		registerComponent(&component{
+			name:      name,
+			category:  ComponentQueue,
+			resources: loadOnly(calculateManagedMessagingResources),
+			outgoing:  queueOutgoing,
+			cost:      queueCost,
+		})
+	}
+
+	// ==================== STORAGE & SEARCH ====================
+	registerComponent(&component{
+		name:        "object_storage",
+		category:    ComponentStorage,
+		performance: fixedPerformance(5500.0, 100.0), // S3 limit per prefix
+		resources:   loadOnly(calculateStorageResources),
+		cost:        objectStorageCost,
+	}, "storage_s3", "file_storage")
+	registerComponent(&component{
+		name:        "search",
+		category:    ComponentStorage,
+		performance: searchPerformance,
+		resources:   loadOnly(calculateSearchResources),
+		cost:        searchCost,
+	}, "search_elasticsearch")
+
+	// ==================== TELEMETRY & OBSERVABILITY ====================
+	registerComponent(&component{
+		name:      "monitoring",
+		category:  ComponentTelemetry,
+		resources: loadOnly(calculateTelemetryResources),
+	}, "logging", "analytics_service")
+	registerComponent(&component{name: "apm", category: ComponentOther, resources: loadOnly(calculateAPMResources)})
+	registerComponent(&component{name: "rum", category: ComponentOther, resources: loadOnly(calculateRUMResources)})
+	registerComponent(&component{name: "synthetic_monitoring", category: ComponentOther, resources: loadOnly(calculateSyntheticMonitoringResources)})
+	registerComponent(&component{name: "sidecar_proxy", category: ComponentNetwork, resources: loadOnly(calculateSidecarProxyResources)})
+
+	// ==================== MODERN API & EDGE ====================
+	registerComponent(&component{name: "graphql_gateway", category: ComponentNetwork, resources: loadOnly(calculateGraphQLGatewayResources)})
+	registerComponent(&component{name: "grpc_server", category: ComponentCompute, resources: loadOnly(calculateGRPCServerResources)})
+	registerComponent(&component{name: "wasm_runtime", category: ComponentCompute, resources: loadOnly(calculateWASMRuntimeResources)})
+	registerComponent(&component{name: "blockchain_node", category: ComponentOther, resources: loadOnly(calculateBlockchainNodeResources)})
+
+	// ==================== SERVERLESS & ML ====================
+	registerComponent(&component{
+		name:        "lambda_function",
+		category:    ComponentServerless,
+		performance: functionPerformance,
+		resources:   loadOnly(calculateServerlessResources),
+		cost:        functionCost,
+	}, "cloud_function", "azure_function")
+	registerComponent(&component{
+		name:      "sagemaker_endpoint",
+		category:  ComponentML,
+		resources: loadOnly(calculateMLEndpointResources),
+	}, "vertex_ai_endpoint", "azure_ml_endpoint")
+
+	// ==================== KUBERNETES & PAAS ====================
+	registerComponent(&component{name: "k8s_pod", category: ComponentCompute, resources: loadOnly(calculateK8sPodResources)})
+	registerComponent(&component{name: "k8s_service", category: ComponentNetwork, resources: loadOnly(calculateK8sServiceResources)})
+	registerComponent(&component{name: "k8s_ingress", category: ComponentNetwork, resources: loadOnly(calculateK8sIngressResources)})
+	registerComponent(&component{
+		name:      "azure_app_service",
+		category:  ComponentCompute,
+		resources: loadOnly(calculatePaaSWebAppResources),
+	}, "gcp_app_engine")
+	registerComponent(&component{
+		name:      "azure_cosmos_db",
+		category:  ComponentDatabase,
+		resources: loadOnly(calculateCosmosDBResources),
+		outgoing:  databaseOutgoing,
+	})
+	registerComponent(&component{
+		name:      "gcp_firestore",
+		category:  ComponentDatabase,
+		resources: loadOnly(calculateFirestoreResources),
+		outgoing:  databaseOutgoing,
+	})
+}
+
+// newDatabase is an instance-sized database with replicas and storage
+func newDatabase(name string, resources func(*NodeState, float64) ResourceUsage) *component {
+	return &component{
+		name:      name,
+		category:  ComponentDatabase,
+		scalable:  true,
+		instances: databaseInstances,
+		resources: resources,
+		outgoing:  databaseOutgoing,
+		cost:      databaseCost,
+	}
+}
+
+// newCache is an instance-sized in-memory cache
+func newCache(name string) *component {
+	return &component{
+		name:      name,
+		category:  ComponentCache,
+		scalable:  true,
+		instances: cacheInstances,
+		resources: calculateCacheResources,
+		outgoing:  cacheOutgoing,
+		cost:      instanceCost(costCompute, "cache", 0.136), // Fallback: cache.m5.large
+	}
+}
+
+// loadOnly adapts a resource curve that only depends on the load
+func loadOnly(curve func(loadRatio float64) ResourceUsage) func(*NodeState, float64) ResourceUsage {
+	return func(_ *NodeState, loadRatio float64) ResourceUsage {
+		return curve(loadRatio)
+	}
+}
+
+// fixedPerformance is a capacity and latency no config changes
+func fixedPerformance(capacityRPS, latencyMS float64) func(HardwareProvider, map[string]interface{}) (float64, float64) {
+	return func(HardwareProvider, map[string]interface{}) (float64, float64) {
+		return capacityRPS, latencyMS
+	}
+}
+
+// loadBalancerPerformance sizes a load balancer by its lbType
+func loadBalancerPerformance(hw HardwareProvider, config map[string]interface{}) (float64, float64) {
+	perf, ok := hw.LoadBalancer(getString(config, "lbType", "alb"))
+	if !ok {
+		perf = offlineLoadBalancers["alb"]
+	}
+	capacityRPS, latencyMS := perf.CapacityRPS, perf.LatencyMs
+
+	// Internal LBs are faster (no WAF/public internet overhead)
+	if getString(config, "accessType", "external") == "internal" {
+		latencyMS *= 0.6 // 40% latency reduction
+	}
+	return capacityRPS, latencyMS
+}
+
+// queuePerformance sizes a queue by its queueType
+func queuePerformance(hw HardwareProvider, config map[string]interface{}) (float64, float64) {
+	if perf, ok := hw.Queue(getString(config, "queueType", defaultQueueType)); ok {
+		return perf.ThroughputRPS, perf.LatencyMs
+	}
+	return 10000.0, 15.0
+}
+
+// cdnPerformance gives a CDN the edge latency of its cdnType
+func cdnPerformance(hw HardwareProvider, config map[string]interface{}) (float64, float64) {
+	perf, ok := hw.CDN(getString(config, "cdnType", defaultCDNType))
+	if !ok {
+		perf = offlineCDNs[defaultCDNType]
+	}
+	return 1000000.0, perf.LatencyMs
+}
+
+// searchPerformance sizes a search engine; a managed searchType is faster
+func searchPerformance(hw HardwareProvider, config map[string]interface{}) (float64, float64) {
+	searchType := getString(config, "searchType", "")
+	if searchType == "" {
+		return 1000.0, 50.0
+	}
+	if perf, ok := hw.Search(searchType); ok {
+		return perf.QueriesPerSec, perf.LatencyMs
+	}
+	return 5000.0, 30.0
+}
+
+// cacheOutgoing absorbs the reads a cache or CDN hits; misses and every
+// write go through to the origin
+func cacheOutgoing(_ *Engine, node *NodeState, incoming trafficMix) trafficMix {
+	cacheHitRate := node.CacheHitRate
+	if cacheHitRate < 0 {
+		cacheHitRate = 0.75 // Default 75% hit rate
+	}
+	return trafficMix{Reads: incoming.Reads * (1.0 - cacheHitRate), Writes: incoming.Writes}
+}
+
+// databaseOutgoing follows the CDC pattern: reads stop at the database,
+// only writes emit downstream events. Read replicas reject writes, so they
+// emit nothing.
+func databaseOutgoing(_ *Engine, node *NodeState, incoming trafficMix) trafficMix {
+	if node.isReadReplica() {
+		return trafficMix{}
+	}
+	return trafficMix{Writes: incoming.Writes}
+}
+
+// queueOutgoing decouples producers from consumers: consumers receive what
+// they can drain (backlog first), not what producers published
+func queueOutgoing(e *Engine, node *NodeState, incoming trafficMix) trafficMix {
+	return e.queueDelivery(node, incoming)
+}
+
+// instanceCost bills every replica at its instance type's hourly rate
+func instanceCost(bucket, category string, fallbackRate float64) func(HardwareProvider, *NodeState, float64) []costItem {
+	return func(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+		hourlyRate := fallbackRate
+		if node.InstanceType != "" {
+			hourlyRate = getInstanceCost(hw, node.InstanceType)
+		}
+		return []costItem{{bucket, category, hourlyRate * float64(node.Replicas) * durationSeconds / 3600.0}}
+	}
+}
+
+// trafficCost bills the data a node sends, at ~1 KB per request
+func trafficCost(bucket, category string, perGB float64) func(HardwareProvider, *NodeState, float64) []costItem {
+	return func(_ HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+		return []costItem{{bucket, category, node.RPSOut * durationSeconds * 0.001 * perGB}}
+	}
+}
+
+// cdnCost bills the data a CDN delivers at its cdnType's price per GB
+func cdnCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	perf, ok := hw.CDN(node.CDNType)
+	if !ok {
+		perf = offlineCDNs[defaultCDNType]
+	}
+	return trafficCost(costNetwork, "cdn", perf.CostPerGBUSD)(hw, node, durationSeconds)
+}
+
+// loadBalancerCost is the hourly rate of the lbType plus processed data
+func loadBalancerCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	hourlyRate := getLBCost(hw, node.LBType)
+	usd := hourlyRate*durationSeconds/3600.0 + node.RPSOut*durationSeconds*0.001*0.008
+	return []costItem{{costCompute, "load_balancer", usd}}
+}
+
+// databaseCost is the instances plus storage at the storageType's price per
+// GB-month ($0.10 when none is set)
+func databaseCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	items := instanceCost(costCompute, "database", 0.188)(hw, node, durationSeconds)
+	if node.StorageSizeGB > 0 {
+		perGBMonth := 0.10
+		if perf, ok := hw.Storage(node.StorageType); ok {
+			perGBMonth = perf.CostPerGBMonthUSD
+		}
+		items = append(items, costItem{costStorage, "database_storage", node.StorageSizeGB * perGBMonth * (durationSeconds / 3600.0 / 720.0)})
+	}
+	return items
+}
+
+// queueCost bills the messages delivered at the queueType's price per million
+func queueCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	queueType := node.QueueType
+	if queueType == "" {
+		queueType = defaultQueueType
+	}
+	perf, ok := hw.Queue(queueType)
+	if !ok {
+		perf = offlineQueues[defaultQueueType]
+	}
+	messagesProcessed := node.RPSOut * durationSeconds
+	return []costItem{{costCompute, "queue", messagesProcessed / 1000000 * perf.CostPerMillionUSD}}
+}
+
+// searchCost bills every replica of a managed searchType at its hourly rate
+func searchCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	perf, ok := hw.Search(node.SearchType)
+	if !ok {
+		return nil
+	}
+	return []costItem{{costCompute, "search", perf.HourlyCostUSD * float64(node.Replicas) * durationSeconds / 3600.0}}
+}
+
+// objectStorageCost is $0.023 per GB stored plus $0.09 per GB out
+func objectStorageCost(hw HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	items := []costItem{}
+	if node.StorageSizeGB > 0 {
+		items = append(items, costItem{costStorage, "object_storage", node.StorageSizeGB * 0.023 * (durationSeconds / 3600.0 / 720.0)})
+	}
+	return append(items, trafficCost(costNetwork, "egress", 0.09)(hw, node, durationSeconds)...)
+}
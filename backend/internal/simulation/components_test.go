@@ -0,0 +1,98 @@
+package simulation
+
+import "testing"
+
+// Listed instance types keep their measured sizes; others are derived from
+// their specs, and unknown ones are sized as the fallback
+func TestInstanceTableSize(t *testing.T) {
+	tests := []struct {
+		table        *instanceTable
+		instanceType string
+		capacity     float64
+		latency      float64
+	}{
+		{computeInstances, "t3.micro", 500, 50},
+		{computeInstances, "c5.2xlarge", 25000, 4},
+		{computeInstances, "no-such-type", 2000, 20},
+		{databaseInstances, "db.r5.large", 3000, 12},
+		{databaseInstances, "no-such-type", 800, 20},
+		{cacheInstances, "cache.m5.xlarge", 50000, 1},
+		{cacheInstances, "no-such-type", 10000, 3},
+	}
+	for _, tt := range tests {
+		capacity, latency := tt.table.size(OfflineHardware(), tt.instanceType)
+		if capacity != tt.capacity || latency != tt.latency {
+			t.Errorf("%s: got %v RPS / %v ms, want %v / %v", tt.instanceType, capacity, latency, tt.capacity, tt.latency)
+		}
+	}
+
+	// m5.4xlarge isn't listed: it sizes from its 16 vCPUs, above m5.2xlarge
+	if capacity, _ := computeInstances.size(OfflineHardware(), "m5.4xlarge"); capacity <= 20000 {
+		t.Errorf("m5.4xlarge: got %v RPS, want more than m5.2xlarge", capacity)
+	}
+}
+
+// Node types that weren't sized before the registry keep their defaults
+// until their config picks a size; aliases sized before keep their tables
+func TestNodePerformanceDefaults(t *testing.T) {
+	tests := []struct {
+		nodeType string
+		config   map[string]interface{}
+		capacity float64
+		latency  float64
+	}{
+		{"worker", nil, 1000, 10},
+		{"worker", map[string]interface{}{"instanceType": "m5.large"}, 5000, 10},
+		{"database_sql", nil, 1000, 10},
+		{"database_sql", map[string]interface{}{"instanceType": "db.m5.large"}, 2000, 10},
+		{"database_postgres", nil, 800, 20},
+		{"queue", nil, 1000, 10},
+		{"queue", map[string]interface{}{"queueType": "kafka-standard"}, 100000, 5},
+		{"queue_sqs", nil, 3000, 20},
+		{"api_server", nil, 2000, 20},
+		{"file_storage", nil, 1000, 10},
+		{"object_storage", nil, 5500, 100},
+		{"no_such_type", nil, 1000, 10},
+		// Sized by the client model on purpose: client aliases send the
+		// workload unhindered
+		{"mobile_app", nil, 1000000, 0},
+	}
+	for _, tt := range tests {
+		config := tt.config
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+		capacity, latency := nodePerformance(OfflineHardware(), tt.nodeType, config)
+		if capacity != tt.capacity || latency != tt.latency {
+			t.Errorf("%s %v: got %v RPS / %v ms, want %v / %v", tt.nodeType, tt.config, capacity, latency, tt.capacity, tt.latency)
+		}
+	}
+}
+
+// Aliases share their model, so every code path treats them alike
+func TestComponentRegistryAliases(t *testing.T) {
+	aliases := map[string][]string{
+		"database_sql": {"database_postgres", "database_mysql"},
+		"queue":        {"queue_sqs", "queue_kafka", "queue_rabbitmq"},
+		"client":       {"mobile_app", "web_browser"},
+	}
+	for name, names := range aliases {
+		for _, alias := range names {
+			if componentModel(alias) != componentModel(name) {
+				t.Errorf("%s doesn't share the model of %s", alias, name)
+			}
+		}
+	}
+
+	if isKnownNodeType("no_such_type") || componentModel("no_such_type") != defaultComponent {
+		t.Error("unknown types must fall back to the default model")
+	}
+	for _, nodeType := range knownNodeTypes() {
+		if componentModel(nodeType).Category() == "" {
+			t.Errorf("%s has no category", nodeType)
+		}
+	}
+	if !componentModel("api_server").Scalable() || componentModel("client").Scalable() {
+		t.Error("api servers scale out, clients don't")
+	}
+}
@@ -0,0 +1,117 @@
+package simulation
+
+import "fmt"
+
+// Changes a running simulation accepts between ticks
+const (
+	ChangeSetRPS        = "set_rps"
+	ChangeInjectFailure = "inject_failure"
+	ChangeClearFailure  = "clear_failure"
+	ChangeSetReplicas   = "set_replicas"
+)
+
+// Change is a mutation of a running simulation, applied before the next tick
+type Change struct {
+	Type     string            `json:"type"`
+	RPS      int               `json:"rps,omitempty"`      // set_rps
+	Failure  *FailureInjection `json:"failure,omitempty"`  // inject_failure: the fault; clear_failure: which faults (default: all)
+	NodeID   string            `json:"nodeId,omitempty"`   // set_replicas
+	Replicas int               `json:"replicas,omitempty"` // set_replicas
+}
+
+// ApplyChange mutates a running simulation between ticks, starting the run
+// if needed. Injected failures start on the next tick at the earliest;
+// cleared ones stop before it.
+func (e *Engine) ApplyChange(change Change) error {
+	if !e.started {
+		if err := e.Start(); err != nil {
+			return err
+		}
+	}
+	if err := e.applyChange(change); err != nil {
+		return err
+	}
+	if change.Failure != nil {
+		failure := *change.Failure // Keep the journal safe from the caller reusing it
+		change.Failure = &failure
+	}
+	e.journal = append(e.journal, JournalEntry{Tick: e.state.Tick, Change: change})
+	return nil
+}
+
+// applyChange makes a change to the running simulation
+func (e *Engine) applyChange(change Change) error {
+	next := e.state.Tick + 1
+
+	switch change.Type {
+	case ChangeSetRPS:
+		if change.RPS <= 0 {
+			return fmt.Errorf("RPS must be greater than 0")
+		}
+		e.config.RPS = change.RPS
+		// A replayed schedule gives way to the RPS set live
+		if e.config.Mode == WorkloadPiecewise || e.config.Mode == WorkloadTrace {
+			e.config.Mode = "constant"
+		}
+
+	case ChangeInjectFailure:
+		if change.Failure == nil {
+			return fmt.Errorf("inject_failure needs a failure")
+		}
+		failure := *change.Failure
+		if failure.StartTick < next {
+			failure.StartTick = next
+		}
+		if failure.EndTick > 0 && failure.EndTick < failure.StartTick {
+			return fmt.Errorf("failure would end (tick %d) before it starts (tick %d)", failure.EndTick, failure.StartTick)
+		}
+		// Copy on append: the failures may still be shared with the input
+		failures := e.config.Failures
+		e.config.Failures = append(failures[:len(failures):len(failures)], failure)
+		e.faultWindows = append(e.faultWindows, e.failureWindows(len(e.config.Failures)-1)...)
+
+	case ChangeClearFailure:
+		cleared := 0
+		for _, window := range e.faultWindows {
+			if !window.injected || window.end < next {
+				continue
+			}
+			if change.Failure != nil && !e.config.Failures[window.failure].matches(*change.Failure) {
+				continue
+			}
+			if window.start >= next {
+				window.injected = false // Scheduled, not started yet: skip it
+			} else {
+				window.end = next - 1
+			}
+			cleared++
+		}
+		if cleared == 0 {
+			return fmt.Errorf("no active or scheduled failure matches")
+		}
+
+	case ChangeSetReplicas:
+		node := e.state.NodeStates[change.NodeID]
+		if node == nil {
+			return fmt.Errorf("unknown node %q", change.NodeID)
+		}
+		if change.Replicas < 1 {
+			return fmt.Errorf("replicas must be at least 1")
+		}
+		node.Replicas = change.Replicas
+
+	default:
+		return fmt.Errorf("unknown change type %q", change.Type)
+	}
+	return nil
+}
+
+// matches reports whether a failure fits a filter: every field set on the
+// filter (type, node, edge, region, zone) must be equal
+func (f FailureInjection) matches(filter FailureInjection) bool {
+	return (filter.Type == "" || f.Type == filter.Type) &&
+		(filter.NodeID == "" || f.NodeID == filter.NodeID) &&
+		(filter.EdgeID == "" || f.EdgeID == filter.EdgeID) &&
+		(filter.Region == "" || f.Region == filter.Region) &&
+		(filter.Zone == "" || f.Zone == filter.Zone)
+}
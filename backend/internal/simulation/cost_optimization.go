@@ -23,28 +23,29 @@ type CostOptimizationRecommendation struct {
 func (e *Engine) AnalyzeCostOptimizations() []CostOptimizationRecommendation {
 	recommendations := []CostOptimizationRecommendation{}
 
-	for nodeID, node := range e.state.NodeStates {
+	for _, node := range e.orderedNodes() {
+		nodeID := node.ID
 		// Analyze each component type
-		switch node.Type {
-		case "api_server", "web_server", "microservice", "worker":
+		switch ComponentCategory(node.Type) {
+		case ComponentCompute:
 			rec := e.analyzeComputeOptimization(nodeID, node)
 			if rec != nil {
 				recommendations = append(recommendations, *rec)
 			}
 
-		case "database_sql", "database_nosql", "database_graph", "database_timeseries":
+		case ComponentDatabase:
 			rec := e.analyzeDatabaseOptimization(nodeID, node)
 			if rec != nil {
 				recommendations = append(recommendations, *rec)
 			}
 
-		case "cache_redis", "cache_memcached":
+		case ComponentCache:
 			rec := e.analyzeCacheOptimization(nodeID, node)
 			if rec != nil {
 				recommendations = append(recommendations, *rec)
 			}
 
-		case "load_balancer", "api_gateway":
+		case ComponentNetwork:
 			rec := e.analyzeNetworkOptimization(nodeID, node)
 			if rec != nil {
 				recommendations = append(recommendations, *rec)
@@ -247,7 +248,7 @@ func (e *Engine) analyzeOverProvisioning(nodeID string, node *NodeState) *CostOp
 // analyzeUnderUtilization detects idle or rarely used resources
 func (e *Engine) analyzeUnderUtilization(nodeID string, node *NodeState) *CostOptimizationRecommendation {
 	// If component receives almost no traffic, recommend removal
-	if node.RPSIn < 0.1 && node.Type != "monitoring" && node.Type != "logging" {
+	if node.RPSIn < 0.1 && !isTelemetry(node.Type) {
 		return &CostOptimizationRecommendation{
 			ComponentID:    nodeID,
 			ComponentType:  node.Type,
@@ -304,41 +305,17 @@ func (e *Engine) EstimateDataTransferCost() float64 {
 
 // Helper functions for cost estimation (private)
 func (e *Engine) estimateInstanceCost(instanceType string) float64 {
-	// Simplified AWS EC2 pricing (hourly)
-	costs := map[string]float64{
-		"t3.micro":   0.0104,
-		"t3.small":   0.0208,
-		"t3.medium":  0.0416,
-		"t3.large":   0.0832,
-		"m5.large":   0.096,
-		"m5.xlarge":  0.192,
-		"m5.2xlarge": 0.384,
-		"c5.large":   0.085,
-		"c5.xlarge":  0.17,
-		"c5.2xlarge": 0.34,
-		"r5.large":   0.126,
-		"r5.xlarge":  0.252,
-	}
-
-	if cost, ok := costs[instanceType]; ok {
-		return cost
+	// On-demand hourly price from the hardware catalog
+	if perf, ok := e.hardware.Instance(instanceType); ok {
+		return perf.HourlyCostUSD
 	}
 	return 0.10 // Default
 }
 
 func (e *Engine) estimateStorageCost(storageType string, sizeGB float64) float64 {
-	// AWS EBS pricing per GB-month
-	pricePerGB := map[string]float64{
-		"gp2": 0.10,
-		"gp3": 0.08,
-		"io1": 0.125,
-		"io2": 0.125,
-		"st1": 0.045,
-		"sc1": 0.015,
-	}
-
-	if price, ok := pricePerGB[storageType]; ok {
-		return price * sizeGB / 730 // Convert to hourly
+	// EBS price per GB-month from the hardware catalog
+	if perf, ok := e.hardware.Storage(storageType); ok {
+		return perf.CostPerGBMonthUSD * sizeGB / 730 // Convert to hourly
 	}
 	return 0.10 * sizeGB / 730
 }
@@ -387,7 +364,7 @@ func isBurstable(instanceType string) bool {
 func (e *Engine) CalculateTotalMonthlyCost() float64 {
 	totalCost := 0.0
 
-	for _, node := range e.state.NodeStates {
+	for _, node := range e.orderedNodes() {
 		// Instance cost
 		if node.InstanceType != "" {
 			totalCost += e.estimateInstanceCost(node.InstanceType) * 730 * float64(node.Replicas)
@@ -409,7 +386,7 @@ func (e *Engine) estimateDataTransferCost() float64 {
 	// Simplified data transfer cost
 	// $0.09 per GB for inter-region, $0.01 per GB within region
 	totalTransferGB := 0.0
-	for _, node := range e.state.NodeStates {
+	for _, node := range e.orderedNodes() {
 		// Estimate based on RPS (assume 100KB per request)
 		totalTransferGB += (node.RPSOut * 100 * 86400 * 30) / (1024 * 1024 * 1024)
 	}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"sort"
 	"time"
 )
 
@@ -13,74 +12,195 @@ type Engine struct {
 	input  *SimulationInput
 	state  *SimulationState
 	config WorkloadConfig
+	seed   int64
 	rand   *rand.Rand
+
+	hardware HardwareProvider // Specs and prices nodes are sized and billed with
+
+	topology           *graphTopology
+	cycleAmplification map[int]float64 // component index -> last observed load amplification
+	routes             map[string][]routeEdge
+	routingPolicies    map[string]string
+	tickLatency        []latencySample // End-to-end latency samples from the current tick
+	guards             map[string]map[string]*edgeGuard
+	guardOrder         []*edgeGuard
+	entryOrigins       map[string]map[string]float64 // Entry node -> user region -> RPS entering this tick
+	tickOriginLatency  map[string]float64            // User region -> end-to-end latency this tick
+	replicationGroups  []*replicationGroup
+	tickWriteDown      []string // Primary databases that couldn't take writes this tick
+	tickCacheHits      float64  // Reads served from cache this tick
+	tickCacheReads     float64  // Reads that reached a cache this tick
+	faultWindows       []*faultWindow
+	edgeLoss           map[string]map[string]float64 // Source -> target -> share of requests lost this tick
+	faultSamples       []faultSample                 // Per-tick health, for blast-radius reports
+	sampledFailed      int
+	sampledTotal       int
+	burstOffset        int     // Shifts burst mode's guaranteed bursts (Monte Carlo)
+	cacheHitVariance   float64 // Relative std dev of per-tick cache hit ratios (Monte Carlo)
+	timeSeries         []TimeSeriesPoint
+	autoscalingEvents  []AutoscalingEvent
+	started            bool
+	finalized          bool
+	journal            []JournalEntry // Changes applied so far, replayed by Restore
 }
 
-// NewEngine creates a new simulation engine
+// NewEngine creates a new simulation engine.
+// All randomness is drawn from a single RNG seeded from workload.seed; when no
+// seed is given one is picked from the clock and echoed back in the output so
+// the run can be replayed exactly.
 func NewEngine(input *SimulationInput) *Engine {
+	seed := time.Now().UnixNano()
+	if input.Workload.Seed != nil {
+		seed = *input.Workload.Seed
+	}
+
 	return &Engine{
-		input:  input,
-		config: input.Workload,
-		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		input:    input,
+		config:   input.Workload,
+		seed:     seed,
+		hardware: input.hardware(),
+		rand:     rand.New(rand.NewSource(seed)),
 	}
 }
 
+// Seed returns the seed driving this engine's random path
+func (e *Engine) Seed() int64 {
+	return e.seed
+}
+
 // Run executes the simulation
 func (e *Engine) Run() (*SimulationOutput, error) {
-	startTime := time.Now()
-
-	// Initialize state
-	if err := e.InitializeState(); err != nil {
-		return nil, fmt.Errorf("failed to initialize state: %w", err)
+	if err := e.Start(); err != nil {
+		return nil, err
 	}
 
 	// Run simulation ticks
-	timeSeries := make([]TimeSeriesPoint, 0, e.config.DurationSeconds)
-	autoscalingEvents := []AutoscalingEvent{}
-
-	for tick := 1; tick <= e.config.DurationSeconds; tick++ {
-		e.state.Tick = tick
-
-		// Generate workload for this tick
-		currentRPS := e.generateWorkload(tick)
-		e.state.CurrentWorkloadRPS = currentRPS
-
-		// Reset transient state for all nodes (failures are re-applied each tick)
-		for _, node := range e.state.NodeStates {
-			node.Failed = false
-			node.Partitioned = false
-			node.CapacityRPS = node.BaseCapacityRPS // Restore capacity
-			// Restore base latency (remove previous network delays)
-			// processNodeWithTraffic will add current delays back
-			// We don't need to manually reset LatencyMS here because processNodeWithTraffic
-			// overwrites it starting from BaseLatencyMS.
-		}
+	for !e.Done() {
+		e.step()
+	}
 
-		// Apply failure injections
-		e.applyFailures(tick)
+	return e.Finalize()
+}
 
-		// Route requests through the architecture
-		e.routeRequests(currentRPS)
+// Start initializes the state and the run's time series, ready for the first
+// tick. Step calls it when needed; calling it again restarts the run.
+func (e *Engine) Start() error {
+	if err := e.InitializeState(); err != nil {
+		return fmt.Errorf("failed to initialize state: %w", err)
+	}
+	e.timeSeries = make([]TimeSeriesPoint, 0, e.config.DurationSeconds)
+	e.autoscalingEvents = []AutoscalingEvent{}
+	e.started, e.finalized = true, false
+	e.journal = nil
+	return nil
+}
 
-		// Update queues
-		e.updateQueues()
+// Done reports whether every tick of the run has been simulated
+func (e *Engine) Done() bool {
+	return e.started && e.state.Tick >= e.config.DurationSeconds
+}
 
-		// Apply auto-scaling if enabled
-		tickScalingEvents := []AutoscalingEvent{}
-		if e.config.AutoScaling != nil && e.config.AutoScaling.Enabled {
-			events := e.applyAutoScaling(tick)
-			autoscalingEvents = append(autoscalingEvents, events...)
-			tickScalingEvents = events
+// Tick returns the last simulated tick (0 before the first)
+func (e *Engine) Tick() int {
+	if e.state == nil {
+		return 0
+	}
+	return e.state.Tick
+}
+
+// Step simulates the next tick and returns its metrics, starting the run on
+// the first call
+func (e *Engine) Step() (TimeSeriesPoint, error) {
+	if !e.started {
+		if err := e.Start(); err != nil {
+			return TimeSeriesPoint{}, err
 		}
+	}
+	if e.Done() {
+		return TimeSeriesPoint{}, fmt.Errorf("simulation already ran its %d ticks", e.config.DurationSeconds)
+	}
+	return e.step(), nil
+}
+
+// step simulates the next tick and returns its metrics
+func (e *Engine) step() TimeSeriesPoint {
+	tick := e.state.Tick + 1
+	e.state.Tick = tick
+
+	// Generate workload for this tick
+	currentRPS := e.generateWorkload(tick)
+	e.state.CurrentWorkloadRPS = currentRPS
+
+	// Reset transient state for all nodes (failures are re-applied each tick)
+	for _, node := range e.orderedNodes() {
+		node.Failed = false
+		node.Partitioned = false
+		node.CapacityRPS = node.BaseCapacityRPS // Restore capacity
+		node.RejectedRPS = 0
+		node.RetryRPS = 0
+		node.ColdStarts = 0
+		node.ThrottledRPS = 0
+		// Restore base latency (remove previous network delays)
+		// processNodeWithTraffic treats anything above BaseLatencyMS as injected delay,
+		// so last tick's queueing and cross-region latency must not carry over
+		node.LatencyMS = node.BaseLatencyMS
+		node.network = 0
+		node.faults = nodeFaults{}
+	}
+
+	// Bring replicas that finished provisioning into service
+	tickScalingEvents := e.activateReplicas(tick)
+
+	// Apply failure injections
+	e.applyFailures(tick)
+
+	// Caches warm up (or stay cold after a flush) from what they served so far
+	e.updateCacheHitRates()
+
+	// Promote a standby/replica once a failed primary's failover completes
+	e.applyFailover(tick)
+
+	// Route requests through the architecture
+	e.routeRequests(currentRPS)
+
+	// Update queues
+	e.updateQueues()
+
+	// Advance replica lag and record write unavailability
+	e.updateReplication()
+
+	// Apply per-node and workload-wide auto-scaling policies
+	tickScalingEvents = append(tickScalingEvents, e.applyAutoScaling(tick)...)
+	e.autoscalingEvents = append(e.autoscalingEvents, tickScalingEvents...)
+
+	// Collect metrics for this tick (including scaling events)
+	point := e.collectTimeSeriesPoint(tick, currentRPS)
+	point.ScalingEvents = tickScalingEvents
+	e.recordFaultSample(tick, point)
+	e.addGuardStates(&point)
+	e.timeSeries = append(e.timeSeries, point)
+	return point
+}
 
-		// Collect metrics for this tick (including scaling events)
-		point := e.collectTimeSeriesPoint(tick, currentRPS)
-		point.ScalingEvents = tickScalingEvents
-		timeSeries = append(timeSeries, point)
+// Finalize ends the run and computes its aggregate metrics and reports from
+// the ticks simulated so far
+func (e *Engine) Finalize() (*SimulationOutput, error) {
+	if !e.started || e.state.Tick == 0 {
+		return nil, fmt.Errorf("no tick simulated yet")
+	}
+	if e.finalized {
+		return nil, fmt.Errorf("simulation already finalized")
 	}
+	e.finalized = true
+
+	// A run stopped early covers only the ticks simulated
+	e.config.DurationSeconds = e.state.Tick
+
+	// Retries that never got another attempt within the run count as failures
+	e.abandonPendingRetries()
 
 	// Calculate aggregate metrics
-	metrics := e.calculateAggregateMetrics(autoscalingEvents)
+	metrics := e.calculateAggregateMetrics(e.autoscalingEvents)
 
 	// Detect bottlenecks
 	bottlenecks := e.detectBottlenecks()
@@ -91,15 +211,29 @@ func (e *Engine) Run() (*SimulationOutput, error) {
 	// Calculate cost metrics
 	costMetrics := e.calculateCostMetrics()
 
-	duration := time.Since(startTime)
+	// Report dependency cycles and how much load they amplify
+	cycles := e.cycleWarnings()
+	warnings := []string{}
+	for _, cycle := range cycles {
+		warnings = append(warnings, cycle.Message)
+	}
+
+	// Report the simulated span rather than wall-clock time so that the same
+	// input + seed always serializes to the same bytes
+	duration := time.Duration(e.config.DurationSeconds) * time.Second
 
 	return &SimulationOutput{
 		Metrics:       metrics,
-		TimeSeries:    timeSeries,
+		TimeSeries:    e.timeSeries,
 		Bottlenecks:   bottlenecks,
 		SLAViolations: slaViolations,
 		CostMetrics:   costMetrics,
+		CriticalPaths: e.criticalPaths(),
+		Cycles:        cycles,
+		Faults:        e.faultReports(),
+		Warnings:      warnings,
 		Duration:      duration,
+		Seed:          e.seed,
 		Success:       true,
 	}, nil
 }
@@ -110,8 +244,10 @@ func (e *Engine) InitializeState() error {
 		Tick:               0,
 		CurrentWorkloadRPS: 0,
 		NodeStates:         make(map[string]*NodeState),
+		NodeOrder:          make([]string, 0, len(e.input.Nodes)),
 		EdgeMap:            make(map[string][]string),
 		ReverseEdgeMap:     make(map[string][]string),
+		LatencyHistogram:   make(map[float64]float64),
 		LatencyHistory:     make([]float64, 0),
 		ThroughputHistory:  make([]float64, 0),
 		ErrorHistory:       make([]int, 0),
@@ -119,55 +255,60 @@ func (e *Engine) InitializeState() error {
 		ActiveFailures:     make([]string, 0),
 		RegionLatency:      make(map[string][]float64),
 		RegionTraffic:      make(map[string]float64),
+		paths:              make(map[string]*pathStats),
+		retries:            make(map[int][]pendingRetry),
+		attemptedLoad:      make(map[string]float64),
+		freshLoad:          make(map[string]float64),
+		originLatency:      make(map[string]float64),
+		originRPS:          make(map[string]float64),
+		writeDowntime:      make(map[string]int),
 	}
 
 	// Initialize node states
 	for idx, node := range e.input.Nodes {
-		// Determine region - distribute nodes across regions for multi-region support
-		region := "default"
-		if len(e.config.Regions) > 0 {
-			// Round-robin distribution across regions
-			region = e.config.Regions[idx%len(e.config.Regions)]
-		}
+		region, zone := e.nodePlacement(idx, node)
 
 		// Calculate capacity and latency from hardware configuration
-		capacityRPS, latencyMS := calculateHardwarePerformance(node.Data.NodeType, node.Data.Config)
+		capacityRPS, latencyMS := nodePerformance(e.hardware, node.Data.NodeType, node.Data.Config)
 
 		state := &NodeState{
-			ID:            node.ID,
-			Type:          node.Data.NodeType,
-			InstanceType:  getString(node.Data.Config, "instanceType", ""),
-			LBType:        getString(node.Data.Config, "lbType", ""),
-			AccessType:    getString(node.Data.Config, "accessType", "external"), // Default to external for LBs
-			CapacityRPS:   capacityRPS,
+			ID:              node.ID,
+			Type:            node.Data.NodeType,
+			InstanceType:    getString(node.Data.Config, "instanceType", ""),
+			LBType:          getString(node.Data.Config, "lbType", ""),
+			StorageType:     getString(node.Data.Config, "storageType", ""),
+			QueueType:       getString(node.Data.Config, "queueType", ""),
+			CDNType:         getString(node.Data.Config, "cdnType", ""),
+			SearchType:      getString(node.Data.Config, "searchType", ""),
+			AccessType:      getString(node.Data.Config, "accessType", "external"), // Default to external for LBs
+			CapacityRPS:     capacityRPS,
 			BaseCapacityRPS: capacityRPS, // Store original capacity
-			BaseLatencyMS: latencyMS, // ORIGINAL latency (never changes)
-			LatencyMS:     latencyMS, // CURRENT latency (will be updated each tick)
-			Replicas:      getInt(node.Data.Config, "replicas", 1),
-			StorageSizeGB: getFloat(node.Data.Config, "storage_size_gb", 0),
-			TTL:           getInt(node.Data.Config, "ttl_ms", 3600000),
-			Consistency:   getString(node.Data.Config, "consistency", "strong"),
-			Region:        region,
-			CurrentLoad:   0,
-			RPSIn:         0,
-			RPSOut:        0,
-			QueueDepth:    0,
-			MaxQueueDepth: 100000, // Default max queue depth
-			CacheHitRate:  0.75,   // Default cache hit rate
-			CPUUsage:      0,
-			MemoryUsage:   20, // Base memory usage
-			ErrorCount:    0,
-			Failed:        false,
-			ReadRatio:     getInt(node.Data.Config, "readRatio", 80), // Default 80% reads
-		}
-
-		// Adjust cache hit rate for cache nodes and CDNs
-		if state.Type == "cache_redis" || state.Type == "cache_memcached" {
-			state.CacheHitRate = 0.80
-		} else if state.Type == "cdn" || state.Type == "cdn_cloudfront" {
-			state.CacheHitRate = 0.90 // CDNs have high hit rates for static content
+			BaseLatencyMS:   latencyMS,   // ORIGINAL latency (never changes)
+			LatencyMS:       latencyMS,   // CURRENT latency (will be updated each tick)
+			Replicas:        getInt(node.Data.Config, "replicas", 1),
+			StorageSizeGB:   getFloat(node.Data.Config, "storage_size_gb", 0),
+			TTL:             getInt(node.Data.Config, "ttl_ms", 3600000),
+			Consistency:     getString(node.Data.Config, "consistency", "strong"),
+			Region:          region,
+			Zone:            zone,
+			CurrentLoad:     0,
+			RPSIn:           0,
+			RPSOut:          0,
+			QueueDepth:      0,
+			MaxQueueDepth:   getInt(node.Data.Config, "maxQueueDepth", 100000), // Default max queue depth
+			ConsumerRate:    getFloat(node.Data.Config, "consumerRate", 0),
+			CacheHitRate:    0.75, // Default cache hit rate
+			CPUUsage:        0,
+			MemoryUsage:     20, // Base memory usage
+			ErrorCount:      0,
+			Failed:          false,
+			ReadRatio:       getInt(node.Data.Config, "readRatio", 80), // Default 80% reads
+			Role:            getString(node.Data.Config, "role", "primary"),
+		}
+
+		if _, exists := e.state.NodeStates[node.ID]; !exists {
+			e.state.NodeOrder = append(e.state.NodeOrder, node.ID)
 		}
-
 		e.state.NodeStates[node.ID] = state
 	}
 
@@ -177,6 +318,23 @@ func (e *Engine) InitializeState() error {
 		e.state.ReverseEdgeMap[edge.Target] = append(e.state.ReverseEdgeMap[edge.Target], edge.Source)
 	}
 
+	// Plan traffic propagation once: the graph shape doesn't change between ticks
+	e.topology = e.buildTopology()
+	e.cycleAmplification = make(map[int]float64)
+	e.routes = e.buildRoutes()
+	e.guards, e.guardOrder = e.buildEdgeGuards()
+	e.resolveScalingPolicies()
+	e.replicationGroups = e.buildReplicationGroups()
+	e.buildCacheModels()
+	e.buildFunctionModels()
+	e.faultWindows = e.buildFaultWindows()
+	e.faultSamples, e.sampledFailed, e.sampledTotal = nil, 0, 0
+	e.config.Shape = e.config.Shape.normalized()
+	e.routingPolicies = make(map[string]string)
+	for _, nodeID := range e.state.NodeOrder {
+		e.routingPolicies[nodeID] = e.routingPolicy(nodeID)
+	}
+
 	return nil
 }
 
@@ -184,13 +342,17 @@ func (e *Engine) InitializeState() error {
 func (e *Engine) generateWorkload(tick int) float64 {
 	baseRPS := float64(e.config.RPS)
 
+	if rps, ok := e.shapedWorkload(tick); ok {
+		return rps
+	}
+
 	switch e.config.Mode {
 	case "constant":
 		return baseRPS
 
 	case "burst":
 		// Random bursts every 5-10 ticks
-		if tick%7 == 0 || e.rand.Float64() < 0.15 {
+		if (tick+e.burstOffset)%burstPeriod == 0 || e.rand.Float64() < 0.15 {
 			return baseRPS * (1.5 + e.rand.Float64()*0.5) // 1.5x-2x burst
 		}
 		return baseRPS
@@ -220,151 +382,139 @@ func (e *Engine) routeRequests(rps float64) {
 		return
 	}
 
-	// Initialize traffic maps
-	nodeIncomingRPS := make(map[string]float64)
-	nodeOutgoingRPS := make(map[string]float64)
+	// Split entry traffic across entry nodes (by user region when a
+	// distribution is given), as separate read and write flows
+	var entryTraffic map[string]trafficMix
+	entryTraffic, e.entryOrigins = e.splitEntryTraffic(rps, entryNodes)
 
-	// Set entry node traffic
-	rpsPerEntry := rps / float64(len(entryNodes))
-	for _, entryID := range entryNodes {
-		nodeIncomingRPS[entryID] = rpsPerEntry
+	// Retries scheduled by earlier ticks are re-sent on the edge that failed,
+	// through its breaker, rate limiter and bulkhead
+	retries := e.takeRetries(e.state.Tick)
+	retryFlows := make(map[string]map[string]trafficMix)
+	for _, retry := range retries {
+		if retryFlows[retry.source] == nil {
+			retryFlows[retry.source] = make(map[string]trafficMix)
+		}
+		retryFlows[retry.source][retry.target] = retryFlows[retry.source][retry.target].add(retry.traffic)
 	}
 
-	// Propagate traffic through the graph layer by layer
-	// Use iterative approach: process nodes when all their inputs are ready
-	maxIterations := 20
-	for iteration := 0; iteration < maxIterations; iteration++ {
-		processed := make(map[string]bool)
+	// Propagate traffic through the graph in topological order (fan-in is
+	// summed before a node is visited; cycles are solved with a feedback factor).
+	// With retries in flight, a retry-free pass first gives the baseline load.
+	var baseline *tickTraffic
+	if len(retries) > 0 {
+		baseline = e.propagateTraffic(entryTraffic, nil)
+	}
+	traffic := e.propagateTraffic(entryTraffic, retryFlows)
+	for _, sourceID := range e.state.NodeOrder {
+		for targetID, retry := range traffic.retried[sourceID] {
+			e.state.NodeStates[targetID].RetryRPS += retry.Total()
+		}
+	}
+	e.recordRetryAmplification(traffic, baseline)
 
-		// Try to process each node
-		for nodeID := range e.state.NodeStates {
-			if processed[nodeID] {
-				continue
-			}
+	// Process each node with its final incoming traffic
+	for _, component := range e.topology.components {
+		for _, nodeID := range component {
+			// Idle nodes are processed too so their metrics drop back to zero
+			// (e.g. behind an open circuit breaker) and queues keep draining
+			e.processNodeWithTraffic(nodeID, traffic.incoming[nodeID])
+		}
+	}
 
-			// Check if this node has incoming traffic calculated
-			// (either it's an entry node, or all its parents have been processed)
-			hasIncoming := nodeIncomingRPS[nodeID] > 0
-
-			if !hasIncoming {
-				// Calculate incoming traffic from all parents (FAN-IN aggregation)
-				totalIncoming := 0.0
-
-				// Use ReverseEdgeMap to find all parents of this node
-				parents := e.state.ReverseEdgeMap[nodeID]
-
-				for _, parentID := range parents {
-					if nodeOutgoingRPS[parentID] > 0 {
-						// Get the parent's target list to determine fan-out factor
-						parentTargets := e.state.EdgeMap[parentID]
-						numTargets := float64(len(parentTargets))
-
-						if numTargets > 0 {
-							// Parent divides its outgoing traffic among all its targets
-							trafficFromParent := nodeOutgoingRPS[parentID] / numTargets
-							totalIncoming += trafficFromParent
-						}
-					}
-				}
+	// Now that every hop's latency is known, account it along each request path
+	e.recordPathLatencies(entryTraffic, traffic)
+	e.recordOriginLatency(traffic)
 
-				if totalIncoming > 0 {
-					nodeIncomingRPS[nodeID] = totalIncoming
-					hasIncoming = true
-				}
-			}
+	// Breakers, rate limiters and bulkheads react to what the targets just did
+	e.updateEdgeGuards(traffic)
 
-			// If node has incoming traffic and hasn't been processed, process it now
-			if hasIncoming && nodeOutgoingRPS[nodeID] == 0 {
-				node := e.state.NodeStates[nodeID]
-				if node != nil {
-					// Calculate outgoing traffic
-					nodeOutgoingRPS[nodeID] = e.calculateNodeOutgoing(node, nodeIncomingRPS[nodeID])
-					processed[nodeID] = true
-				}
-			}
-		}
+	// Requests lost to packet loss never reached their target
+	e.countPacketLoss(traffic)
 
-		// If nothing was processed this iteration, we're done
-		if len(processed) == 0 {
-			break
-		}
-	}
+	// Requests a node had no edge to send to fail at that node
+	e.countUnrouted(traffic)
 
-	// STEP 2: Process each node with its final incoming traffic
-	for nodeID, incomingRPS := range nodeIncomingRPS {
-		if incomingRPS > 0 {
-			e.processNodeWithTraffic(nodeID, incomingRPS)
-		}
+	// Failed calls covered by a retry policy come back in later ticks
+	e.scheduleRetries(traffic, retries)
+}
+
+// readFraction returns the share of workload requests that are reads (default 80%)
+func (e *Engine) readFraction() float64 {
+	ratio := e.config.ReadWriteRatio
+	if ratio.Read <= 0 && ratio.Write <= 0 {
+		return 0.8
 	}
+	if ratio.Read < 0 {
+		ratio.Read = 0
+	}
+	if ratio.Write < 0 {
+		ratio.Write = 0
+	}
+	return float64(ratio.Read) / float64(ratio.Read+ratio.Write)
 }
 
-// calculateNodeOutgoing calculates how much RPS a node sends downstream
-func (e *Engine) calculateNodeOutgoing(node *NodeState, incomingRPS float64) float64 {
+// calculateNodeOutgoing calculates how much traffic a node sends downstream
+func (e *Engine) calculateNodeOutgoing(node *NodeState, incoming trafficMix) trafficMix {
 	if node.Failed {
-		return 0
+		return trafficMix{}
 	}
-	
+
 	if node.Partitioned {
 		// Network partition: drops all outgoing traffic (blackhole)
-		return 0
+		return trafficMix{}
 	}
 
-	// CRITICAL FIX: Most nodes pass ALL incoming traffic downstream!
-	// The downstream node will handle capacity limiting and generate errors.
-	// This ensures traffic conservation: DB sees full attempted load from all APIs.
-
-	outgoingRPS := incomingRPS
-
-	// EXCEPTION 1: Cache/CDN reduces downstream traffic (only misses pass through)
-	if node.Type == "cache_redis" || node.Type == "cache_memcached" || node.Type == "cdn" || node.Type == "cdn_cloudfront" {
-		cacheHitRate := node.CacheHitRate
-		if cacheHitRate < 0 {
-			cacheHitRate = 0.75 // Default 75% hit rate
-		}
-		// Only cache misses go to downstream database/origin
-		outgoingRPS = incomingRPS * (1.0 - cacheHitRate)
-	}
-
-	// EXCEPTION 2: Databases - CDC Pattern (Change Data Capture)
-	// Traffic out of a database is typically CDC events (writes only)
-	if node.Type == "database_sql" || node.Type == "database_nosql" || node.Type == "database_postgres" || node.Type == "database_mysql" || node.Type == "database_mongodb" {
-		readRatioFloat := float64(node.ReadRatio) / 100.0
-		writeRatio := 1.0 - readRatioFloat
-
-		// Only Writes generate downstream events (CDC)
-		// Reads stop at the database (query response)
-		if writeRatio > 0 {
-			outgoingRPS = incomingRPS * writeRatio
-		} else {
-			outgoingRPS = 0 // Pure Read-Replica, no downstream events
-		}
+	// Requests answered with an injected error go no further
+	if node.faults.errorRate > 0 {
+		incoming = incoming.scale(1 - node.faults.errorRate)
 	}
 
-	// EXCEPTION 2: Client nodes send unlimited traffic (traffic generators)
-	if node.Type == "client" {
-		outgoingRPS = incomingRPS
-	}
-
-	return outgoingRPS
+	// Most nodes pass ALL incoming traffic downstream; the downstream node
+	// handles capacity limiting and generates the errors. Caches, databases
+	// and queues transform it (see their component models).
+	return componentModel(node.Type).Outgoing(e, node, incoming)
 }
 
 // processNodeWithTraffic processes a node with its aggregated incoming traffic
-func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
+func (e *Engine) processNodeWithTraffic(nodeID string, incoming trafficMix) {
 	node := e.state.NodeStates[nodeID]
 	if node == nil {
 		return
 	}
 
-	// Update incoming RPS
+	// Update incoming RPS and the observed read/write mix
+	incomingRPS := incoming.Total()
 	node.RPSIn = incomingRPS
+	node.ReadRPS = incoming.Reads
+	node.WriteRPS = incoming.Writes
+	if incomingRPS > 0 {
+		node.ReadRatio = int(math.Round(incoming.Reads / incomingRPS * 100))
+	}
 
 	// Check if node has failed
 	if node.Failed {
 		e.state.FailedRequests += int(incomingRPS)
 		node.ErrorCount += int(incomingRPS)
+		node.RejectedRPS = incomingRPS
 		return
 	}
 
+	// Read replicas reject writes outright; only reads compete for capacity
+	if node.isReadReplica() && incoming.Writes > 0 {
+		node.ErrorCount += int(incoming.Writes)
+		node.RejectedRPS += incoming.Writes
+		e.state.FailedRequests += int(incoming.Writes)
+		incoming.Writes = 0
+		incomingRPS = incoming.Reads
+	}
+
+	// Functions take what free concurrency fits: warm environments plus new
+	// ones paying a cold start
+	if node.function != nil && node.BaseCapacityRPS > 0 {
+		node.CapacityRPS *= node.function.capacity() / node.BaseCapacityRPS
+	}
+
 	// Calculate effective capacity
 	effectiveCapacity := node.CapacityRPS * float64(node.Replicas)
 
@@ -380,30 +530,45 @@ func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
 		throughput = effectiveCapacity
 		overflow = incomingRPS - effectiveCapacity
 		node.ErrorCount += int(overflow)
+		node.RejectedRPS += overflow
 		e.state.FailedRequests += int(overflow)
 	}
 
-	// Update outgoing RPS
-	outgoingRPS := throughput
+	// Functions start environments for what they run; the rest was throttled
+	if node.function != nil {
+		node.ColdStarts = node.function.serve(throughput)
+		node.ThrottledRPS = overflow
+		e.state.ColdStarts += int(math.Round(node.ColdStarts))
+		e.state.Throttles += int(math.Round(overflow))
+	}
+
+	// Injected errors (dependencyErrors, DNS, clock skew) fail part of what was served
+	if errors := throughput * node.faults.errorRate; errors > 0 {
+		node.ErrorCount += int(errors)
+		node.RejectedRPS += errors
+		e.state.FailedRequests += int(errors)
+	}
 
-	// Apply cache/CDN logic
-	if node.Type == "cache_redis" || node.Type == "cache_memcached" || node.Type == "cdn" || node.Type == "cdn_cloudfront" {
-		cacheHitRate := node.CacheHitRate
-		if cacheHitRate < 0 {
-			cacheHitRate = 0.75
-		}
-		// Only cache misses go to downstream nodes
-		outgoingRPS = throughput * (1.0 - cacheHitRate)
+	// Update outgoing RPS: what the node actually served, after cache/CDC transforms
+	served := trafficMix{}
+	if incomingRPS > 0 {
+		served = incoming.scale(throughput / incomingRPS)
 	}
+	node.RPSOut = e.calculateNodeOutgoing(node, served).Total()
+	e.recordCacheAccess(node, served)
 
-	node.RPSOut = outgoingRPS
+	// Queues accept what the broker can ingest; consumers drain it in updateQueues
+	if isQueue(node.Type) {
+		e.handleQueue(node, served)
+	}
 
 	// Calculate realistic resource usage based on component type
 	// Uses the new resource model that understands each component's characteristics
 	resources := calculateResourceUsage(node, incomingRPS, effectiveCapacity)
 
 	node.CPUUsage = resources.CPUPercent
-	node.MemoryUsage = resources.MemoryPercent
+	node.memory = resources.MemoryPercent
+	node.MemoryUsage = math.Min(100, resources.MemoryPercent+node.faults.leak)
 
 	// Capture any injected latency from failures (applied before this function)
 	// applyNetworkDelay adds to node.LatencyMS, so we extract the delta here
@@ -415,6 +580,9 @@ func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
 	// Calculate realistic latency based on load and queueing
 	// Use BaseLatencyMS (original hardware speed) for service time calculations
 	baseLatency := node.BaseLatencyMS
+	if node.function != nil {
+		baseLatency = node.function.latencyMS() // Cold starts slow the invocations paying them
+	}
 
 	// REAL-WORLD: Add cross-region network latency
 	// This simulates actual AWS inter-region communication delays
@@ -428,8 +596,8 @@ func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
 		if sourceNodes, ok := e.state.ReverseEdgeMap[nodeID]; ok {
 			for _, sourceID := range sourceNodes {
 				sourceNode := e.state.NodeStates[sourceID]
-				if sourceNode != nil && IsCrossRegion(sourceNode.Region, node.Region) {
-					totalCrossRegionLatency += GetRegionLatency(sourceNode.Region, node.Region)
+				if sourceNode != nil && IsCrossPlacement(sourceNode.Region, sourceNode.Zone, node.Region, node.Zone) {
+					totalCrossRegionLatency += GetPlacementLatency(sourceNode.Region, sourceNode.Zone, node.Region, node.Zone)
 					incomingEdgeCount++
 				}
 			}
@@ -439,8 +607,10 @@ func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
 			crossRegionLatency = totalCrossRegionLatency / float64(incomingEdgeCount)
 		}
 	}
-	if incomingRPS > effectiveCapacity {
+	node.network = crossRegionLatency
+	if incomingRPS > effectiveCapacity && node.function == nil {
 		// When overloaded, latency increases due to queueing
+		// (throttled function invocations fail fast instead)
 		overloadRatio := (incomingRPS - effectiveCapacity) / effectiveCapacity
 		// Add queueing delay: overload causes latency increase
 		queueingDelay := baseLatency * overloadRatio * 5.0 // 5x base latency per 100% overload
@@ -448,159 +618,33 @@ func (e *Engine) processNodeWithTraffic(nodeID string, incomingRPS float64) {
 
 		// CRITICAL: Cap latency at 30 seconds (real systems timeout!)
 		node.LatencyMS = math.Min(calculatedLatency+crossRegionLatency+injectedLatency, 30000.0)
-
-		// Record high latency for percentile calculation
-		e.state.LatencyHistory = append(e.state.LatencyHistory, node.LatencyMS)
 	} else {
 		node.LatencyMS = baseLatency + crossRegionLatency + injectedLatency
-		e.state.LatencyHistory = append(e.state.LatencyHistory, node.LatencyMS)
 	}
+	// Writes on a primary with synchronous replicas wait for their acknowledgement
+	if commit := e.syncCommitLatency(node); commit > 0 && incomingRPS > 0 {
+		node.LatencyMS += commit * incoming.Writes / incomingRPS
+	}
+	// Request latency is recorded per path (see recordPathLatencies); the
+	// per-node history is only kept for existing readers of the state
+	e.state.LatencyHistory = append(e.state.LatencyHistory, node.LatencyMS)
 
 	// DON'T count successful requests per node (causes double counting!)
 	// We'll calculate it as: TotalRequests - FailedRequests at the end
 }
 
-// OLD processNode (keeping for backward compatibility, but deprecated)
-func (e *Engine) processNode(nodeID string, incomingRPS float64, currentLatency float64, visited map[string]bool) {
-	// Prevent infinite loops
-	if visited[nodeID] {
-		return
-	}
-	visited[nodeID] = true
-
-	node := e.state.NodeStates[nodeID]
-	if node == nil {
-		return
-	}
-
-	// Check if node has failed
-	if node.Failed {
-		e.state.FailedRequests += int(incomingRPS)
-		return
-	}
-
-	// Calculate effective capacity
-	effectiveCapacity := node.CapacityRPS * float64(node.Replicas)
-
-	// Update node load and RPS tracking
-	node.CurrentLoad = incomingRPS
-	node.RPSIn += incomingRPS
-
-	// Calculate throughput
-	var throughput, overflow float64
-	if incomingRPS <= effectiveCapacity {
-		throughput = incomingRPS
-		overflow = 0
-	} else {
-		throughput = effectiveCapacity
-		overflow = incomingRPS - effectiveCapacity
-	}
-
-	node.RPSOut += throughput
-
-	// Calculate latency with overload penalty
-	nodeLatency := node.LatencyMS
-	if overflow > 0 {
-		overloadRatio := overflow / effectiveCapacity
-		nodeLatency += nodeLatency * overloadRatio * 2.0 // 2x penalty factor
-	}
-
-	totalLatency := currentLatency + nodeLatency
-
-	// Handle cache logic
-	if node.Type == "cache_redis" || node.Type == "cache_memcached" {
-		e.handleCache(node, throughput)
-	}
-
-	// Handle queue logic
-	if node.Type == "queue" || node.Type == "message_broker" {
-		e.handleQueue(node, overflow)
-		overflow = 0 // Queue absorbs overflow
-	}
-
-	// Record latency metrics
-	e.state.LatencyHistory = append(e.state.LatencyHistory, totalLatency)
-
-	// NOTE: Do NOT increment SuccessRequests here!
-	// Success is calculated as: TotalRequests - FailedRequests
-	// Counting per-node causes massive over-counting (270% error rate bug)
-
-	// Handle overflow
-	if overflow > 0 {
-		// Check if there's a queue downstream
-		targets := e.state.EdgeMap[nodeID]
-		hasQueue := false
-		for _, targetID := range targets {
-			targetNode := e.state.NodeStates[targetID]
-			if targetNode != nil && (targetNode.Type == "queue" || targetNode.Type == "message_broker") {
-				e.handleQueue(targetNode, overflow)
-				hasQueue = true
-				break
-			}
-		}
-
-		if !hasQueue {
-			e.state.FailedRequests += int(overflow)
-		}
-	}
-
-	// Route to downstream nodes
-	// Route to downstream nodes
-	targets := e.state.EdgeMap[nodeID]
-	if len(targets) > 0 {
-		mainTargets := []string{}
-		telemetryTargets := []string{}
-
-		// Classify targets
-		for _, targetID := range targets {
-			targetNode := e.state.NodeStates[targetID]
-			if targetNode != nil && (targetNode.Type == "monitoring" || targetNode.Type == "logging") {
-				telemetryTargets = append(telemetryTargets, targetID)
-			} else {
-				mainTargets = append(mainTargets, targetID)
-			}
-		}
-
-		// 1. Handle Main Targets (Load Balanced traffic splitting)
-		if len(mainTargets) > 0 {
-			rpsPerTarget := throughput / float64(len(mainTargets))
-			for _, targetID := range mainTargets {
-				e.processNode(targetID, rpsPerTarget, totalLatency, visited)
-			}
-		}
-
-		// 2. Handle Telemetry Targets (Sidecar pattern - traffic copy/sampling)
-		// Monitoring/Logging gets a COPY of traffic, doesn't consume main capacity
-		if len(telemetryTargets) > 0 {
-			// Telemetry usually samples traffic (e.g. 10%) or is batched
-			// We simulate this as 10% load to represent overhead without killing the service
-			telemetryRPS := throughput * 0.10
-			for _, targetID := range telemetryTargets {
-				// Create a new visited map branch for telemetry so it doesn't block main flow loops
-				// Telemetry is usually "fire and forget"
-				telemetryVisited := make(map[string]bool)
-				for k, v := range visited {
-					telemetryVisited[k] = v
-				}
-				e.processNode(targetID, telemetryRPS, totalLatency, telemetryVisited)
-			}
-		}
-	}
-}
-
 // findEntryNodes finds nodes where requests start (client nodes)
 func (e *Engine) findEntryNodes() []string {
 	entryNodes := []string{}
 	for _, node := range e.input.Nodes {
-		nodeType := node.Data.NodeType
-		if nodeType == "client" || nodeType == "mobile_app" || nodeType == "web_browser" {
+		if isClientType(node.Data.NodeType) {
 			entryNodes = append(entryNodes, node.ID)
 		}
 	}
 
 	// If no explicit client nodes, use nodes with no incoming edges
 	if len(entryNodes) == 0 {
-		for nodeID := range e.state.NodeStates {
+		for _, nodeID := range e.state.NodeOrder {
 			if len(e.state.ReverseEdgeMap[nodeID]) == 0 {
 				entryNodes = append(entryNodes, nodeID)
 			}
@@ -610,6 +654,16 @@ func (e *Engine) findEntryNodes() []string {
 	return entryNodes
 }
 
+// orderedNodes returns node states in input order so every pass over the
+// graph (float accumulation, history appends) is reproducible for a given seed
+func (e *Engine) orderedNodes() []*NodeState {
+	nodes := make([]*NodeState, 0, len(e.state.NodeOrder))
+	for _, nodeID := range e.state.NodeOrder {
+		nodes = append(nodes, e.state.NodeStates[nodeID])
+	}
+	return nodes
+}
+
 // Helper functions
 func getFloat(config map[string]interface{}, key string, defaultValue float64) float64 {
 	if val, ok := config[key]; ok {
@@ -648,45 +702,26 @@ func getString(config map[string]interface{}, key string, defaultValue string) s
 	return defaultValue
 }
 
-// calculatePercentile calculates the nth percentile of a sorted slice
-func calculatePercentile(sorted []float64, percentile float64) float64 {
-	if len(sorted) == 0 {
-		return 0
-	}
-
-	index := int(math.Ceil(percentile*float64(len(sorted)))) - 1
-	if index < 0 {
-		index = 0
-	}
-	if index >= len(sorted) {
-		index = len(sorted) - 1
+func getBool(config map[string]interface{}, key string, defaultValue bool) bool {
+	if val, ok := config[key]; ok {
+		if b, ok := val.(bool); ok {
+			return b
+		}
 	}
-
-	return sorted[index]
+	return defaultValue
 }
 
 // calculateAggregateMetrics calculates final metrics
 func (e *Engine) calculateAggregateMetrics(autoscalingEvents []AutoscalingEvent) AggregateMetrics {
-	// Sort latency history for percentile calculation
-	sortedLatency := make([]float64, len(e.state.LatencyHistory))
-	copy(sortedLatency, e.state.LatencyHistory)
-	sort.Float64s(sortedLatency)
-
-	// Calculate latency metrics
-	var latency LatencyMetrics
-	if len(sortedLatency) > 0 {
-		// Round all latency values to 2 decimal places for clean UI
-		latency.P50 = math.Round(calculatePercentile(sortedLatency, 0.50)*100) / 100
-		latency.P95 = math.Round(calculatePercentile(sortedLatency, 0.95)*100) / 100
-		latency.P99 = math.Round(calculatePercentile(sortedLatency, 0.99)*100) / 100
-		latency.Max = math.Round(sortedLatency[len(sortedLatency)-1]*100) / 100
-
-		sum := 0.0
-		for _, l := range sortedLatency {
-			sum += l
-		}
-		latency.Avg = math.Round((sum/float64(len(sortedLatency)))*100) / 100
-	}
+	// Latency percentiles are over requests (each path weighted by its traffic), not nodes
+	latency := latencyMetricsFromSamples(e.latencyHistogramSamples())
+
+	// Round all latency values to 2 decimal places for clean UI
+	latency.P50 = math.Round(latency.P50*100) / 100
+	latency.P95 = math.Round(latency.P95*100) / 100
+	latency.P99 = math.Round(latency.P99*100) / 100
+	latency.Avg = math.Round(latency.Avg*100) / 100
+	latency.Max = math.Round(latency.Max*100) / 100
 
 	// FIX: Calculate successful requests correctly (was being double-counted per node!)
 	// SuccessRequests = TotalRequests - FailedRequests
@@ -725,8 +760,8 @@ func (e *Engine) calculateAggregateMetrics(autoscalingEvents []AutoscalingEvent)
 
 	// Get final queue depth
 	queueDepth := 0
-	for _, node := range e.state.NodeStates {
-		if node.Type == "queue" || node.Type == "message_broker" {
+	for _, node := range e.orderedNodes() {
+		if isQueue(node.Type) {
 			queueDepth += node.QueueDepth
 		}
 	}
@@ -740,33 +775,22 @@ func (e *Engine) calculateAggregateMetrics(autoscalingEvents []AutoscalingEvent)
 		TotalRequests:      e.state.TotalRequests,
 		SuccessfulRequests: successfulRequests, // FIX: Use calculated value, not double-counted state
 		FailedRequests:     e.state.FailedRequests,
+		RetriedRequests:    e.state.RetriedRequests,
+		RetryAmplification: e.retryAmplification(),
 		AutoscalingEvents:  autoscalingEvents,
+		OriginLatency:      e.originLatency(),
+		StaleReads:         e.state.StaleReads,
+		ColdStarts:         e.state.ColdStarts,
+		Throttles:          e.state.Throttles,
+		WriteDowntime:      e.writeDowntime(),
+		Failovers:          e.state.failovers,
 	}
 }
 
 // collectTimeSeriesPoint collects metrics for a single tick (enhanced for Module 5)
 func (e *Engine) collectTimeSeriesPoint(tick int, incomingRPS float64) TimeSeriesPoint {
-	// Calculate latency percentiles from recent history
-	latency := LatencyMetrics{}
-	if len(e.state.LatencyHistory) > 0 {
-		recent := make([]float64, len(e.state.LatencyHistory))
-		copy(recent, e.state.LatencyHistory)
-		if len(recent) > 100 {
-			recent = recent[len(recent)-100:]
-		}
-		sortedRecent := make([]float64, len(recent))
-		copy(sortedRecent, recent)
-
-		latency.P50 = calculatePercentile(sortedRecent, 0.50)
-		latency.P95 = calculatePercentile(sortedRecent, 0.95)
-		latency.P99 = calculatePercentile(sortedRecent, 0.99)
-
-		sum := 0.0
-		for _, l := range recent {
-			sum += l
-		}
-		latency.Avg = sum / float64(len(recent))
-	}
+	// Calculate latency percentiles from this tick's request paths
+	latency := latencyMetricsFromSamples(e.tickLatency)
 
 	// Calculate throughput for this tick
 	throughput := float64(e.state.SuccessRequests) / float64(tick)
@@ -783,24 +807,31 @@ func (e *Engine) collectTimeSeriesPoint(tick int, incomingRPS float64) TimeSerie
 		dropRate = float64(e.state.DroppedRequests) / float64(e.state.TotalRequests)
 	}
 
-	// Calculate cache hit ratio
+	// Calculate this tick's cache hit ratio
 	cacheHitRatio := 0.0
-	totalCacheAccess := e.state.CacheHits + e.state.CacheMisses
-	if totalCacheAccess > 0 {
-		cacheHitRatio = float64(e.state.CacheHits) / float64(totalCacheAccess)
+	if e.tickCacheReads > 0 {
+		cacheHitRatio = e.tickCacheHits / e.tickCacheReads
+	}
+
+	// Count function cold starts and throttles
+	coldStarts, throttles := 0, 0
+	for _, node := range e.orderedNodes() {
+		if node.function != nil {
+			coldStarts += int(math.Round(node.ColdStarts))
+			throttles += int(math.Round(node.ThrottledRPS))
+		}
 	}
 
 	// Get queue depth and wait time
 	queueDepth := 0
 	queueWaitTime := 0.0
 	queueCount := 0
-	for _, node := range e.state.NodeStates {
-		if node.Type == "queue" || node.Type == "message_broker" {
+	for _, node := range e.orderedNodes() {
+		if isQueue(node.Type) {
 			queueDepth += node.QueueDepth
-			if node.QueueDepth > 0 && node.CapacityRPS > 0 {
-				// Estimate wait time based on queue depth and drain rate
-				drainRate := node.CapacityRPS * float64(node.Replicas)
-				queueWaitTime += float64(node.QueueDepth) / drainRate * 1000 // ms
+			if node.QueueWaitMS > 0 {
+				// Real age of the messages consumers picked up (see updateQueues)
+				queueWaitTime += node.QueueWaitMS
 				queueCount++
 			}
 		}
@@ -813,7 +844,7 @@ func (e *Engine) collectTimeSeriesPoint(tick int, incomingRPS float64) TimeSerie
 	totalCPU := 0.0
 	totalMem := 0.0
 	nodeCount := 0
-	for _, state := range e.state.NodeStates {
+	for _, state := range e.orderedNodes() {
 		effectiveCapacity := state.CapacityRPS * float64(state.Replicas)
 		if effectiveCapacity > 0 {
 			cpu := math.Min(100, (state.CurrentLoad/effectiveCapacity)*100)
@@ -862,182 +893,13 @@ func (e *Engine) collectTimeSeriesPoint(tick int, incomingRPS float64) TimeSerie
 		RegionLatencyMap:   regionLatency,
 		RegionTrafficMap:   regionTraffic,
 		RegionErrorRateMap: regionErrorRate, // Add error rate per region
+		ZoneLatencyMap:     e.calculateZoneLatency(),
+		OriginLatencyMap:   e.tickOriginLatency,
+		WriteUnavailable:   e.tickWriteDown,
+		ColdStarts:         coldStarts,
+		Throttles:          throttles,
 		NodeMetrics:        nodeMetrics,
 		FailuresActive:     e.state.ActiveFailures,
 		SLAStatus:          slaStatus,
 	}
 }
-
-// calculateHardwarePerformance determines capacity and latency from hardware configuration
-func calculateHardwarePerformance(nodeType string, config map[string]interface{}) (capacityRPS float64, latencyMS float64) {
-	// Default values
-	capacityRPS = 1000.0
-	latencyMS = 10.0
-
-	switch nodeType {
-	case "client":
-		// Clients generate unlimited traffic
-		capacityRPS = 1000000.0
-		latencyMS = 0.0
-
-	case "load_balancer", "api_gateway", "reverse_proxy":
-		// Load balancer capacity from lbType
-		lbType := getString(config, "lbType", "alb")
-
-		switch lbType {
-		case "alb":
-			capacityRPS = 50000.0
-			latencyMS = 5.0
-		case "nlb":
-			capacityRPS = 500000.0
-			latencyMS = 1.0
-		case "classic":
-			capacityRPS = 25000.0
-			latencyMS = 10.0
-		default:
-			capacityRPS = 50000.0
-			latencyMS = 5.0
-		}
-
-		// Internal LBs are faster (no WAF/public internet overhead)
-		accessType := getString(config, "accessType", "external")
-		if accessType == "internal" {
-			latencyMS *= 0.6 // 40% latency reduction
-		}
-
-	case "subnet":
-		// Subnets are logical containers (passthrough)
-		capacityRPS = 1000000000.0 // Infinite
-		latencyMS = 0.0            // Zero latency
-
-	case "api_server", "compute":
-		// Compute capacity from instance type
-		instanceType := getString(config, "instanceType", "t3.medium")
-		capacityRPS, latencyMS = getComputeCapacity(instanceType)
-
-	case "database_postgres", "database_mysql", "database_mongodb":
-		// Database capacity from instance type
-		instanceType := getString(config, "instanceType", "db.t3.medium")
-		capacityRPS, latencyMS = getDatabaseCapacity(instanceType)
-
-	case "cache_redis", "cache_memcached":
-		// Cache capacity from instance type
-		instanceType := getString(config, "instanceType", "cache.t3.micro")
-		capacityRPS, latencyMS = getCacheCapacity(instanceType)
-
-	case "queue_sqs", "queue_kafka", "queue_rabbitmq":
-		// Queue has very high throughput
-		queueType := getString(config, "queueType", "sqs-standard")
-		switch queueType {
-		case "sqs-standard", "sqs-fifo":
-			capacityRPS = 3000.0 // SQS batch limit
-			latencyMS = 20.0
-		case "kafka-standard", "kafka-premium":
-			capacityRPS = 100000.0
-			latencyMS = 5.0
-		case "rabbitmq-basic", "rabbitmq-ha":
-			capacityRPS = 50000.0
-			latencyMS = 10.0
-		default:
-			capacityRPS = 10000.0
-			latencyMS = 15.0
-		}
-
-	case "cdn_cloudfront", "cdn":
-		// CDN has massive capacity
-		capacityRPS = 1000000.0
-		latencyMS = 50.0 // Higher latency due to edge locations
-
-	case "storage_s3", "object_storage":
-		// Object storage
-		capacityRPS = 5500.0 // S3 limit per prefix
-		latencyMS = 100.0
-
-	case "search_elasticsearch", "search":
-		// Search engines
-		if getString(config, "searchType", "") == "" {
-			capacityRPS = 1000.0
-			latencyMS = 50.0
-		} else {
-			capacityRPS = 5000.0
-			latencyMS = 30.0
-		}
-
-	default:
-		// Unknown node type - use conservative defaults
-		capacityRPS = 1000.0
-		latencyMS = 10.0
-	}
-
-	return capacityRPS, latencyMS
-}
-
-// getComputeCapacity returns capacity for compute instances
-func getComputeCapacity(instanceType string) (float64, float64) {
-	switch instanceType {
-	case "t3.micro":
-		return 500.0, 50.0
-	case "t3.small":
-		return 1000.0, 30.0
-	case "t3.medium":
-		return 2000.0, 20.0
-	case "t3.large":
-		return 4000.0, 15.0
-	case "m5.large":
-		return 5000.0, 10.0
-	case "m5.xlarge":
-		return 10000.0, 8.0
-	case "m5.2xlarge":
-		return 20000.0, 5.0
-	case "c5.large":
-		return 6000.0, 8.0
-	case "c5.xlarge":
-		return 12000.0, 6.0
-	case "c5.2xlarge":
-		return 25000.0, 4.0
-	default:
-		return 2000.0, 20.0
-	}
-}
-
-// getDatabaseCapacity returns capacity for database instances
-func getDatabaseCapacity(instanceType string) (float64, float64) {
-	switch instanceType {
-	case "db.t3.micro":
-		return 100.0, 50.0
-	case "db.t3.small":
-		return 300.0, 30.0
-	case "db.t3.medium":
-		return 800.0, 20.0
-	case "db.m5.large":
-		return 2000.0, 10.0
-	case "db.m5.xlarge":
-		return 5000.0, 8.0
-	case "db.r5.large":
-		return 3000.0, 12.0
-	case "db.r5.xlarge":
-		return 7000.0, 8.0
-	default:
-		return 800.0, 20.0
-	}
-}
-
-// getCacheCapacity returns capacity for cache instances
-func getCacheCapacity(instanceType string) (float64, float64) {
-	switch instanceType {
-	case "cache.t3.micro":
-		return 5000.0, 5.0
-	case "cache.t3.small":
-		return 10000.0, 3.0
-	case "cache.m5.large":
-		return 25000.0, 2.0
-	case "cache.m5.xlarge":
-		return 50000.0, 1.0
-	case "cache.r5.large":
-		return 40000.0, 2.0
-	case "cache.r5.xlarge":
-		return 80000.0, 1.0
-	default:
-		return 10000.0, 3.0
-	}
-}
@@ -0,0 +1,61 @@
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// runJSON runs an input to the end and returns its output as JSON
+func runJSON(t *testing.T, input *SimulationInput) []byte {
+	t.Helper()
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return data
+}
+
+// The same input and seed produce the same output, byte for byte
+func TestRunIsDeterministic(t *testing.T) {
+	first := runJSON(t, snapshotTestInput())
+	for i := 0; i < 3; i++ {
+		if again := runJSON(t, snapshotTestInput()); !bytes.Equal(first, again) {
+			t.Fatalf("run %d differs from the first", i+2)
+		}
+	}
+
+	other := snapshotTestInput()
+	seed := int64(7)
+	other.Workload.Seed = &seed
+	if bytes.Equal(first, runJSON(t, other)) {
+		t.Error("a different seed gave the same output")
+	}
+}
+
+// An unseeded run reports the seed it picked, which replays it exactly
+func TestRunEchoesItsSeed(t *testing.T) {
+	input := snapshotTestInput()
+	input.Workload.Seed = nil
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Duration != 30*time.Second {
+		t.Errorf("duration %v, want the 30 simulated seconds", output.Duration)
+	}
+	first, err := json.Marshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input.Workload.Seed = &output.Seed
+	if again := runJSON(t, input); !bytes.Equal(first, again) {
+		t.Error("replaying the echoed seed gave a different output")
+	}
+}
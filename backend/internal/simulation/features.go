@@ -1,125 +1,202 @@
 package simulation
 
-// handleCache processes cache hit/miss logic
-func (e *Engine) handleCache(node *NodeState, throughput float64) {
-	// Calculate reads (80% of requests by default)
-	readRatio := float64(e.config.ReadWriteRatio.Read) / 100.0
-	totalReads := throughput * readRatio
-
-	// Calculate cache hits and misses
-	cacheHits := int(totalReads * node.CacheHitRate)
-	cacheMisses := int(totalReads) - cacheHits
-
-	e.state.CacheHits += cacheHits
-	e.state.CacheMisses += cacheMisses
-
-	// Cache misses generate load on downstream database
-	if cacheMisses > 0 {
-		targets := e.state.EdgeMap[node.ID]
-		for _, targetID := range targets {
-			targetNode := e.state.NodeStates[targetID]
-			if targetNode != nil && isDatabase(targetNode.Type) {
-				// Add cache miss load to database
-				targetNode.CurrentLoad += float64(cacheMisses)
-			}
-		}
-	}
+import "math"
+
+// queueBatch is a group of messages enqueued on the same tick. A queue's
+// backlog is a FIFO of batches so message age survives across ticks.
+type queueBatch struct {
+	tick     int
+	messages trafficMix
 }
 
-// handleQueue processes queue buildup and drain
-func (e *Engine) handleQueue(node *NodeState, incomingLoad float64) {
-	// Add incoming overflow to queue
-	node.QueueDepth += int(incomingLoad)
+// handleQueue enqueues the messages producers published to a queue this tick.
+// Delivery to consumers and dead-lettering happen in updateQueues.
+func (e *Engine) handleQueue(node *NodeState, published trafficMix) {
+	if published.Total() <= 0 {
+		return
+	}
+	node.backlog = append(node.backlog, queueBatch{tick: e.state.Tick, messages: published})
+}
 
-	// Calculate drain rate (processing capacity of consumers)
-	drainRate := int(node.CapacityRPS * float64(node.Replicas))
+// queueDrainRate returns how many messages per second consumers can pull off
+// a queue: the configured consumerRate, or else the combined capacity of the
+// healthy downstream consumers. Either way the broker's own throughput caps it.
+// A queue with no consumers wired up acts as a sink and acks at broker speed.
+func (e *Engine) queueDrainRate(node *NodeState) float64 {
+	brokerRate := node.CapacityRPS * float64(node.Replicas)
+	if node.ConsumerRate > 0 {
+		return math.Min(node.ConsumerRate, brokerRate)
+	}
 
-	// Drain queue
-	if node.QueueDepth > 0 {
-		drained := drainRate
-		if drained > node.QueueDepth {
-			drained = node.QueueDepth
+	consumerRate := 0.0
+	hasConsumers := false
+	seen := make(map[string]bool)
+	for _, route := range e.routes[node.ID] {
+		consumer := e.state.NodeStates[route.target]
+		if route.mirror || consumer == nil || seen[route.target] {
+			continue
+		}
+		seen[route.target] = true
+		hasConsumers = true
+		if isHealthy(consumer) {
+			consumerRate += consumer.CapacityRPS * float64(consumer.Replicas)
 		}
-		node.QueueDepth -= drained
-		e.state.SuccessRequests += drained
 	}
+	if !hasConsumers {
+		return brokerRate
+	}
+	return math.Min(consumerRate, brokerRate)
+}
 
-	// Check if queue is full
-	if node.MaxQueueDepth > 0 && node.QueueDepth > node.MaxQueueDepth {
-		dropped := node.QueueDepth - node.MaxQueueDepth
-		node.QueueDepth = node.MaxQueueDepth
-		e.state.FailedRequests += dropped
-		e.state.DroppedRequests += dropped
+// queueDelivery returns the messages consumers receive this tick: oldest
+// backlog first, then this tick's arrivals, up to the drain rate. It doesn't
+// modify the backlog so it can be called while traffic is still settling.
+func (e *Engine) queueDelivery(node *NodeState, arrivals trafficMix) trafficMix {
+	if !isHealthy(node) {
+		return trafficMix{}
 	}
 
-	// Record queue depth
-	e.state.QueueHistory = append(e.state.QueueHistory, node.QueueDepth)
+	budget := e.queueDrainRate(node)
+	delivered := trafficMix{}
+	for _, batch := range node.backlog {
+		if budget <= 0 {
+			break
+		}
+		take := math.Min(budget, batch.messages.Total())
+		delivered = delivered.add(batch.messages.scale(take / batch.messages.Total()))
+		budget -= take
+	}
+	if budget > 0 && arrivals.Total() > 0 {
+		take := math.Min(budget, arrivals.Total())
+		delivered = delivered.add(arrivals.scale(take / arrivals.Total()))
+	}
+	return delivered
 }
 
-// updateQueues updates all queue nodes
+// updateQueues delivers backlog to consumers, dead-letters what no longer fits
+// and records depth and message age for every queue node
 func (e *Engine) updateQueues() {
-	for _, node := range e.state.NodeStates {
-		if node.Type == "queue" || node.Type == "message_broker" || node.Type == "event_bus" {
-			// Natural drain even without explicit incoming load
-			drainRate := int(node.CapacityRPS * float64(node.Replicas))
-			if node.QueueDepth > 0 {
-				drained := drainRate
-				if drained > node.QueueDepth {
-					drained = node.QueueDepth
+	for _, node := range e.orderedNodes() {
+		if !isQueue(node.Type) {
+			continue
+		}
+
+		// Consumers pull the oldest messages first; track how long they waited
+		budget := e.queueDelivery(node, trafficMix{}).Total()
+		delivered, waitSum := 0.0, 0.0
+		for len(node.backlog) > 0 && budget > 0 {
+			batch := &node.backlog[0]
+			take := math.Min(budget, batch.messages.Total())
+			ageMS := float64(e.state.Tick-batch.tick) * 1000
+			delivered += take
+			waitSum += take * ageMS
+			budget -= take
+
+			if take >= batch.messages.Total() {
+				node.backlog = node.backlog[1:]
+			} else {
+				batch.messages = batch.messages.scale(1 - take/batch.messages.Total())
+			}
+		}
+
+		// Messages beyond MaxQueueDepth are rejected newest-first into the DLQ
+		depth := 0.0
+		for _, batch := range node.backlog {
+			depth += batch.messages.Total()
+		}
+		if node.MaxQueueDepth > 0 && depth > float64(node.MaxQueueDepth) {
+			excess := depth - float64(node.MaxQueueDepth)
+			deadLettered := int(excess)
+			for excess > 0 && len(node.backlog) > 0 {
+				last := &node.backlog[len(node.backlog)-1]
+				if last.messages.Total() <= excess {
+					excess -= last.messages.Total()
+					node.backlog = node.backlog[:len(node.backlog)-1]
+					continue
 				}
-				node.QueueDepth -= drained
+				last.messages = last.messages.scale(1 - excess/last.messages.Total())
+				excess = 0
 			}
+			depth = float64(node.MaxQueueDepth)
+
+			node.DeadLettered += deadLettered
+			e.state.DroppedRequests += deadLettered
+			e.state.FailedRequests += deadLettered
+		}
+		node.QueueDepth = int(math.Round(depth))
+
+		// Wait time is the real age of delivered messages; when nothing was
+		// delivered it's the age of the oldest message still waiting
+		node.QueueWaitMS = 0
+		if delivered > 0 {
+			node.QueueWaitMS = waitSum / delivered
+		} else if len(node.backlog) > 0 {
+			node.QueueWaitMS = float64(e.state.Tick-node.backlog[0].tick) * 1000
 		}
+
+		e.state.QueueHistory = append(e.state.QueueHistory, node.QueueDepth)
 	}
 }
 
-// applyFailures applies failure injections
+// applyFailures applies the fault occurrences active at this tick
 func (e *Engine) applyFailures(tick int) {
 	// Reset active failures list
 	e.state.ActiveFailures = []string{}
+	e.edgeLoss = make(map[string]map[string]float64)
 
-	for _, failure := range e.config.Failures {
-		// Check if failure should be active at this tick
-		if failure.StartTick > 0 && tick < failure.StartTick {
-			continue
-		}
-		if failure.EndTick > 0 && tick > failure.EndTick {
-			continue
-		}
+	for _, window := range e.activeFaults(tick) {
+		failure := e.config.Failures[window.failure]
 
 		// Track active failure
-		e.state.ActiveFailures = append(e.state.ActiveFailures, failure.Type)
+		e.state.ActiveFailures = append(e.state.ActiveFailures, string(failure.Type))
 
 		switch failure.Type {
-		case "nodeFail":
+		case FailureNodeFail:
 			e.applyNodeFailure(failure.NodeID)
 
-		case "regionFail":
-			e.applyRegionFailure(failure.Region)
+		case FailureRegionFail, FailureAZOutage:
+			e.applyRegionFailure(failure.Region, failure.Zone)
 
-		case "cacheFail":
+		case FailureCacheFail:
 			e.applyCacheFailure(failure.NodeID)
 
-		case "dbFail":
+		case FailureDBFail:
 			e.applyDBFailure(failure.NodeID)
 
-		case "networkDelay":
-			e.applyNetworkDelay(failure.Region, failure.DelayMs)
-			
-		case "nodeLatency":
+		case FailureNetworkDelay:
+			e.applyNetworkDelay(failure.Region, failure.Zone, failure.DelayMs)
+
+		case FailureNodeLatency:
 			e.applyNodeLatency(failure.NodeID, failure.DelayMs)
 
-		case "throttle":
-			// Severity is treated as % capacity reduction
-			// Using DelayMs field to pass Severity from frontend (a bit hacky but works without struct change)
-			// Or we assume the input model has a field for severity.
-			// Checking types.go: FailureInjection has DelayMs (int).
-			// We will treat DelayMs as "Severity" percentage (0-100) for throttle.
-			severity := failure.DelayMs
-			e.applyThrottle(failure.NodeID, severity)
+		case FailureThrottle:
+			e.applyThrottle(failure.NodeID, failure.severity())
 
-		case "partition":
+		case FailurePartition:
 			e.applyPartition(failure.NodeID)
+
+		case FailurePacketLoss:
+			e.applyPacketLoss(failure)
+
+		case FailureSlowDisk:
+			e.applySlowDisk(failure.NodeID, failure.severity())
+
+		case FailureMemoryLeak:
+			e.applyMemoryLeak(window, failure, tick)
+
+		case FailureCPUSteal:
+			e.applyCPUSteal(failure.NodeID, failure.severity())
+
+		case FailureDependencyErrors:
+			if node := e.state.NodeStates[failure.NodeID]; node != nil {
+				e.applyErrorRate(node, failure.severity())
+			}
+
+		case FailureDNS:
+			e.applyDNSFailure(failure.NodeID, failure.severity())
+
+		case FailureClockSkew:
+			e.applyClockSkew(failure)
 		}
 	}
 }
@@ -131,21 +208,21 @@ func (e *Engine) applyNodeFailure(nodeID string) {
 	}
 }
 
-// applyRegionFailure fails all nodes in a region
-func (e *Engine) applyRegionFailure(region string) {
-	for _, node := range e.state.NodeStates {
-		// Check if node belongs to this region (simplified)
-		if contains(e.config.Regions, region) {
+// applyRegionFailure fails the nodes placed in a region, or only in one of
+// its availability zones when zone is set
+func (e *Engine) applyRegionFailure(region, zone string) {
+	for _, node := range e.orderedNodes() {
+		if node.placedIn(region, zone) {
 			node.Failed = true
 		}
 	}
 }
 
-// applyCacheFailure sets cache hit rate to 0
+// applyCacheFailure flushes a cache or CDN: reads miss until it warms up again
 func (e *Engine) applyCacheFailure(nodeID string) {
 	if node := e.state.NodeStates[nodeID]; node != nil {
-		if node.Type == "cache_redis" || node.Type == "cache_memcached" {
-			node.CacheHitRate = 0.0
+		if isCaching(node.Type) {
+			node.flushCache()
 		}
 	}
 }
@@ -159,13 +236,11 @@ func (e *Engine) applyDBFailure(nodeID string) {
 	}
 }
 
-// applyNetworkDelay adds latency to nodes in a region
-func (e *Engine) applyNetworkDelay(region string, delayMs int) {
-	if contains(e.config.Regions, region) {
-		for _, node := range e.state.NodeStates {
-			if node.Region == region {
-				node.LatencyMS += float64(delayMs)
-			}
+// applyNetworkDelay adds latency to nodes in a region (or one of its AZs)
+func (e *Engine) applyNetworkDelay(region, zone string, delayMs int) {
+	for _, node := range e.orderedNodes() {
+		if node.placedIn(region, zone) {
+			node.LatencyMS += float64(delayMs)
 		}
 	}
 }
@@ -177,14 +252,11 @@ func (e *Engine) applyNodeLatency(nodeID string, delayMs int) {
 	}
 }
 
-// applyThrottle reduces node capacity by percentage
-func (e *Engine) applyThrottle(nodeID string, severity int) {
+// applyThrottle reduces node capacity by a fraction
+func (e *Engine) applyThrottle(nodeID string, severity float64) {
 	if node := e.state.NodeStates[nodeID]; node != nil {
-		if severity <= 0 { severity = 50 } // Default 50%
-		if severity > 99 { severity = 99 } // Don't allow 100% (that's a crash)
-		
-		reduction := float64(severity) / 100.0
-		node.CapacityRPS = node.BaseCapacityRPS * (1.0 - reduction)
+		severity = math.Min(severity, 0.99) // Don't allow 100% (that's a crash)
+		node.CapacityRPS *= 1.0 - severity
 	}
 }
 
@@ -195,105 +267,21 @@ func (e *Engine) applyPartition(nodeID string) {
 	}
 }
 
-func (e *Engine) applyAutoScaling(tick int) []AutoscalingEvent {
-	events := []AutoscalingEvent{}
-
-	if e.config.AutoScaling == nil || !e.config.AutoScaling.Enabled {
-		return events
-	}
-
-	config := e.config.AutoScaling
-
-	for _, node := range e.state.NodeStates {
-		// Skip nodes that don't support scaling
-		if !canScale(node.Type) {
-			continue
-		}
-
-		// Check cooldown (simplified - just checks if scaled recently)
-		// We define LastScaleTime implicitly by checking autoscaling event history,
-		// but since we don't track it on node state, we'll skip cooldown for now
-		// or add LastScaleTime to NodeState later if needed.
-		// For now, assuming cooldown logic is handled by frontend intervals or simplified simulation.
-
-		// Calculate load ratio
-		effectiveCapacity := node.CapacityRPS * float64(node.Replicas)
-		loadRatio := 0.0
-		if effectiveCapacity > 0 {
-			loadRatio = node.CurrentLoad / effectiveCapacity
-		}
-
-		oldReplicas := node.Replicas
-
-		// Scale up if above threshold
-		if loadRatio > config.UpThreshold {
-			if config.MaxReplicas == 0 || node.Replicas < config.MaxReplicas {
-				node.Replicas++
-
-				events = append(events, AutoscalingEvent{
-					Tick:     tick,
-					NodeID:   node.ID,
-					OldValue: oldReplicas,
-					NewValue: node.Replicas,
-					Reason:   "High load - scaling up",
-				})
-			}
-		}
-
-		// Scale down if below threshold
-		if loadRatio < config.DownThreshold {
-			minReplicas := config.MinReplicas
-			if minReplicas == 0 {
-				minReplicas = 1
-			}
-
-			if node.Replicas > minReplicas {
-				node.Replicas--
-
-				events = append(events, AutoscalingEvent{
-					Tick:     tick,
-					NodeID:   node.ID,
-					OldValue: oldReplicas,
-					NewValue: node.Replicas,
-					Reason:   "Low load - scaling down",
-				})
-			}
-		}
-	}
-
-	return events
-}
-
 // Helper functions
 
+// isDatabase reports whether a node stores data behind the services
 func isDatabase(nodeType string) bool {
-	dbTypes := []string{
-		"database_sql",
-		"database_nosql",
-		"database_graph",
-		"database_timeseries",
-	}
-	return contains(dbTypes, nodeType)
+	return ComponentCategory(nodeType) == ComponentDatabase
+}
+
+// isQueue reports whether a node buffers messages between producers and consumers
+func isQueue(nodeType string) bool {
+	return ComponentCategory(nodeType) == ComponentQueue
 }
 
+// canScale reports whether a node's replicas can be added and removed
 func canScale(nodeType string) bool {
-	scalableTypes := []string{
-		"api_server",
-		"web_server",
-		"microservice",
-		"worker",
-		"cache_redis",
-		"cache_memcached",
-		"load_balancer",
-		"database_sql",
-		"database_nosql",
-		"database_postgres",
-		"database_mysql",
-		"database_mongodb",
-		"database_graph",
-		"database_timeseries",
-	}
-	return contains(scalableTypes, nodeType)
+	return componentModel(nodeType).Scalable()
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,87 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// queueInput publishes 1000 messages a second to a queue whose consumers pull 400
+func queueInput(maxQueueDepth int) *SimulationInput {
+	seed := int64(1)
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{"replicas": 4}}},
+			{ID: "queue", Data: SimNodeData{NodeType: "queue_sqs", Config: map[string]interface{}{"consumerRate": 400, "maxQueueDepth": maxQueueDepth}}},
+			{ID: "worker", Data: SimNodeData{NodeType: "worker", Config: map[string]interface{}{"replicas": 4}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "queue"},
+			{ID: "e3", Source: "queue", Target: "worker"},
+		},
+		Workload: WorkloadConfig{RPS: 1000, Mode: "constant", DurationSeconds: 20, Seed: &seed},
+	}
+}
+
+// Overflow waits in the queue: depth and message age carry across ticks
+// while consumers drain at their rate
+func TestQueueBuildsBacklog(t *testing.T) {
+	output, err := NewEngine(queueInput(100000)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := NodeMetrics{}
+	for i, point := range output.TimeSeries {
+		queue, worker := tickMetrics(t, point, "queue"), tickMetrics(t, point, "worker")
+		if want := 600 * (i + 1); math.Abs(float64(queue.QueueDepth-want)) > 1 {
+			t.Errorf("tick %d: depth %d, want %d", point.Tick, queue.QueueDepth, want)
+		}
+		if math.Abs(worker.RPSIn-400) > 1 {
+			t.Errorf("tick %d: worker got %.1f RPS, want the 400 consumers pull", point.Tick, worker.RPSIn)
+		}
+		if queue.QueueWaitMs < previous.QueueWaitMs {
+			t.Errorf("tick %d: wait fell from %.0fms to %.0fms, want messages aging", point.Tick, previous.QueueWaitMs, queue.QueueWaitMs)
+		}
+		previous = queue
+	}
+	// Consumers get through 40% of a tick's messages a tick
+	if want := 0.6 * float64(len(output.TimeSeries)) * 1000; math.Abs(previous.QueueWaitMs-want) > 1500 {
+		t.Errorf("wait %.0fms at the end, want about %.0fms", previous.QueueWaitMs, want)
+	}
+	if output.Metrics.FailedRequests != 0 {
+		t.Errorf("got %d failed requests, want the backlog kept", output.Metrics.FailedRequests)
+	}
+}
+
+// Past maxQueueDepth the newest messages go to the DLQ; the oldest stay
+// queued, so messages wait as long as consumers take to drain a full queue
+func TestQueueOverflowDeadLettersNewestFirst(t *testing.T) {
+	output, err := NewEngine(queueInput(3000)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := output.TimeSeries[10:]
+	for i, point := range full {
+		queue := tickMetrics(t, point, "queue")
+		if queue.QueueDepth != 3000 {
+			t.Errorf("tick %d: depth %d, want capped at 3000", point.Tick, queue.QueueDepth)
+		}
+		if i > 0 {
+			before := tickMetrics(t, full[i-1], "queue")
+			if got := queue.DeadLettered - before.DeadLettered; math.Abs(float64(got-600)) > 1 {
+				t.Errorf("tick %d: %d dead-lettered, want the 600 that don't fit", point.Tick, got)
+			}
+			if queue.QueueWaitMs < before.QueueWaitMs {
+				t.Errorf("tick %d: wait fell from %.0fms to %.0fms, want the oldest messages kept", point.Tick, before.QueueWaitMs, queue.QueueWaitMs)
+			}
+		}
+	}
+	last := tickMetrics(t, output.TimeSeries[len(output.TimeSeries)-1], "queue")
+	if math.Abs(last.QueueWaitMs-3000.0/400*1000) > 1000 {
+		t.Errorf("wait %.0fms at the end, want about the %.0fms a full queue takes to drain", last.QueueWaitMs, 3000.0/400*1000)
+	}
+	if output.Metrics.FailedRequests < last.DeadLettered {
+		t.Errorf("got %d failed requests, want the %d dead-lettered counted", output.Metrics.FailedRequests, last.DeadLettered)
+	}
+}
@@ -0,0 +1,385 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+)
+
+// geoMismatchPenalty ranks every out-of-region target behind an in-region one
+// for geo routing, while keeping the nearest region first among them
+const geoMismatchPenalty = 1e6
+
+// regionShare is one region's share of the workload's users
+type regionShare struct {
+	region   string
+	fraction float64
+}
+
+// isGlobalTrafficManager reports whether a node routes users between regions (DNS / GTM)
+func isGlobalTrafficManager(nodeType string) bool {
+	return componentModel(nodeType).Name() == "dns"
+}
+
+// isPlacedRegion reports whether a region is a real location, as opposed to
+// global services or nodes with no placement
+func isPlacedRegion(region string) bool {
+	return region != "" && region != GlobalRegion && region != DefaultRegion
+}
+
+// userDistribution returns the workload's user regions with their share of
+// traffic, normalized and in a stable order. Empty without a distribution.
+func (e *Engine) userDistribution() []regionShare {
+	regions := sortedRegions(e.config.UserRegions)
+	total := 0.0
+	for _, region := range regions {
+		total += math.Max(0, e.config.UserRegions[region])
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	shares := make([]regionShare, 0, len(regions))
+	for _, region := range regions {
+		if weight := e.config.UserRegions[region]; weight > 0 {
+			shares = append(shares, regionShare{region: region, fraction: weight / total})
+		}
+	}
+	return shares
+}
+
+// splitEntryTraffic spreads the workload over the entry nodes, as read and
+// write flows. Without a user distribution every entry node gets an even
+// share and its users are assumed to be in its own region. With one, each
+// region's users enter through the entry nodes placed in that region, or
+// through all of them when none is. Also returns each entry node's RPS by
+// user region.
+func (e *Engine) splitEntryTraffic(rps float64, entryNodes []string) (map[string]trafficMix, map[string]map[string]float64) {
+	readFraction := e.readFraction()
+	entryTraffic := make(map[string]trafficMix)
+	origins := make(map[string]map[string]float64)
+
+	addEntry := func(entryID, origin string, entryRPS float64) {
+		entryTraffic[entryID] = entryTraffic[entryID].add(trafficMix{
+			Reads:  entryRPS * readFraction,
+			Writes: entryRPS * (1.0 - readFraction),
+		})
+		if !isPlacedRegion(origin) {
+			return
+		}
+		if origins[entryID] == nil {
+			origins[entryID] = make(map[string]float64)
+		}
+		origins[entryID][origin] += entryRPS
+	}
+
+	distribution := e.userDistribution()
+	if len(distribution) == 0 {
+		rpsPerEntry := rps / float64(len(entryNodes))
+		for _, entryID := range entryNodes {
+			addEntry(entryID, e.state.NodeStates[entryID].Region, rpsPerEntry)
+		}
+		return entryTraffic, origins
+	}
+
+	for _, share := range distribution {
+		local := []string{}
+		for _, entryID := range entryNodes {
+			if e.state.NodeStates[entryID].Region == share.region {
+				local = append(local, entryID)
+			}
+		}
+		if len(local) == 0 {
+			local = entryNodes
+		}
+		rpsPerEntry := rps * share.fraction / float64(len(local))
+		for _, entryID := range local {
+			addEntry(entryID, share.region, rpsPerEntry)
+		}
+	}
+	return entryTraffic, origins
+}
+
+// isOriginAware reports whether a node routes each user region separately
+func (e *Engine) isOriginAware(nodeID string) bool {
+	policy := e.routingPolicies[nodeID]
+	return policy == RoutingGeo || policy == RoutingLatency
+}
+
+// incomingOrigins sums a node's incoming RPS by user region: what entered
+// there plus what its parents sent it. Parents not settled yet (cycle back
+// edges) don't contribute.
+func (e *Engine) incomingOrigins(nodeID string, flows map[string]map[string]trafficMix, originFlows map[string]map[string]map[string]float64, origins map[string]map[string]float64) map[string]float64 {
+	result := make(map[string]float64)
+	for region, rps := range e.entryOrigins[nodeID] {
+		result[region] += rps
+	}
+	for _, parentID := range e.topology.parents[nodeID] {
+		for region, rps := range edgeOrigins(parentID, nodeID, flows, originFlows, origins) {
+			result[region] += rps
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// edgeOrigins breaks the traffic on one edge down by user region. Origin-aware
+// parents recorded the split explicitly; any other parent forwards its users
+// in the proportions they arrived in.
+func edgeOrigins(parentID, targetID string, flows map[string]map[string]trafficMix, originFlows map[string]map[string]map[string]float64, origins map[string]map[string]float64) map[string]float64 {
+	flow := flows[parentID][targetID].Total()
+	if flow <= 0 {
+		return nil
+	}
+
+	result := make(map[string]float64)
+	if shares, ok := originFlows[parentID][targetID]; ok {
+		for region, share := range shares {
+			result[region] = flow * share
+		}
+		return result
+	}
+
+	parentOrigins := origins[parentID]
+	regions := sortedRegions(parentOrigins)
+	total := 0.0
+	for _, region := range regions {
+		total += parentOrigins[region]
+	}
+	if total <= 0 {
+		return nil
+	}
+	for _, region := range regions {
+		result[region] = flow * parentOrigins[region] / total
+	}
+	return result
+}
+
+// splitByOrigin routes each user region's share of a node's outgoing traffic
+// to the target its geo/latency policy picks for that region, skipping
+// unhealthy targets (DNS failover). Mirror edges still get their copy. Returns
+// the flows plus, per target, the share of its flow from each user region.
+func (e *Engine) splitByOrigin(nodeID string, outgoing trafficMix, origins map[string]float64) (map[string]trafficMix, map[string]map[string]float64) {
+	flows := make(map[string]trafficMix)
+	if outgoing.Total() <= 0 {
+		return flows, nil
+	}
+
+	primary := []routeEdge{}
+	for _, route := range e.routes[nodeID] {
+		if route.mirror {
+			flows[route.target] = flows[route.target].add(outgoing.scale(route.mirrorPercent / 100.0))
+			continue
+		}
+		primary = append(primary, route)
+	}
+	if len(primary) == 0 {
+		return flows, nil
+	}
+
+	regions := sortedRegions(origins)
+	total := 0.0
+	for _, region := range regions {
+		total += origins[region]
+	}
+
+	byRegion := make(map[string]map[string]float64)
+	for _, region := range regions {
+		for _, choice := range e.originTargets(nodeID, region, primary) {
+			mix := outgoing.scale(origins[region] / total * choice.fraction)
+			flows[choice.target] = flows[choice.target].add(mix)
+			if byRegion[choice.target] == nil {
+				byRegion[choice.target] = make(map[string]float64)
+			}
+			byRegion[choice.target][region] += mix.Total()
+		}
+	}
+
+	originFlows := make(map[string]map[string]float64, len(byRegion))
+	for target, rpsByRegion := range byRegion {
+		shares := make(map[string]float64, len(rpsByRegion))
+		for region, rps := range rpsByRegion {
+			if flow := flows[target].Total(); flow > 0 {
+				shares[region] = rps / flow
+			}
+		}
+		originFlows[target] = shares
+	}
+	return flows, originFlows
+}
+
+// routeChoice is a target and the share of a region's traffic it receives
+type routeChoice struct {
+	target   string
+	fraction float64
+}
+
+// originTargets picks where one user region's traffic goes: the healthy
+// targets closest to the region (in-region first for geo routing), split by
+// edge weight when several tie. With no healthy target the traffic is still
+// sent, to fail at the closest one.
+func (e *Engine) originTargets(nodeID, origin string, routes []routeEdge) []routeChoice {
+	candidates := []routeEdge{}
+	for _, route := range routes {
+		if node := e.state.NodeStates[route.target]; node != nil && isHealthy(node) {
+			candidates = append(candidates, route)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = routes
+	}
+
+	geo := e.routingPolicies[nodeID] == RoutingGeo
+	score := func(route routeEdge) float64 {
+		region := ""
+		if node := e.state.NodeStates[route.target]; node != nil {
+			region = node.Region
+		}
+		latency := GetPlacementLatency(origin, "", region, "")
+		if geo && region != origin {
+			latency += geoMismatchPenalty
+		}
+		return latency
+	}
+
+	best := math.Inf(1)
+	for _, route := range candidates {
+		best = math.Min(best, score(route))
+	}
+	chosen := []routeEdge{}
+	totalWeight := 0.0
+	for _, route := range candidates {
+		if score(route) <= best+1e-9 {
+			chosen = append(chosen, route)
+			totalWeight += route.weight
+		}
+	}
+
+	choices := make([]routeChoice, 0, len(chosen))
+	for _, route := range chosen {
+		fraction := 1.0 / float64(len(chosen))
+		if totalWeight > 0 {
+			fraction = route.weight / totalWeight
+		}
+		choices = append(choices, routeChoice{target: route.target, fraction: fraction})
+	}
+	return choices
+}
+
+// recordOriginLatency estimates the end-to-end latency users in each region
+// saw this tick: the access network from their region to the first placed
+// node, plus every hop after it weighted by where their requests went. Runs
+// after every node's latency for the tick is known.
+func (e *Engine) recordOriginLatency(traffic *tickTraffic) {
+	e.tickOriginLatency = nil
+
+	regionSet := make(map[string]float64)
+	for _, entryID := range e.state.NodeOrder {
+		for region, rps := range e.entryOrigins[entryID] {
+			regionSet[region] += rps
+		}
+	}
+
+	for _, region := range sortedRegions(regionSet) {
+		memo := make(map[string]float64)
+		visiting := make(map[string]bool)
+		sum, weight := 0.0, 0.0
+		for _, entryID := range e.state.NodeOrder {
+			rps := e.entryOrigins[entryID][region]
+			if rps <= 0 {
+				continue
+			}
+			sum += rps * e.expectedOriginLatency(region, entryID, false, traffic, memo, visiting)
+			weight += rps
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		if e.tickOriginLatency == nil {
+			e.tickOriginLatency = make(map[string]float64)
+		}
+		e.tickOriginLatency[region] = math.Round(sum/weight*100) / 100
+		e.state.originLatency[region] += sum
+		e.state.originRPS[region] += weight
+	}
+}
+
+// expectedOriginLatency is the average latency of one region's requests from
+// a node onward, excluding the network hop into the node. placed tells
+// whether the access network was already crossed.
+func (e *Engine) expectedOriginLatency(origin, nodeID string, placed bool, traffic *tickTraffic, memo map[string]float64, visiting map[string]bool) float64 {
+	key := nodeID
+	if placed {
+		key += "|placed"
+	}
+	if cached, ok := memo[key]; ok {
+		return cached
+	}
+
+	node := e.state.NodeStates[nodeID]
+	latency := e.hopLatency("", nodeID)
+	if !placed && isPlacedRegion(node.Region) {
+		latency += GetRegionLatency(origin, node.Region)
+		placed = true
+	}
+	if visiting[nodeID] {
+		return latency // Cycle back edge: don't walk around again
+	}
+	visiting[nodeID] = true
+	defer delete(visiting, nodeID)
+
+	arrived := traffic.origins[nodeID][origin]
+	forwarded := 0.0
+	seen := make(map[string]bool)
+	for _, route := range e.routes[nodeID] {
+		if route.mirror || seen[route.target] {
+			continue
+		}
+		seen[route.target] = true
+		target := e.state.NodeStates[route.target]
+		if target == nil {
+			continue // Edge to a missing node: its traffic is lost (see splitTraffic)
+		}
+		if arrived <= 0 {
+			break
+		}
+
+		sent := edgeOrigins(nodeID, route.target, traffic.flows, traffic.originFlows, traffic.origins)[origin]
+		fraction := math.Min(sent/arrived, 1-forwarded)
+		if fraction <= 0 {
+			continue
+		}
+		forwarded += fraction
+		network := GetPlacementLatency(node.Region, node.Zone, target.Region, target.Zone)
+		latency += fraction * (network + e.expectedOriginLatency(origin, route.target, placed, traffic, memo, visiting))
+	}
+
+	memo[key] = latency
+	return latency
+}
+
+// originLatency reports the run's average latency per user region
+func (e *Engine) originLatency() map[string]float64 {
+	if len(e.state.originRPS) == 0 {
+		return nil
+	}
+	result := make(map[string]float64, len(e.state.originRPS))
+	for region, rps := range e.state.originRPS {
+		if rps > 0 {
+			result[region] = math.Round(e.state.originLatency[region]/rps*100) / 100
+		}
+	}
+	return result
+}
+
+// sortedRegions returns a region-keyed map's keys in a stable order
+func sortedRegions(values map[string]float64) []string {
+	regions := make([]string, 0, len(values))
+	for region := range values {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
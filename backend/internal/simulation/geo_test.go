@@ -0,0 +1,28 @@
+package simulation
+
+import "testing"
+
+// A client placed in a region calling through an edge to a node that doesn't
+// exist: the traffic on that edge is lost, and origin latency must skip it
+func TestOriginLatencySkipsDanglingEdges(t *testing.T) {
+	seed := int64(1)
+	input := &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{"region": "us-east-1"}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "missing"},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 5, Seed: &seed},
+	}
+
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("run failed: %s", output.Error)
+	}
+}
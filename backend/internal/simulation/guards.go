@@ -0,0 +1,234 @@
+package simulation
+
+import "math"
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // Calls flow; errors are counted
+	BreakerOpen     = "open"      // Calls fail fast without reaching the target
+	BreakerHalfOpen = "half_open" // A share of calls probes whether the target recovered
+)
+
+// Circuit breaker defaults (Hystrix/resilience4j-like)
+const (
+	DefaultBreakerErrorThreshold = 50.0 // Percent of failing calls that trips the breaker
+	DefaultBreakerMinRPS         = 10.0 // Below this, error rates are too noisy to act on
+	DefaultBreakerOpenSeconds    = 5    // Time spent open before probing
+	DefaultBreakerHalfOpenProbe  = 10.0 // Percent of calls let through while half-open
+)
+
+// edgeGuard is the runtime state of the resilience policies on one source->target edge
+type edgeGuard struct {
+	key       string // Edge ID, used in the time series
+	source    string
+	target    string
+	timeoutMS float64 // Caller timeout on the edge; timeouts count as breaker errors
+
+	breaker   *CircuitBreakerConfig
+	rateLimit *RateLimitConfig
+	bulkhead  *BulkheadConfig
+
+	breakerState string
+	openedTick   int
+	tokens       float64 // Token bucket level at the start of the tick
+	inFlight     float64 // Concurrent calls last tick (Little's law)
+}
+
+// buildEdgeGuards resolves the circuit breaker, rate limiter and bulkhead
+// policies configured on edges. The first edge between a pair that
+// configures a guard owns it.
+func (e *Engine) buildEdgeGuards() (map[string]map[string]*edgeGuard, []*edgeGuard) {
+	guards := make(map[string]map[string]*edgeGuard)
+	ordered := []*edgeGuard{}
+
+	for _, edge := range e.input.Edges {
+		data := edge.Data
+		if data.CircuitBreaker == nil && data.RateLimit == nil && data.Bulkhead == nil {
+			continue
+		}
+		if guards[edge.Source][edge.Target] != nil {
+			continue
+		}
+
+		key := edge.ID
+		if key == "" {
+			key = edge.Source + "->" + edge.Target
+		}
+		guard := &edgeGuard{
+			key:          key,
+			source:       edge.Source,
+			target:       edge.Target,
+			timeoutMS:    e.resolveRetryPolicy(edge).timeoutMS,
+			breaker:      data.CircuitBreaker,
+			rateLimit:    data.RateLimit,
+			bulkhead:     data.Bulkhead,
+			breakerState: BreakerClosed,
+		}
+		if guard.rateLimit != nil {
+			guard.tokens = guard.rateLimit.burst()
+		}
+
+		if guards[edge.Source] == nil {
+			guards[edge.Source] = make(map[string]*edgeGuard)
+		}
+		guards[edge.Source][edge.Target] = guard
+		ordered = append(ordered, guard)
+	}
+
+	return guards, ordered
+}
+
+// admit returns the part of an edge's traffic the guard lets through this tick.
+// It only reads state from the start of the tick, so propagation can call it
+// repeatedly (cycles, retry baselines); updateEdgeGuards commits the tick.
+func (g *edgeGuard) admit(flow trafficMix, target *NodeState) trafficMix {
+	requested := flow.Total()
+	if requested <= 0 {
+		return flow
+	}
+	allowed := requested
+
+	if g.breaker != nil {
+		switch g.breakerState {
+		case BreakerOpen:
+			allowed = 0
+		case BreakerHalfOpen:
+			allowed = requested * g.breaker.halfOpenProbe() / 100
+		}
+	}
+
+	if g.rateLimit != nil {
+		available := math.Min(g.rateLimit.burst(), g.tokens+g.rateLimit.RPS)
+		allowed = math.Min(allowed, math.Max(0, available))
+	}
+
+	if g.bulkhead != nil && target != nil {
+		allowed = math.Min(allowed, g.bulkhead.maxRPS(target))
+	}
+
+	return flow.scale(allowed / requested)
+}
+
+// updateEdgeGuards counts traffic the guards shed as fast failures and moves
+// every guard to its next state from what the target did this tick
+func (e *Engine) updateEdgeGuards(traffic *tickTraffic) {
+	for _, guard := range e.guardOrder {
+		target := e.state.NodeStates[guard.target]
+		admitted := traffic.flows[guard.source][guard.target].Total()
+		shed := traffic.shed[guard.source][guard.target].Total()
+		e.state.FailedRequests += int(shed)
+
+		if guard.rateLimit != nil {
+			available := math.Min(guard.rateLimit.burst(), guard.tokens+guard.rateLimit.RPS)
+			guard.tokens = math.Max(0, available-admitted)
+		}
+
+		guard.inFlight = 0
+		if guard.bulkhead != nil && target != nil {
+			guard.inFlight = admitted * target.LatencyMS / 1000
+		}
+
+		if guard.breaker != nil && target != nil {
+			rejected, timedOut := attemptFailureRate(target, guard.timeoutMS)
+			guard.step(e.state.Tick, admitted, (rejected+timedOut)*100)
+		}
+	}
+}
+
+// step advances the breaker state machine by one tick
+func (g *edgeGuard) step(tick int, admitted, errorPercent float64) {
+	tripped := admitted >= g.breaker.minRPS() && errorPercent >= g.breaker.errorThreshold()
+
+	switch g.breakerState {
+	case BreakerClosed:
+		if tripped {
+			g.breakerState = BreakerOpen
+			g.openedTick = tick
+		}
+	case BreakerOpen:
+		if tick-g.openedTick >= g.breaker.openSeconds() {
+			g.breakerState = BreakerHalfOpen
+		}
+	case BreakerHalfOpen:
+		// Probes are few by design, so judge them on error rate alone
+		if admitted > 0 && errorPercent >= g.breaker.errorThreshold() {
+			g.breakerState = BreakerOpen
+			g.openedTick = tick
+		} else if admitted > 0 {
+			g.breakerState = BreakerClosed
+		}
+	}
+}
+
+// addGuardStates adds per-edge breaker, rate limiter and bulkhead state to a time series point
+func (e *Engine) addGuardStates(point *TimeSeriesPoint) {
+	for _, guard := range e.guardOrder {
+		if guard.breaker != nil {
+			if point.BreakerStates == nil {
+				point.BreakerStates = make(map[string]string)
+			}
+			point.BreakerStates[guard.key] = guard.breakerState
+		}
+		if guard.rateLimit != nil {
+			if point.RateLimiterTokens == nil {
+				point.RateLimiterTokens = make(map[string]float64)
+			}
+			point.RateLimiterTokens[guard.key] = math.Round(guard.tokens*10) / 10
+		}
+		if guard.bulkhead != nil {
+			if point.BulkheadInFlight == nil {
+				point.BulkheadInFlight = make(map[string]float64)
+			}
+			point.BulkheadInFlight[guard.key] = math.Round(guard.inFlight*10) / 10
+		}
+	}
+}
+
+func (c *CircuitBreakerConfig) errorThreshold() float64 {
+	if c.ErrorThresholdPercent <= 0 {
+		return DefaultBreakerErrorThreshold
+	}
+	return c.ErrorThresholdPercent
+}
+
+func (c *CircuitBreakerConfig) minRPS() float64 {
+	if c.MinRPS <= 0 {
+		return DefaultBreakerMinRPS
+	}
+	return c.MinRPS
+}
+
+func (c *CircuitBreakerConfig) openSeconds() int {
+	if c.OpenSeconds <= 0 {
+		return DefaultBreakerOpenSeconds
+	}
+	return c.OpenSeconds
+}
+
+func (c *CircuitBreakerConfig) halfOpenProbe() float64 {
+	if c.HalfOpenPercent <= 0 || c.HalfOpenPercent > 100 {
+		return DefaultBreakerHalfOpenProbe
+	}
+	return c.HalfOpenPercent
+}
+
+// burst is the bucket size; defaults to one second of the refill rate
+func (c *RateLimitConfig) burst() float64 {
+	if c.Burst <= 0 {
+		return math.Max(0, c.RPS)
+	}
+	return c.Burst
+}
+
+// maxRPS converts the concurrency cap into throughput at the target's current
+// latency (Little's law: concurrency = RPS * latency)
+func (c *BulkheadConfig) maxRPS(target *NodeState) float64 {
+	latencyMS := target.LatencyMS
+	if latencyMS <= 0 {
+		latencyMS = target.BaseLatencyMS
+	}
+	if c.MaxConcurrent <= 0 || latencyMS <= 0 {
+		return math.Inf(1)
+	}
+	return float64(c.MaxConcurrent) / (latencyMS / 1000)
+}
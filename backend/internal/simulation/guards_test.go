@@ -0,0 +1,76 @@
+package simulation
+
+import "testing"
+
+// breakerInput calls a database that is down for the first ticks through an
+// edge with a circuit breaker and a retry policy
+func breakerInput(downTicks int) *SimulationInput {
+	seed := int64(1)
+	retries := 3
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+			{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "db", Data: SimEdgeData{
+				Retries:        &retries,
+				CircuitBreaker: &CircuitBreakerConfig{OpenSeconds: 3},
+			}},
+		},
+		Workload: WorkloadConfig{
+			RPS: 100, Mode: "constant", DurationSeconds: 15, Seed: &seed,
+			Failures: []FailureInjection{{Type: FailureNodeFail, NodeID: "db", EndTick: downTicks}},
+		},
+	}
+}
+
+func TestBreakerOpensHalfOpensAndCloses(t *testing.T) {
+	output, err := NewEngine(breakerInput(2)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := []string{}
+	for _, point := range output.TimeSeries {
+		state := point.BreakerStates["e2"]
+		if len(states) == 0 || states[len(states)-1] != state {
+			states = append(states, state)
+		}
+	}
+	want := []string{BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(states) != len(want) {
+		t.Fatalf("breaker went %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("breaker went %v, want %v", states, want)
+		}
+	}
+}
+
+// A retry is a call like any other: an open breaker fails it fast
+func TestOpenBreakerShedsRetries(t *testing.T) {
+	output, err := NewEngine(breakerInput(15)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := 0
+	for i, point := range output.TimeSeries[1:] {
+		if output.TimeSeries[i].BreakerStates["e2"] != BreakerOpen {
+			continue // Only ticks that started with the breaker open
+		}
+		opened++
+		db := tickMetrics(t, point, "db")
+		if db.RPSIn != 0 || db.RetryRPS != 0 {
+			t.Errorf("tick %d: db got %.1f RPS (%.1f retried) through an open breaker", point.Tick, db.RPSIn, db.RetryRPS)
+		}
+	}
+	if opened == 0 {
+		t.Fatal("breaker never opened")
+	}
+	if output.Metrics.RetriedRequests == 0 {
+		t.Error("want the failed calls retried")
+	}
+}
@@ -2,109 +2,140 @@ package simulation
 
 // StoragePerformance defines performance characteristics of different storage types
 type StoragePerformance struct {
-	IOPS           int     // Input/Output Operations Per Second
-	ThroughputMBps int     // Throughput in MB/s
-	LatencyMs      float64 // Average latency in milliseconds
+	IOPS              int     // Input/Output Operations Per Second
+	ThroughputMBps    int     // Throughput in MB/s
+	LatencyMs         float64 // Average latency in milliseconds
+	CostPerGBMonthUSD float64 // Price of a provisioned GB per month
 }
 
-// GetStoragePerformance returns performance specs for different storage types
-func GetStoragePerformance(storageType string) StoragePerformance {
-	storageSpecs := map[string]StoragePerformance{
-		// AWS EBS Storage Types
-		"gp3": {
-			IOPS:           3000, // Base 3000 IOPS
-			ThroughputMBps: 125,  // 125 MB/s
-			LatencyMs:      1.0,  // ~1ms latency
-		},
-		"gp2": {
-			IOPS:           3000, // 3 IOPS per GB (baseline)
-			ThroughputMBps: 128,  // 128 MB/s
-			LatencyMs:      1.2,  // Slightly higher latency
-		},
-		"io2": {
-			IOPS:           64000, // Up to 64,000 IOPS
-			ThroughputMBps: 1000,  // 1000 MB/s
-			LatencyMs:      0.5,   // Sub-millisecond latency
-		},
-		"io1": {
-			IOPS:           50000, // Up to 50,000 IOPS
-			ThroughputMBps: 1000,  // 1000 MB/s
-			LatencyMs:      0.6,   // Sub-millisecond latency
-		},
-		"st1": {
-			IOPS:           500, // Throughput optimized, not IOPS
-			ThroughputMBps: 500, // 500 MB/s throughput
-			LatencyMs:      5.0, // HDD latency
-		},
-		"sc1": {
-			IOPS:           250,  // Cold HDD
-			ThroughputMBps: 250,  // 250 MB/s
-			LatencyMs:      10.0, // Higher HDD latency
-		},
-	}
+// offlineStorage is the storage types known without the catalog
+var offlineStorage = map[string]StoragePerformance{
+	// AWS EBS Storage Types
+	"gp3": {
+		IOPS:              3000, // Base 3000 IOPS
+		ThroughputMBps:    125,  // 125 MB/s
+		LatencyMs:         1.0,  // ~1ms latency
+		CostPerGBMonthUSD: 0.08,
+	},
+	"gp2": {
+		IOPS:              3000, // 3 IOPS per GB (baseline)
+		ThroughputMBps:    128,  // 128 MB/s
+		LatencyMs:         1.2,  // Slightly higher latency
+		CostPerGBMonthUSD: 0.10,
+	},
+	"io2": {
+		IOPS:              64000, // Up to 64,000 IOPS
+		ThroughputMBps:    1000,  // 1000 MB/s
+		LatencyMs:         0.5,   // Sub-millisecond latency
+		CostPerGBMonthUSD: 0.125,
+	},
+	"io1": {
+		IOPS:              50000, // Up to 50,000 IOPS
+		ThroughputMBps:    1000,  // 1000 MB/s
+		LatencyMs:         0.6,   // Sub-millisecond latency
+		CostPerGBMonthUSD: 0.125,
+	},
+	"st1": {
+		IOPS:              500, // Throughput optimized, not IOPS
+		ThroughputMBps:    500, // 500 MB/s throughput
+		LatencyMs:         5.0, // HDD latency
+		CostPerGBMonthUSD: 0.045,
+	},
+	"sc1": {
+		IOPS:              250,  // Cold HDD
+		ThroughputMBps:    250,  // 250 MB/s
+		LatencyMs:         10.0, // Higher HDD latency
+		CostPerGBMonthUSD: 0.015,
+	},
+}
 
-	if perf, ok := storageSpecs[storageType]; ok {
+// GetStoragePerformance returns performance specs for different storage
+// types from a hardware provider (nil for the built-in tables)
+func GetStoragePerformance(hw HardwareProvider, storageType string) StoragePerformance {
+	if perf, ok := orOffline(hw).Storage(storageType); ok {
 		return perf
 	}
 
 	// Default to gp3 if not specified
-	return storageSpecs["gp3"]
+	return offlineStorage["gp3"]
 }
 
 // InstancePerformance defines performance characteristics of instance types
 type InstancePerformance struct {
-	VCPU        int     // Number of vCPUs
-	MemoryGB    float64 // Memory in GB
-	NetworkGbps float64 // Network bandwidth in Gbps
-	CPUCredits  bool    // Whether it's burstable (T3 instances)
+	VCPU          int     // Number of vCPUs
+	MemoryGB      float64 // Memory in GB
+	NetworkGbps   float64 // Network bandwidth in Gbps
+	CPUCredits    bool    // Whether it's burstable (T3 instances)
+	HourlyCostUSD float64 // On-demand price per instance-hour
 }
 
-// GetInstancePerformance returns performance specs for different instance types
-func GetInstancePerformance(instanceType string) InstancePerformance {
-	instanceSpecs := map[string]InstancePerformance{
-		// T3 Instances (Burstable)
-		"t3.micro":  {VCPU: 2, MemoryGB: 1, NetworkGbps: 5.0, CPUCredits: true},
-		"t3.small":  {VCPU: 2, MemoryGB: 2, NetworkGbps: 5.0, CPUCredits: true},
-		"t3.medium": {VCPU: 2, MemoryGB: 4, NetworkGbps: 5.0, CPUCredits: true},
-		"t3.large":  {VCPU: 2, MemoryGB: 8, NetworkGbps: 5.0, CPUCredits: true},
-		"t3.xlarge": {VCPU: 4, MemoryGB: 16, NetworkGbps: 5.0, CPUCredits: true},
-
-		// M5 Instances (General Purpose)
-		"m5.large":   {VCPU: 2, MemoryGB: 8, NetworkGbps: 10.0, CPUCredits: false},
-		"m5.xlarge":  {VCPU: 4, MemoryGB: 16, NetworkGbps: 10.0, CPUCredits: false},
-		"m5.2xlarge": {VCPU: 8, MemoryGB: 32, NetworkGbps: 10.0, CPUCredits: false},
-		"m5.4xlarge": {VCPU: 16, MemoryGB: 64, NetworkGbps: 10.0, CPUCredits: false},
-
-		// C5 Instances (Compute Optimized)
-		"c5.large":   {VCPU: 2, MemoryGB: 4, NetworkGbps: 10.0, CPUCredits: false},
-		"c5.xlarge":  {VCPU: 4, MemoryGB: 8, NetworkGbps: 10.0, CPUCredits: false},
-		"c5.2xlarge": {VCPU: 8, MemoryGB: 16, NetworkGbps: 10.0, CPUCredits: false},
-		"c5.4xlarge": {VCPU: 16, MemoryGB: 32, NetworkGbps: 10.0, CPUCredits: false},
-
-		// R5 Instances (Memory Optimized)
-		"r5.large":   {VCPU: 2, MemoryGB: 16, NetworkGbps: 10.0, CPUCredits: false},
-		"r5.xlarge":  {VCPU: 4, MemoryGB: 32, NetworkGbps: 10.0, CPUCredits: false},
-		"r5.2xlarge": {VCPU: 8, MemoryGB: 64, NetworkGbps: 10.0, CPUCredits: false},
-
-		// Database Instances
-		"db.t3.micro":  {VCPU: 2, MemoryGB: 1, NetworkGbps: 5.0, CPUCredits: true},
-		"db.t3.small":  {VCPU: 2, MemoryGB: 2, NetworkGbps: 5.0, CPUCredits: true},
-		"db.t3.medium": {VCPU: 2, MemoryGB: 4, NetworkGbps: 5.0, CPUCredits: true},
-		"db.t3.large":  {VCPU: 2, MemoryGB: 8, NetworkGbps: 5.0, CPUCredits: true},
-		"db.m5.large":  {VCPU: 2, MemoryGB: 8, NetworkGbps: 10.0, CPUCredits: false},
-		"db.m5.xlarge": {VCPU: 4, MemoryGB: 16, NetworkGbps: 10.0, CPUCredits: false},
-		"db.r5.large":  {VCPU: 2, MemoryGB: 16, NetworkGbps: 10.0, CPUCredits: false},
-		"db.r5.xlarge": {VCPU: 4, MemoryGB: 32, NetworkGbps: 10.0, CPUCredits: false},
-
-		// Cache Instances
-		"cache.t3.micro":  {VCPU: 2, MemoryGB: 0.5, NetworkGbps: 5.0, CPUCredits: true},
-		"cache.t3.small":  {VCPU: 2, MemoryGB: 1.37, NetworkGbps: 5.0, CPUCredits: true},
-		"cache.t3.medium": {VCPU: 2, MemoryGB: 3.09, NetworkGbps: 5.0, CPUCredits: true},
-		"cache.m5.large":  {VCPU: 2, MemoryGB: 6.38, NetworkGbps: 10.0, CPUCredits: false},
-		"cache.r5.large":  {VCPU: 2, MemoryGB: 13.07, NetworkGbps: 10.0, CPUCredits: false},
-	}
+// offlineInstances is the instance types known without the catalog
+var offlineInstances = map[string]InstancePerformance{
+	// T3 Instances (Burstable)
+	"t3.micro":   {VCPU: 2, MemoryGB: 1, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.0104},
+	"t3.small":   {VCPU: 2, MemoryGB: 2, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.0208},
+	"t3.medium":  {VCPU: 2, MemoryGB: 4, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.0416},
+	"t3.large":   {VCPU: 2, MemoryGB: 8, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.0832},
+	"t3.xlarge":  {VCPU: 4, MemoryGB: 16, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.1664},
+	"t3.2xlarge": {VCPU: 8, MemoryGB: 32, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.3328},
+
+	// M5 Instances (General Purpose)
+	"m5.large":   {VCPU: 2, MemoryGB: 8, NetworkGbps: 10.0, HourlyCostUSD: 0.096},
+	"m5.xlarge":  {VCPU: 4, MemoryGB: 16, NetworkGbps: 10.0, HourlyCostUSD: 0.192},
+	"m5.2xlarge": {VCPU: 8, MemoryGB: 32, NetworkGbps: 10.0, HourlyCostUSD: 0.384},
+	"m5.4xlarge": {VCPU: 16, MemoryGB: 64, NetworkGbps: 10.0, HourlyCostUSD: 0.768},
+	"m5.8xlarge": {VCPU: 32, MemoryGB: 128, NetworkGbps: 10.0, HourlyCostUSD: 1.536},
+
+	// C5 Instances (Compute Optimized)
+	"c5.large":   {VCPU: 2, MemoryGB: 4, NetworkGbps: 10.0, HourlyCostUSD: 0.085},
+	"c5.xlarge":  {VCPU: 4, MemoryGB: 8, NetworkGbps: 10.0, HourlyCostUSD: 0.17},
+	"c5.2xlarge": {VCPU: 8, MemoryGB: 16, NetworkGbps: 10.0, HourlyCostUSD: 0.34},
+	"c5.4xlarge": {VCPU: 16, MemoryGB: 32, NetworkGbps: 10.0, HourlyCostUSD: 0.68},
+	"c5.9xlarge": {VCPU: 36, MemoryGB: 72, NetworkGbps: 10.0, HourlyCostUSD: 1.53},
+
+	// R5 Instances (Memory Optimized)
+	"r5.large":   {VCPU: 2, MemoryGB: 16, NetworkGbps: 10.0, HourlyCostUSD: 0.126},
+	"r5.xlarge":  {VCPU: 4, MemoryGB: 32, NetworkGbps: 10.0, HourlyCostUSD: 0.252},
+	"r5.2xlarge": {VCPU: 8, MemoryGB: 64, NetworkGbps: 10.0, HourlyCostUSD: 0.504},
+	"r5.4xlarge": {VCPU: 16, MemoryGB: 128, NetworkGbps: 10.0, HourlyCostUSD: 1.008},
+
+	// Database Instances (RDS)
+	"db.t3.micro":   {VCPU: 2, MemoryGB: 1, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.017},
+	"db.t3.small":   {VCPU: 2, MemoryGB: 2, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.034},
+	"db.t3.medium":  {VCPU: 2, MemoryGB: 4, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.068},
+	"db.t3.large":   {VCPU: 2, MemoryGB: 8, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.136},
+	"db.t3.xlarge":  {VCPU: 4, MemoryGB: 16, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.272},
+	"db.t3.2xlarge": {VCPU: 8, MemoryGB: 32, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.544},
+	"db.m5.large":   {VCPU: 2, MemoryGB: 8, NetworkGbps: 10.0, HourlyCostUSD: 0.188},
+	"db.m5.xlarge":  {VCPU: 4, MemoryGB: 16, NetworkGbps: 10.0, HourlyCostUSD: 0.376},
+	"db.m5.2xlarge": {VCPU: 8, MemoryGB: 32, NetworkGbps: 10.0, HourlyCostUSD: 0.752},
+	"db.m5.4xlarge": {VCPU: 16, MemoryGB: 64, NetworkGbps: 10.0, HourlyCostUSD: 1.504},
+	"db.m5.8xlarge": {VCPU: 32, MemoryGB: 128, NetworkGbps: 10.0, HourlyCostUSD: 3.008},
+	"db.r5.large":   {VCPU: 2, MemoryGB: 16, NetworkGbps: 10.0, HourlyCostUSD: 0.29},
+	"db.r5.xlarge":  {VCPU: 4, MemoryGB: 32, NetworkGbps: 10.0, HourlyCostUSD: 0.58},
+	"db.r5.2xlarge": {VCPU: 8, MemoryGB: 64, NetworkGbps: 10.0, HourlyCostUSD: 1.16},
+	"db.r5.4xlarge": {VCPU: 16, MemoryGB: 128, NetworkGbps: 10.0, HourlyCostUSD: 2.32},
+	"db.r5.8xlarge": {VCPU: 32, MemoryGB: 256, NetworkGbps: 10.0, HourlyCostUSD: 4.64},
+
+	// Cache Instances (ElastiCache)
+	"cache.t3.micro":   {VCPU: 2, MemoryGB: 0.5, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.017},
+	"cache.t3.small":   {VCPU: 2, MemoryGB: 1.37, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.034},
+	"cache.t3.medium":  {VCPU: 2, MemoryGB: 3.09, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.068},
+	"cache.t3.large":   {VCPU: 2, MemoryGB: 6.38, NetworkGbps: 5.0, CPUCredits: true, HourlyCostUSD: 0.136},
+	"cache.m5.large":   {VCPU: 2, MemoryGB: 6.38, NetworkGbps: 10.0, HourlyCostUSD: 0.136},
+	"cache.m5.xlarge":  {VCPU: 4, MemoryGB: 12.93, NetworkGbps: 10.0, HourlyCostUSD: 0.272},
+	"cache.m5.2xlarge": {VCPU: 8, MemoryGB: 26.04, NetworkGbps: 10.0, HourlyCostUSD: 0.544},
+	"cache.m5.4xlarge": {VCPU: 16, MemoryGB: 52.26, NetworkGbps: 10.0, HourlyCostUSD: 1.088},
+	"cache.r5.large":   {VCPU: 2, MemoryGB: 13.07, NetworkGbps: 10.0, HourlyCostUSD: 0.252},
+	"cache.r5.xlarge":  {VCPU: 4, MemoryGB: 26.32, NetworkGbps: 10.0, HourlyCostUSD: 0.504},
+	"cache.r5.2xlarge": {VCPU: 8, MemoryGB: 52.82, NetworkGbps: 10.0, HourlyCostUSD: 1.008},
+	"cache.r5.4xlarge": {VCPU: 16, MemoryGB: 105.81, NetworkGbps: 10.0, HourlyCostUSD: 2.016},
+}
 
-	if perf, ok := instanceSpecs[instanceType]; ok {
+// GetInstancePerformance returns performance specs for different instance
+// types from a hardware provider (nil for the built-in tables)
+func GetInstancePerformance(hw HardwareProvider, instanceType string) InstancePerformance {
+	if perf, ok := orOffline(hw).Instance(instanceType); ok {
 		return perf
 	}
 
@@ -112,9 +143,36 @@ func GetInstancePerformance(instanceType string) InstancePerformance {
 	return InstancePerformance{VCPU: 2, MemoryGB: 4, NetworkGbps: 5.0, CPUCredits: true}
 }
 
+// LoadBalancerPerformance defines the capacity and price of a load balancer type
+type LoadBalancerPerformance struct {
+	CapacityRPS   float64
+	LatencyMs     float64
+	HourlyCostUSD float64
+}
+
+// QueuePerformance defines the throughput and price of a queue type
+type QueuePerformance struct {
+	ThroughputRPS     float64 // Messages per second
+	LatencyMs         float64
+	CostPerMillionUSD float64 // Price of a million requests
+}
+
+// CDNPerformance defines the edge latency and price of a CDN type
+type CDNPerformance struct {
+	LatencyMs    float64
+	CostPerGBUSD float64 // Price of a GB delivered
+}
+
+// SearchPerformance defines the capacity and price of a search cluster type
+type SearchPerformance struct {
+	QueriesPerSec float64
+	LatencyMs     float64
+	HourlyCostUSD float64
+}
+
 // CalculateStorageImpact adjusts disk I/O percentage based on storage type
-func CalculateStorageImpact(baseDiskIO float64, storageType string, rps float64) float64 {
-	perf := GetStoragePerformance(storageType)
+func CalculateStorageImpact(hw HardwareProvider, baseDiskIO float64, storageType string, rps float64) float64 {
+	perf := GetStoragePerformance(hw, storageType)
 
 	// Estimate IOPS needed (rough approximation: 1 RPS = 10 IOPS for databases)
 	estimatedIOPS := rps * 10
@@ -132,8 +190,8 @@ func CalculateStorageImpact(baseDiskIO float64, storageType string, rps float64)
 }
 
 // CalculateInstanceImpact adjusts capacity based on instance type
-func CalculateInstanceImpact(baseCapacity float64, instanceType string) float64 {
-	perf := GetInstancePerformance(instanceType)
+func CalculateInstanceImpact(hw HardwareProvider, baseCapacity float64, instanceType string) float64 {
+	perf := GetInstancePerformance(hw, instanceType)
 
 	// Scale capacity based on vCPU count
 	// t3.micro (2 vCPU) = 1x
@@ -149,8 +207,8 @@ func CalculateInstanceImpact(baseCapacity float64, instanceType string) float64
 }
 
 // GetStorageLatencyImpact returns additional latency based on storage type
-func GetStorageLatencyImpact(storageType string) float64 {
-	perf := GetStoragePerformance(storageType)
+func GetStorageLatencyImpact(hw HardwareProvider, storageType string) float64 {
+	perf := GetStoragePerformance(hw, storageType)
 	return perf.LatencyMs
 }
 
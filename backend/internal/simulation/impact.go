@@ -0,0 +1,257 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DependencyImpact is what failing one node for a whole run does to the system
+type DependencyImpact struct {
+	Rank                 int      `json:"rank"`
+	NodeID               string   `json:"nodeId"`
+	NodeType             string   `json:"nodeType"`
+	Replicas             int      `json:"replicas"`
+	AffectedNodes        []string `json:"affectedNodes"`         // Nodes that erred, slowed down or lost traffic in the simulation
+	DownstreamNodes      []string `json:"downstreamNodes"`       // Nodes the failed node calls, directly or not
+	UpstreamNodes        []string `json:"upstreamNodes"`         // Nodes that call the failed node, directly or not
+	CutOffNodes          []string `json:"cutOffNodes,omitempty"` // Nodes no entry point can reach without the failed node
+	ErrorRateIncrease    float64  `json:"errorRateIncrease"`     // Percentage points over the healthy run
+	P50LatencyIncrease   float64  `json:"p50LatencyIncreaseMs"`  // Milliseconds
+	P99LatencyIncrease   float64  `json:"p99LatencyIncreaseMs"`  // Milliseconds
+	SLAViolations        []string `json:"slaViolations"`         // Violations the healthy run doesn't have
+	SinglePointOfFailure bool     `json:"singlePointOfFailure"`  // Requests can't route around it
+}
+
+// BlastRadiusAnalysis ranks nodes by the damage their failure does
+type BlastRadiusAnalysis struct {
+	BaselineErrorRate     float64            `json:"baselineErrorRate"` // Percentage, healthy run
+	BaselineP99Latency    float64            `json:"baselineP99LatencyMs"`
+	Impacts               []DependencyImpact `json:"impacts"` // Worst first
+	SinglePointsOfFailure []string           `json:"singlePointsOfFailure"`
+	Seed                  int64              `json:"seed"`
+}
+
+// nodeRunStats summarizes one node over a run
+type nodeRunStats struct {
+	rps     float64 // Average incoming RPS
+	latency float64 // Average latency
+	errors  int     // Errors at the end of the run
+}
+
+// AnalyzeBlastRadius fails each of nodeIDs (every non-entry node when empty)
+// for a whole run, one at a time, and compares the runs with a healthy one.
+// Every run uses the same seed, and the workload's own failures are left out
+// so only the failed node differs. Runs share the batch slots with Monte
+// Carlo and optimization.
+func AnalyzeBlastRadius(input *SimulationInput, nodeIDs []string) (*BlastRadiusAnalysis, error) {
+	base := *input
+	base.Workload.Failures = nil
+	engine := NewEngine(&base)
+	seed := engine.Seed()
+	base.Workload.Seed = &seed
+
+	var healthy *SimulationOutput
+	if err := runBatchCall(0, func(int) (err error) {
+		healthy, err = engine.Run()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	healthyStats := runStats(healthy)
+	entries := make(map[string]bool)
+	for _, entryID := range engine.findEntryNodes() {
+		entries[entryID] = true
+	}
+
+	if len(nodeIDs) == 0 {
+		for _, nodeID := range engine.state.NodeOrder {
+			if !entries[nodeID] {
+				nodeIDs = append(nodeIDs, nodeID)
+			}
+		}
+	}
+
+	analysis := &BlastRadiusAnalysis{
+		BaselineErrorRate:     math.Round(healthy.Metrics.ErrorRate*10000) / 100,
+		BaselineP99Latency:    healthy.Metrics.Latency.P99,
+		Impacts:               []DependencyImpact{},
+		SinglePointsOfFailure: []string{},
+		Seed:                  seed,
+	}
+	reachable := engine.reachableFrom(entries, "")
+
+	for _, nodeID := range nodeIDs {
+		if engine.state.NodeStates[nodeID] == nil {
+			return nil, fmt.Errorf("unknown node %q", nodeID)
+		}
+	}
+	outputs := make([]*SimulationOutput, len(nodeIDs))
+	errs := runBatch(len(nodeIDs), 0, func(i int) (err error) {
+		failing := base
+		failing.Workload.Failures = []FailureInjection{{Type: FailureNodeFail, NodeID: nodeIDs[i]}}
+		outputs[i], err = NewEngine(&failing).Run()
+		return err
+	})
+
+	for i, nodeID := range nodeIDs {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failing %s: %w", nodeID, errs[i])
+		}
+		node, output := engine.state.NodeStates[nodeID], outputs[i]
+
+		impact := DependencyImpact{
+			NodeID:             nodeID,
+			NodeType:           node.Type,
+			Replicas:           node.Replicas,
+			AffectedNodes:      affectedNodes(engine.state.NodeOrder, nodeID, len(output.TimeSeries), healthyStats, runStats(output)),
+			DownstreamNodes:    engine.dependencyClosure(nodeID, engine.state.EdgeMap),
+			UpstreamNodes:      engine.dependencyClosure(nodeID, engine.state.ReverseEdgeMap),
+			CutOffNodes:        []string{},
+			ErrorRateIncrease:  math.Round((output.Metrics.ErrorRate-healthy.Metrics.ErrorRate)*10000) / 100,
+			P50LatencyIncrease: math.Round((output.Metrics.Latency.P50-healthy.Metrics.Latency.P50)*100) / 100,
+			P99LatencyIncrease: math.Round((output.Metrics.Latency.P99-healthy.Metrics.Latency.P99)*100) / 100,
+			SLAViolations:      newViolations(healthy.SLAViolations, output.SLAViolations),
+		}
+
+		without := engine.reachableFrom(entries, nodeID)
+		for _, id := range engine.state.NodeOrder {
+			if id != nodeID && reachable[id] && !without[id] {
+				impact.CutOffNodes = append(impact.CutOffNodes, id)
+			}
+		}
+		// A node is a single point of failure when losing it cuts other nodes
+		// off, or when it's a terminal node whose requests fail with it
+		terminal := len(engine.state.EdgeMap[nodeID]) == 0 && impact.ErrorRateIncrease >= 1
+		impact.SinglePointOfFailure = !entries[nodeID] && (len(impact.CutOffNodes) > 0 || terminal)
+		if impact.SinglePointOfFailure {
+			analysis.SinglePointsOfFailure = append(analysis.SinglePointsOfFailure, nodeID)
+		}
+		analysis.Impacts = append(analysis.Impacts, impact)
+	}
+
+	sort.SliceStable(analysis.Impacts, func(i, j int) bool {
+		a, b := analysis.Impacts[i], analysis.Impacts[j]
+		if a.ErrorRateIncrease != b.ErrorRateIncrease {
+			return a.ErrorRateIncrease > b.ErrorRateIncrease
+		}
+		if len(a.AffectedNodes) != len(b.AffectedNodes) {
+			return len(a.AffectedNodes) > len(b.AffectedNodes)
+		}
+		return a.P99LatencyIncrease > b.P99LatencyIncrease
+	})
+	for i := range analysis.Impacts {
+		analysis.Impacts[i].Rank = i + 1
+	}
+	return analysis, nil
+}
+
+// runStats averages every node's traffic and latency over a run
+func runStats(output *SimulationOutput) map[string]nodeRunStats {
+	stats := make(map[string]nodeRunStats)
+	ticks := float64(len(output.TimeSeries))
+	for _, point := range output.TimeSeries {
+		for _, node := range point.NodeMetrics {
+			s := stats[node.NodeID]
+			s.rps += node.RPSIn / ticks
+			s.latency += node.LatencyMs / ticks
+			s.errors = node.Errors
+			stats[node.NodeID] = s
+		}
+	}
+	return stats
+}
+
+// affectedNodes lists the nodes, other than the failed one, that erred (1%
+// of their traffic or more), slowed down (20% and at least 1ms) or lost a
+// tenth of their traffic compared with the healthy run
+func affectedNodes(order []string, failedID string, ticks int, healthy, failing map[string]nodeRunStats) []string {
+	affected := []string{}
+	for _, nodeID := range order {
+		if nodeID == failedID {
+			continue
+		}
+		before, after := healthy[nodeID], failing[nodeID]
+		// Traffic seen over the run, against which extra errors are weighed
+		seen := math.Max(1, math.Max(before.rps, after.rps)*float64(ticks))
+		erroring := float64(after.errors-before.errors) >= 0.01*seen
+		slower := after.latency > before.latency*1.2 && after.latency-before.latency >= 1
+		starved := after.rps < before.rps*0.9
+		if erroring || slower || starved {
+			affected = append(affected, nodeID)
+		}
+	}
+	return affected
+}
+
+// dependencyClosure walks edges transitively from a node (EdgeMap for what
+// it depends on, ReverseEdgeMap for what depends on it)
+func (e *Engine) dependencyClosure(nodeID string, edges map[string][]string) []string {
+	seen := map[string]bool{nodeID: true}
+	stack := []string{nodeID}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range edges[current] {
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	closure := []string{}
+	for _, id := range e.state.NodeOrder {
+		if seen[id] && id != nodeID {
+			closure = append(closure, id)
+		}
+	}
+	return closure
+}
+
+// reachableFrom returns the nodes the entry points reach over EdgeMap
+// without passing through removed ("" removes nothing)
+func (e *Engine) reachableFrom(entries map[string]bool, removed string) map[string]bool {
+	reached := make(map[string]bool)
+	stack := []string{}
+	for _, id := range e.state.NodeOrder {
+		if entries[id] && id != removed {
+			reached[id] = true
+			stack = append(stack, id)
+		}
+	}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range e.state.EdgeMap[current] {
+			if next != removed && !reached[next] {
+				reached[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return reached
+}
+
+// newViolations returns the SLA violations of a run the healthy run didn't
+// have. Violations are matched by the target they break, not the measured value.
+func newViolations(healthy, failing []string) []string {
+	target := func(violation string) string {
+		if i := strings.Index(violation, " ("); i >= 0 {
+			return violation[:i]
+		}
+		return violation
+	}
+	known := make(map[string]bool, len(healthy))
+	for _, violation := range healthy {
+		known[target(violation)] = true
+	}
+	added := []string{}
+	for _, violation := range failing {
+		if !known[target(violation)] {
+			added = append(added, violation)
+		}
+	}
+	return added
+}
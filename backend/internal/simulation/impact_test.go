@@ -0,0 +1,42 @@
+package simulation
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Every what-if run goes through the batch slots and gives them back; the
+// chain's nodes are all single points of failure
+func TestBlastRadiusRunsInBatchSlots(t *testing.T) {
+	seed := int64(3)
+	input := &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+			{ID: "db", Data: SimNodeData{NodeType: "database_sql", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "db"},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 10, Seed: &seed},
+	}
+
+	analysis, err := AnalyzeBlastRadius(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batchSlots) != 0 {
+		t.Fatalf("%d batch slots still held", len(batchSlots))
+	}
+	if len(analysis.Impacts) != 2 {
+		t.Fatalf("got %d impacts, want api and db", len(analysis.Impacts))
+	}
+	if want := []string{"api", "db"}; !reflect.DeepEqual(analysis.SinglePointsOfFailure, want) {
+		t.Errorf("single points of failure %v, want %v", analysis.SinglePointsOfFailure, want)
+	}
+
+	if _, err := AnalyzeBlastRadius(input, []string{"nope"}); err == nil {
+		t.Error("unknown node: want an error")
+	}
+}
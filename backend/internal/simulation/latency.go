@@ -0,0 +1,349 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxLatencyBuckets bounds the latency distribution kept per node while
+// propagating a tick, so wide fan-outs can't grow it without bound
+const maxLatencyBuckets = 256
+
+// maxCriticalPaths is how many of the slowest paths are reported
+const maxCriticalPaths = 5
+
+// PathHop is one node's contribution to an end-to-end path latency
+type PathHop struct {
+	NodeID    string  `json:"nodeId"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// CriticalPath is a client->leaf path with its traffic-weighted latency
+type CriticalPath struct {
+	Nodes          []string  `json:"nodes"`
+	LatencyMs      float64   `json:"latencyMs"`      // Traffic-weighted average over the run
+	MaxLatencyMs   float64   `json:"maxLatencyMs"`   // Worst tick
+	TrafficPercent float64   `json:"trafficPercent"` // Share of all requests that completed on this path
+	Hops           []PathHop `json:"hops"`
+}
+
+// latencySample is an end-to-end latency carried by a given amount of traffic
+type latencySample struct {
+	latency float64
+	weight  float64
+}
+
+// pathStats accumulates a single path's latency across ticks
+type pathStats struct {
+	nodes      []string
+	weight     float64
+	latencySum float64 // weight * latency
+	maxLatency float64
+	hopSums    []float64 // weight * hop latency
+}
+
+// latencyDist is traffic (RPS) by end-to-end latency so far, bucketed like
+// the run-wide histogram
+type latencyDist map[float64]float64
+
+// slowPath is one of the slowest client->node paths reaching a node this tick
+type slowPath struct {
+	nodes   []string
+	hops    []float64
+	latency float64
+	rps     float64
+}
+
+// tickTraffic is the result of propagating one tick's traffic
+type tickTraffic struct {
+	incoming    map[string]trafficMix
+	outgoing    map[string]trafficMix
+	flows       map[string]map[string]trafficMix         // parent -> target -> traffic on that edge
+	shed        map[string]map[string]trafficMix         // parent -> target -> traffic an edge guard failed fast
+	lost        map[string]map[string]trafficMix         // parent -> target -> traffic dropped by packet loss
+	unrouted    map[string]trafficMix                    // node -> traffic none of its edges would take
+	retried     map[string]map[string]trafficMix         // parent -> target -> retried traffic that got through on that edge
+	origins     map[string]map[string]float64            // node -> user region -> incoming RPS from that region
+	originFlows map[string]map[string]map[string]float64 // parent -> target -> user region -> share, for origin-aware splits
+}
+
+// recordPathLatencies follows this tick's requests from the entry points in
+// topological order, carrying each node's distribution of latency so far to
+// its targets, and records the latency of the requests that completed at each
+// node (cache hits end at the cache, reads end at the DB...). Every node also
+// keeps its maxCriticalPaths slowest paths, which is enough to find the
+// slowest complete paths without enumerating them all.
+func (e *Engine) recordPathLatencies(entryTraffic map[string]trafficMix, traffic *tickTraffic) {
+	e.tickLatency = e.tickLatency[:0]
+	arrived := make(map[string]latencyDist)
+	slowest := make(map[string][]slowPath)
+
+	for _, entryID := range e.state.NodeOrder {
+		if rps := entryTraffic[entryID].Total(); rps > 0 && e.state.NodeStates[entryID] != nil {
+			hop := e.hopLatency("", entryID)
+			arrived[entryID] = latencyDist{latencyBucket(hop): rps}
+			slowest[entryID] = []slowPath{{nodes: []string{entryID}, hops: []float64{hop}, latency: hop, rps: rps}}
+		}
+	}
+
+	for _, component := range e.topology.components {
+		for _, nodeID := range component {
+			dist := arrived[nodeID].compact()
+			incoming := traffic.incoming[nodeID].Total()
+			if len(dist) == 0 || incoming <= 0 {
+				continue
+			}
+			latencies := dist.latencies()
+
+			// Follow synchronous edges in route order; mirror copies are fire-and-forget
+			forwarded := 0.0
+			seen := make(map[string]bool)
+			for _, route := range e.routes[nodeID] {
+				if seen[route.target] {
+					continue
+				}
+				seen[route.target] = true
+
+				flow := traffic.flows[nodeID][route.target].Total() - traffic.outgoing[nodeID].Total()*e.mirrorShare(nodeID, route.target)
+				if flow <= 0 || e.topology.feedbackEdges[nodeID][route.target] {
+					// Cycle-closing edges carry feedback, not the original request
+					continue
+				}
+				// A queue can deliver more than arrived this tick (draining backlog)
+				fraction := math.Min(flow/incoming, 1.0-forwarded)
+				if fraction <= 0 {
+					continue
+				}
+				forwarded += fraction
+				if e.state.NodeStates[route.target] == nil {
+					continue // Routed to a missing node: lost
+				}
+
+				hop := e.hopLatency(nodeID, route.target)
+				if arrived[route.target] == nil {
+					arrived[route.target] = make(latencyDist)
+				}
+				for _, latency := range latencies {
+					arrived[route.target][latencyBucket(latency+hop)] += dist[latency] * fraction
+				}
+				slowest[route.target] = keepSlowest(slowest[route.target], slowest[nodeID], route.target, hop, fraction)
+			}
+
+			if completed := math.Max(0, 1.0-forwarded); completed > 0 {
+				for _, latency := range latencies {
+					e.tickLatency = append(e.tickLatency, latencySample{latency: latency, weight: dist[latency] * completed})
+					e.recordLatency(latency, dist[latency]*completed)
+				}
+				for _, path := range slowest[nodeID] {
+					e.recordPath(path, path.rps*completed)
+				}
+			}
+		}
+	}
+}
+
+// keepSlowest extends a parent's slowest paths over one edge and keeps the
+// maxCriticalPaths slowest of them and the paths the target already has
+func keepSlowest(paths, parentPaths []slowPath, targetID string, hop, fraction float64) []slowPath {
+	for _, parent := range parentPaths {
+		if parent.rps*fraction <= 0 {
+			continue
+		}
+		paths = append(paths, slowPath{
+			nodes:   append(append([]string(nil), parent.nodes...), targetID),
+			hops:    append(append([]float64(nil), parent.hops...), hop),
+			latency: parent.latency + hop,
+			rps:     parent.rps * fraction,
+		})
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return paths[i].latency > paths[j].latency
+	})
+	if len(paths) > maxCriticalPaths {
+		paths = paths[:maxCriticalPaths]
+	}
+	return paths
+}
+
+// latencies returns the distribution's latencies in ascending order
+func (d latencyDist) latencies() []float64 {
+	latencies := make([]float64, 0, len(d))
+	for latency := range d {
+		latencies = append(latencies, latency)
+	}
+	sort.Float64s(latencies)
+	return latencies
+}
+
+// compact merges neighbouring latencies into at most maxLatencyBuckets
+// buckets of equal width; a merged bucket keeps the traffic-weighted mean
+// of its latencies
+func (d latencyDist) compact() latencyDist {
+	if len(d) <= maxLatencyBuckets {
+		return d
+	}
+	latencies := d.latencies()
+	low, high := latencies[0], latencies[len(latencies)-1]
+	width := (high - low) / maxLatencyBuckets
+
+	sums := make([]float64, maxLatencyBuckets+1)
+	weights := make([]float64, maxLatencyBuckets+1)
+	for _, latency := range latencies {
+		i := int((latency - low) / width)
+		sums[i] += latency * d[latency]
+		weights[i] += d[latency]
+	}
+	compacted := make(latencyDist, maxLatencyBuckets+1)
+	for i, weight := range weights {
+		if weight > 0 {
+			compacted[latencyBucket(sums[i]/weight)] += weight
+		}
+	}
+	return compacted
+}
+
+// hopLatency is a node's latency as seen by requests arriving from parentID:
+// the node's own latency with the network latency of that specific edge
+// instead of the average over all its incoming edges
+func (e *Engine) hopLatency(parentID, nodeID string) float64 {
+	node := e.state.NodeStates[nodeID]
+	latency := node.LatencyMS - node.network
+	if parent := e.state.NodeStates[parentID]; parent != nil {
+		latency += GetPlacementLatency(parent.Region, parent.Zone, node.Region, node.Zone)
+	}
+	return latency
+}
+
+// recordPath adds the requests that completed on a path to its run stats
+func (e *Engine) recordPath(path slowPath, weight float64) {
+	key := strings.Join(path.nodes, ">")
+	stats := e.state.paths[key]
+	if stats == nil {
+		stats = &pathStats{
+			nodes:   path.nodes,
+			hopSums: make([]float64, len(path.hops)),
+		}
+		e.state.paths[key] = stats
+	}
+	stats.weight += weight
+	stats.latencySum += path.latency * weight
+	stats.maxLatency = math.Max(stats.maxLatency, path.latency)
+	for i, hop := range path.hops {
+		stats.hopSums[i] += hop * weight
+	}
+}
+
+// recordLatency adds a traffic-weighted sample to the run-wide distribution
+func (e *Engine) recordLatency(latency, weight float64) {
+	e.state.LatencyHistogram[latencyBucket(latency)] += weight
+}
+
+// latencyBucket rounds a latency to 0.01ms, the precision the output is rounded to
+func latencyBucket(latency float64) float64 {
+	return math.Round(latency*100) / 100
+}
+
+// mirrorShare returns the fraction of a parent's output copied to a target over mirror edges
+func (e *Engine) mirrorShare(parentID, targetID string) float64 {
+	share := 0.0
+	for _, route := range e.routes[parentID] {
+		if route.mirror && route.target == targetID {
+			share += route.mirrorPercent / 100.0
+		}
+	}
+	return share
+}
+
+// latencyMetricsFromSamples computes traffic-weighted percentiles
+func latencyMetricsFromSamples(samples []latencySample) LatencyMetrics {
+	sorted := make([]latencySample, 0, len(samples))
+	totalWeight := 0.0
+	for _, sample := range samples {
+		if sample.weight > 0 {
+			sorted = append(sorted, sample)
+			totalWeight += sample.weight
+		}
+	}
+	if len(sorted) == 0 || totalWeight == 0 {
+		return LatencyMetrics{}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].latency < sorted[j].latency
+	})
+
+	weightedSum := 0.0
+	for _, sample := range sorted {
+		weightedSum += sample.latency * sample.weight
+	}
+
+	return LatencyMetrics{
+		P50: weightedPercentile(sorted, totalWeight, 0.50),
+		P95: weightedPercentile(sorted, totalWeight, 0.95),
+		P99: weightedPercentile(sorted, totalWeight, 0.99),
+		Avg: weightedSum / totalWeight,
+		Max: sorted[len(sorted)-1].latency,
+	}
+}
+
+// latencyHistogramSamples flattens the run-wide distribution into samples
+func (e *Engine) latencyHistogramSamples() []latencySample {
+	samples := make([]latencySample, 0, len(e.state.LatencyHistogram))
+	for latency, weight := range e.state.LatencyHistogram {
+		samples = append(samples, latencySample{latency: latency, weight: weight})
+	}
+	return samples
+}
+
+// weightedPercentile returns the latency below which a share of traffic falls
+func weightedPercentile(sorted []latencySample, totalWeight, percentile float64) float64 {
+	threshold := percentile * totalWeight
+	cumulative := 0.0
+	for _, sample := range sorted {
+		cumulative += sample.weight
+		if cumulative >= threshold {
+			return sample.latency
+		}
+	}
+	return sorted[len(sorted)-1].latency
+}
+
+// criticalPaths returns the slowest paths that carried traffic, with each hop's share
+func (e *Engine) criticalPaths() []CriticalPath {
+	totalWeight := 0.0
+	for _, weight := range e.state.LatencyHistogram {
+		totalWeight += weight
+	}
+	keys := make([]string, 0, len(e.state.paths))
+	for key := range e.state.paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	paths := make([]CriticalPath, 0, len(keys))
+	for _, key := range keys {
+		stats := e.state.paths[key]
+		if stats.weight <= 0 {
+			continue
+		}
+		hops := make([]PathHop, len(stats.nodes))
+		for i, nodeID := range stats.nodes {
+			hops[i] = PathHop{NodeID: nodeID, LatencyMs: math.Round(stats.hopSums[i]/stats.weight*100) / 100}
+		}
+		paths = append(paths, CriticalPath{
+			Nodes:          stats.nodes,
+			LatencyMs:      math.Round(stats.latencySum/stats.weight*100) / 100,
+			MaxLatencyMs:   math.Round(stats.maxLatency*100) / 100,
+			TrafficPercent: math.Round(stats.weight/totalWeight*1000) / 10,
+			Hops:           hops,
+		})
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return paths[i].LatencyMs > paths[j].LatencyMs
+	})
+	if len(paths) > maxCriticalPaths {
+		paths = paths[:maxCriticalPaths]
+	}
+	return paths
+}
@@ -0,0 +1,121 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestWeightedPercentiles(t *testing.T) {
+	// 90 requests at 10ms, 9 at 50ms, 1 at 200ms
+	samples := []latencySample{{latency: 50, weight: 9}, {latency: 10, weight: 90}, {latency: 200, weight: 1}, {latency: 999, weight: 0}}
+	got := latencyMetricsFromSamples(samples)
+	want := LatencyMetrics{P50: 10, P95: 50, P99: 50, Avg: (900 + 450 + 200) / 100.0, Max: 200}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := latencyMetricsFromSamples(nil); got != (LatencyMetrics{}) {
+		t.Errorf("no samples: got %+v, want zeros", got)
+	}
+}
+
+// latencyInput splits traffic 90/10 between two servers, the second one
+// slowed down by delayMs
+func latencyInput(delayMs int) *SimulationInput {
+	var failures []FailureInjection
+	if delayMs > 0 {
+		failures = append(failures, FailureInjection{Type: FailureNodeLatency, NodeID: "b", DelayMs: delayMs})
+	}
+	return routingInput(SimEdgeData{Weight: weight(90)}, SimEdgeData{Weight: weight(10)}, failures...)
+}
+
+func TestPercentilesFollowTrafficShares(t *testing.T) {
+	healthy, err := NewEngine(latencyInput(0)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, err := NewEngine(latencyInput(500)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The slow tenth of the traffic shows in p95 and p99 but not in p50
+	if slow.Metrics.Latency.P50 != healthy.Metrics.Latency.P50 {
+		t.Errorf("p50 %.2fms, want the healthy %.2fms", slow.Metrics.Latency.P50, healthy.Metrics.Latency.P50)
+	}
+	for name, p := range map[string]float64{"p95": slow.Metrics.Latency.P95, "p99": slow.Metrics.Latency.P99} {
+		if p < healthy.Metrics.Latency.P99+500 {
+			t.Errorf("%s %.2fms, want the 500ms delay on top of %.2fms", name, p, healthy.Metrics.Latency.P99)
+		}
+	}
+	if path := slow.CriticalPaths[0]; path.Nodes[len(path.Nodes)-1] != "b" || math.Abs(path.TrafficPercent-10) > 0.5 {
+		t.Errorf("slowest path %v with %.1f%% of traffic, want the tenth through b", path.Nodes, path.TrafficPercent)
+	}
+}
+
+// Every request is accounted for however many paths the graph has
+func TestLatencyCoversEveryPath(t *testing.T) {
+	seed := int64(1)
+	input := &SimulationInput{Workload: WorkloadConfig{RPS: 1000, Mode: "constant", DurationSeconds: 3, Seed: &seed}}
+	input.Nodes = append(input.Nodes, SimNode{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}})
+	previous := []string{"client"}
+	for layer := 0; layer < 6; layer++ { // 5^6 client->leaf paths
+		current := []string{}
+		for i := 0; i < 5; i++ {
+			id := fmt.Sprintf("n%d_%d", layer, i)
+			config := map[string]interface{}{"replicas": 5 + i}
+			input.Nodes = append(input.Nodes, SimNode{ID: id, Data: SimNodeData{NodeType: "api_server", Config: config}})
+			for _, parentID := range previous {
+				input.Edges = append(input.Edges, SimEdge{ID: parentID + ">" + id, Source: parentID, Target: id})
+			}
+			current = append(current, id)
+		}
+		previous = current
+	}
+
+	engine := NewEngine(input)
+	output, err := engine.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed := 0.0
+	for _, requests := range engine.state.LatencyHistogram {
+		completed += requests
+	}
+	if math.Abs(completed-float64(output.Metrics.TotalRequests)) > 1 {
+		t.Errorf("latency recorded for %.0f requests, want all %d", completed, output.Metrics.TotalRequests)
+	}
+	// Requests only complete at the leaves, so no path may be cut short
+	for key, path := range engine.state.paths {
+		if len(path.nodes) != 7 {
+			t.Fatalf("path %s ends before the leaves", key)
+		}
+	}
+	if len(output.CriticalPaths) != maxCriticalPaths {
+		t.Errorf("got %d critical paths, want %d", len(output.CriticalPaths), maxCriticalPaths)
+	}
+}
+
+func TestCompactLatencyDist(t *testing.T) {
+	dist := make(latencyDist)
+	total, weighted := 0.0, 0.0
+	for i := 0; i < 10*maxLatencyBuckets; i++ {
+		latency := latencyBucket(float64(i) * 0.37)
+		dist[latency] += float64(i%7 + 1)
+		total += float64(i%7 + 1)
+		weighted += latency * float64(i%7+1)
+	}
+
+	compacted := dist.compact()
+	if len(compacted) > maxLatencyBuckets+1 {
+		t.Errorf("got %d buckets, want at most %d", len(compacted), maxLatencyBuckets+1)
+	}
+	gotTotal, gotWeighted := 0.0, 0.0
+	for latency, requests := range compacted {
+		gotTotal += requests
+		gotWeighted += latency * requests
+	}
+	if math.Abs(gotTotal-total) > 1e-6 || math.Abs(gotWeighted/gotTotal-weighted/total) > 0.01 {
+		t.Errorf("got %.1f requests averaging %.3fms, want %.1f averaging %.3fms", gotTotal, gotWeighted/gotTotal, total, weighted/total)
+	}
+}
@@ -9,9 +9,10 @@ import (
 func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 	metrics := make(map[string]NodeMetrics)
 
-	for nodeID, state := range e.state.NodeStates {
+	for _, state := range e.orderedNodes() {
+		nodeID := state.ID
 		// Client nodes don't have CPU/memory - they just generate traffic
-		isClient := state.Type == "client"
+		isClient := isClientType(state.Type)
 
 		effectiveCapacity := state.CapacityRPS * float64(state.Replicas)
 
@@ -21,7 +22,7 @@ func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 		if !isClient {
 			resources := calculateResourceUsage(state, state.CurrentLoad, effectiveCapacity)
 			cpuPercent = resources.CPUPercent
-			memPercent = resources.MemoryPercent
+			memPercent = math.Min(100, resources.MemoryPercent+state.faults.leak)
 			diskIOPercent = resources.DiskIOPercent
 			networkPercent = resources.NetworkPercent
 			bottleneck = resources.Bottleneck
@@ -40,7 +41,7 @@ func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 		}
 
 		cacheHitRate := -1.0
-		if state.Type == "cache_redis" || state.Type == "cache_memcached" {
+		if isCache(state.Type) {
 			cacheHitRate = state.CacheHitRate
 		}
 
@@ -62,6 +63,12 @@ func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 			NodeID:         nodeID,
 			RPSIn:          math.Round(state.RPSIn*10) / 10, // Round RPS to 1 decimal
 			RPSOut:         math.Round(state.RPSOut*10) / 10,
+			ReadRPS:        math.Round(state.ReadRPS*10) / 10,
+			WriteRPS:       math.Round(state.WriteRPS*10) / 10,
+			RetryRPS:       math.Round(state.RetryRPS*10) / 10,
+			Amplification:  math.Round(state.Amplification*1000) / 1000,
+			ReplicationLag: math.Round(state.ReplicaLagMS*100) / 100,
+			StaleReadRPS:   math.Round(state.StaleReadRPS*10) / 10,
 			LatencyMs:      math.Round(state.LatencyMS*100) / 100, // Round latency to 2 decimal for precision
 			CPUPercent:     cpuPercent,
 			MemPercent:     memPercent,
@@ -69,11 +76,22 @@ func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 			NetworkPercent: networkPercent, // NEW
 			Errors:         state.ErrorCount,
 			QueueDepth:     state.QueueDepth,
+			QueueWaitMs:    math.Round(state.QueueWaitMS*100) / 100,
+			DeadLettered:   state.DeadLettered,
 			CacheHitRate:   cacheHitRate,
 			Status:         status,
 			SuccessRate:    successRate,
 			Replicas:       state.Replicas, // Include current replica count for auto-scaling viz
 			Bottleneck:     bottleneck,     // NEW: Include bottleneck type
+			Provisioning:   state.pendingReplicas(),
+		}
+		if f := state.function; f != nil {
+			m := metrics[nodeID]
+			m.ColdStarts = int(math.Round(state.ColdStarts))
+			m.Throttles = int(math.Round(state.ThrottledRPS))
+			m.Concurrency = math.Round(f.concurrency(state.RPSIn-state.ThrottledRPS)*10) / 10
+			m.WarmPool = int(math.Round(f.warm))
+			metrics[nodeID] = m
 		}
 	}
 
@@ -84,11 +102,13 @@ func (e *Engine) calculateNodeMetrics() map[string]NodeMetrics {
 func (e *Engine) detectBottlenecks() []Bottleneck {
 	bottlenecks := []Bottleneck{}
 
-	for nodeID, state := range e.state.NodeStates {
+	for _, state := range e.orderedNodes() {
+		nodeID := state.ID
 		effectiveCapacity := state.CapacityRPS * float64(state.Replicas)
 
 		// Use the resource model to get accurate resource usage
 		resources := calculateResourceUsage(state, state.CurrentLoad, effectiveCapacity)
+		kind := componentModel(state.Type).Name()
 
 		// Check for overload
 		if state.CurrentLoad > effectiveCapacity*1.2 {
@@ -103,7 +123,7 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 				"Optimize request processing",
 			}
 
-			if state.Type == "database_sql" || state.Type == "database_nosql" {
+			if kind == "database_sql" || kind == "database_nosql" {
 				suggestions = append(suggestions, "Add read replicas", "Enable caching layer")
 			}
 
@@ -122,7 +142,7 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		case "cpu":
 			if resources.CPUPercent > 85 && !state.Failed {
 				suggestions := []string{"Scale horizontally", "Optimize algorithms"}
-				if state.Type == "database_sql" || state.Type == "database_graph" {
+				if kind == "database_sql" || kind == "database_graph" {
 					suggestions = append(suggestions, "Optimize queries", "Add indexes")
 				}
 				bottlenecks = append(bottlenecks, Bottleneck{
@@ -138,9 +158,9 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		case "memory":
 			if resources.MemoryPercent > 85 {
 				suggestions := []string{"Increase instance size", "Add more memory"}
-				if state.Type == "cache_redis" || state.Type == "cache_memcached" {
+				if isCache(state.Type) {
 					suggestions = []string{"Increase cache size", "Implement eviction policy", "Add cache sharding"}
-				} else if state.Type == "database_nosql" {
+				} else if kind == "database_nosql" {
 					suggestions = []string{"Increase memory", "Optimize document size", "Add sharding"}
 				}
 				bottlenecks = append(bottlenecks, Bottleneck{
@@ -156,9 +176,9 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		case "disk":
 			if resources.DiskIOPercent > 85 {
 				suggestions := []string{"Upgrade storage type (e.g., gp3 → io2)", "Add read replicas"}
-				if state.Type == "database_sql" {
+				if kind == "database_sql" {
 					suggestions = append(suggestions, "Optimize indexes", "Partition tables")
-				} else if state.Type == "database_timeseries" {
+				} else if kind == "database_timeseries" {
 					suggestions = []string{"Increase write buffer", "Optimize retention policy", "Use faster storage"}
 				}
 				bottlenecks = append(bottlenecks, Bottleneck{
@@ -174,7 +194,7 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		case "network":
 			if resources.NetworkPercent > 90 {
 				suggestions := []string{"Upgrade instance type for better network", "Add CDN", "Implement compression"}
-				if state.Type == "load_balancer" {
+				if kind == "load_balancer" {
 					suggestions = []string{"Upgrade to NLB for higher throughput", "Add more load balancers", "Enable connection pooling"}
 				}
 				bottlenecks = append(bottlenecks, Bottleneck{
@@ -189,7 +209,7 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		}
 
 		// Queue-specific bottleneck (backlog)
-		if (state.Type == "queue" || state.Type == "message_broker") && float64(state.QueueDepth) > float64(state.MaxQueueDepth)*0.5 {
+		if isQueue(state.Type) && float64(state.QueueDepth) > float64(state.MaxQueueDepth)*0.5 {
 			severity := "medium"
 			if state.QueueDepth >= state.MaxQueueDepth {
 				severity = "critical"
@@ -208,7 +228,7 @@ func (e *Engine) detectBottlenecks() []Bottleneck {
 		}
 
 		// Cache-specific bottleneck (low hit rate)
-		if (state.Type == "cache_redis" || state.Type == "cache_memcached") && state.CacheHitRate < 0.5 {
+		if isCache(state.Type) && state.CacheHitRate < 0.5 {
 			bottlenecks = append(bottlenecks, Bottleneck{
 				NodeID:      nodeID,
 				Issue:       "Low Cache Hit Rate",
@@ -262,186 +282,42 @@ func (e *Engine) checkSLAStatus(latency LatencyMetrics, errorRate, throughput fl
 	}
 }
 
-// calculateCostMetrics estimates infrastructure costs
 // getInstanceCost returns the hourly cost for an instance type
-func getInstanceCost(instanceType string) float64 {
-	costs := map[string]float64{
-		// Compute instances (EC2)
-		"t3.micro":   0.0104,
-		"t3.small":   0.0208,
-		"t3.medium":  0.0416,
-		"t3.large":   0.0832,
-		"t3.xlarge":  0.1664,
-		"t3.2xlarge": 0.3328,
-		"m5.large":   0.096,
-		"m5.xlarge":  0.192,
-		"m5.2xlarge": 0.384,
-		"m5.4xlarge": 0.768,
-		"m5.8xlarge": 1.536,
-		"c5.large":   0.085,
-		"c5.xlarge":  0.17,
-		"c5.2xlarge": 0.34,
-		"c5.4xlarge": 0.68,
-		"c5.9xlarge": 1.53,
-		"r5.large":   0.126,
-		"r5.xlarge":  0.252,
-		"r5.2xlarge": 0.504,
-		"r5.4xlarge": 1.008,
-		// Database instances (RDS)
-		"db.t3.micro":   0.017,
-		"db.t3.small":   0.034,
-		"db.t3.medium":  0.068,
-		"db.t3.large":   0.136,
-		"db.t3.xlarge":  0.272,
-		"db.t3.2xlarge": 0.544,
-		"db.m5.large":   0.188,
-		"db.m5.xlarge":  0.376,
-		"db.m5.2xlarge": 0.752,
-		"db.m5.4xlarge": 1.504,
-		"db.m5.8xlarge": 3.008,
-		"db.r5.large":   0.29,
-		"db.r5.xlarge":  0.58,
-		"db.r5.2xlarge": 1.16, // ← ADDED! $1.16/hr (user expects ~$1.00)
-		"db.r5.4xlarge": 2.32,
-		"db.r5.8xlarge": 4.64,
-		// Cache instances (ElastiCache)
-		"cache.t3.micro":   0.017,
-		"cache.t3.small":   0.034,
-		"cache.t3.medium":  0.068,
-		"cache.t3.large":   0.136,
-		"cache.m5.large":   0.136,
-		"cache.m5.xlarge":  0.272,
-		"cache.m5.2xlarge": 0.544,
-		"cache.m5.4xlarge": 1.088,
-		"cache.r5.large":   0.252,
-		"cache.r5.xlarge":  0.504,
-		"cache.r5.2xlarge": 1.008,
-		"cache.r5.4xlarge": 2.016,
-	}
-
-	if cost, ok := costs[instanceType]; ok {
-		return cost
+func getInstanceCost(hw HardwareProvider, instanceType string) float64 {
+	if perf, ok := hw.Instance(instanceType); ok {
+		return perf.HourlyCostUSD
 	}
 	// Default fallback cost
 	return 0.05
 }
 
 // getLBCost returns the hourly cost for a load balancer type
-func getLBCost(lbType string) float64 {
-	costs := map[string]float64{
-		"alb":     0.0225,
-		"nlb":     0.0225,
-		"classic": 0.025,
-	}
-
-	if cost, ok := costs[lbType]; ok {
-		return cost
+func getLBCost(hw HardwareProvider, lbType string) float64 {
+	if perf, ok := hw.LoadBalancer(lbType); ok {
+		return perf.HourlyCostUSD
 	}
 	return 0.0225
 }
 
+// calculateCostMetrics estimates infrastructure costs
 func (e *Engine) calculateCostMetrics() CostMetrics {
 	compute := make(map[string]float64)
 	storage := make(map[string]float64)
 	network := make(map[string]float64)
 	perRegion := make(map[string]float64)
+	buckets := map[string]map[string]float64{costCompute: compute, costStorage: storage, costNetwork: network}
+	totals := make(map[string]float64)
+	durationSeconds := float64(e.config.DurationSeconds)
 
-	totalCompute := 0.0
-	totalStorage := 0.0
-	totalNetwork := 0.0
-
-	// Duration in hours
-	durationHours := float64(e.config.DurationSeconds) / 3600.0
-
-	for _, state := range e.state.NodeStates {
+	for _, state := range e.orderedNodes() {
+		// Each component model prices its own nodes
 		nodeCost := 0.0
-
-		// Use real AWS pricing based on instance types
-		switch state.Type {
-		case "api_server", "compute", "web_server", "microservice":
-			// Use actual instance type pricing
-			if state.InstanceType != "" {
-				hourlyRate := getInstanceCost(state.InstanceType)
-				nodeCost = hourlyRate * float64(state.Replicas) * durationHours
-				compute["compute_instances"] += nodeCost
-			} else {
-				// Fallback: assume m5.large at $0.096/hr if no instance type
-				nodeCost = 0.096 * float64(state.Replicas) * durationHours
-				compute["compute_instances"] += nodeCost
-			}
-
-		case "load_balancer":
-			// Real AWS ALB/NLB pricing
-			if state.LBType != "" {
-				hourlyRate := getLBCost(state.LBType)
-				nodeCost = hourlyRate*durationHours + (state.RPSOut * float64(e.config.DurationSeconds) * 0.001 * 0.008)
-				compute["load_balancer"] += nodeCost
-			} else {
-				// Fallback: ALB pricing
-				hourlyRate := 0.0225
-				nodeCost = hourlyRate*durationHours + (state.RPSOut * float64(e.config.DurationSeconds) * 0.001 * 0.008)
-				compute["load_balancer"] += nodeCost
-			}
-
-		case "database_sql", "database_nosql", "database_postgres", "database_mysql", "database_mongodb":
-			// Real AWS RDS pricing
-			if state.InstanceType != "" {
-				hourlyRate := getInstanceCost(state.InstanceType)
-				nodeCost = hourlyRate * float64(state.Replicas) * durationHours
-				compute["database"] += nodeCost
-			} else {
-				// Fallback if no instance type specified
-				nodeCost = 0.188 * float64(state.Replicas) * durationHours
-				compute["database"] += nodeCost
-			}
-
-			// Storage cost: $0.10 per GB-month
-			if state.StorageSizeGB > 0 {
-				storageCost := state.StorageSizeGB * 0.10 * (durationHours / 720.0)
-				storage["database_storage"] += storageCost
-				totalStorage += storageCost
-			}
-
-		case "cache_redis", "cache_memcached":
-			// Real AWS ElastiCache pricing
-			if state.InstanceType != "" {
-				hourlyRate := getInstanceCost(state.InstanceType)
-				nodeCost = hourlyRate * float64(state.Replicas) * durationHours
-				compute["cache"] += nodeCost
-			} else {
-				// Fallback: cache.m5.large at $0.136/hr
-				nodeCost = 0.136 * float64(state.Replicas) * durationHours
-				compute["cache"] += nodeCost
-			}
-
-		case "queue", "message_broker":
-			// $0.01 per million messages
-			messagesProcessed := state.RPSOut * float64(e.config.DurationSeconds)
-			nodeCost = (messagesProcessed / 1000000) * 0.01
-			compute["queue"] += nodeCost
-
-		case "cdn":
-			// $0.085 per GB transferred
-			dataGB := state.RPSOut * float64(e.config.DurationSeconds) * 0.001
-			nodeCost = dataGB * 0.085
-			network["cdn"] += nodeCost
-			totalNetwork += nodeCost
-
-		case "object_storage":
-			// $0.023 per GB stored + $0.09 per GB out
-			if state.StorageSizeGB > 0 {
-				storageCost := state.StorageSizeGB * 0.023 * (durationHours / 720.0)
-				storage["object_storage"] += storageCost
-				totalStorage += storageCost
-			}
-			dataOut := state.RPSOut * float64(e.config.DurationSeconds) * 0.001
-			egressCost := dataOut * 0.09
-			network["egress"] += egressCost
-			totalNetwork += egressCost
+		for _, item := range componentModel(state.Type).Cost(e.hardware, state, durationSeconds) {
+			buckets[item.bucket][item.category] += item.usd
+			totals[item.bucket] += item.usd
+			nodeCost += item.usd
 		}
 
-		totalCompute += nodeCost
-
 		// Regional cost tracking
 		if state.Region != "" {
 			perRegion[state.Region] += nodeCost
@@ -453,10 +329,10 @@ func (e *Engine) calculateCostMetrics() CostMetrics {
 	// Network transfer costs (between nodes)
 	networkTransferCost := float64(e.state.SuccessRequests) * 0.00001 // $0.01 per 1M requests
 	network["internal_transfer"] = networkTransferCost
-	totalNetwork += networkTransferCost
+	totals[costNetwork] += networkTransferCost
 
 	// Calculate total cost
-	totalCost := totalCompute + totalStorage + totalNetwork
+	totalCost := totals[costCompute] + totals[costStorage] + totals[costNetwork]
 
 	// CRITICAL FIX: Round to 4 decimal places to show small costs accurately
 	// For 30-second simulations, costs like $0.0042 would round to $0.00
@@ -471,6 +347,30 @@ func (e *Engine) calculateCostMetrics() CostMetrics {
 	}
 }
 
+// calculateZoneLatency averages node latency per availability zone, keyed
+// "region/az". Nodes not placed in an AZ are left out.
+func (e *Engine) calculateZoneLatency() map[string]float64 {
+	zoneLatency := make(map[string]float64)
+	zoneCounts := make(map[string]int)
+
+	for _, state := range e.orderedNodes() {
+		if state.Zone == "" || state.LatencyMS <= 0 {
+			continue
+		}
+		key := state.Region + "/" + state.Zone
+		zoneLatency[key] += state.LatencyMS
+		zoneCounts[key]++
+	}
+
+	for zone, count := range zoneCounts {
+		zoneLatency[zone] /= float64(count)
+	}
+	if len(zoneLatency) == 0 {
+		return nil
+	}
+	return zoneLatency
+}
+
 // calculateRegionalMetrics aggregates metrics by region
 func (e *Engine) calculateRegionalMetrics() (map[string]float64, map[string]float64, map[string]float64) {
 	regionLatency := make(map[string]float64)
@@ -480,7 +380,7 @@ func (e *Engine) calculateRegionalMetrics() (map[string]float64, map[string]floa
 	regionTotalRequests := make(map[string]int)
 	regionFailedRequests := make(map[string]int)
 
-	for _, state := range e.state.NodeStates {
+	for _, state := range e.orderedNodes() {
 		region := state.Region
 		if region == "" {
 			region = "default"
@@ -498,7 +398,7 @@ func (e *Engine) calculateRegionalMetrics() (map[string]float64, map[string]floa
 		// CRITICAL FIX: Only count traffic at ENTRY NODES (clients) to avoid double-counting!
 		// Traffic flows: Client → LB → API → DB
 		// If we sum RPSIn at every node, we count the same request 3-4 times!
-		isEntryNode := (state.Type == "client" || state.Type == "mobile_app" || state.Type == "web_browser")
+		isEntryNode := isClientType(state.Type)
 		if isEntryNode {
 			// For entry nodes, use RPSOut (what they generate)
 			totalReqs := int(state.RPSOut * float64(e.config.DurationSeconds))
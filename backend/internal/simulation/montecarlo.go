@@ -0,0 +1,338 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Monte Carlo defaults and limits
+const (
+	DefaultMonteCarloIterations = 100
+	MaxMonteCarloIterations     = 1000
+	DefaultConfidenceLevel      = 0.95
+	burstPeriod                 = 7 // Ticks between the guaranteed bursts of burst mode
+)
+
+// batchSlots caps the simulation runs in flight across every batch on the
+// server (Monte Carlo, optimization), so one request can't take every core
+var batchSlots = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+// MonteCarloConfig sets up a batch of randomized runs
+type MonteCarloConfig struct {
+	Iterations       int     `json:"iterations"`                 // Runs in the batch (default 100)
+	Concurrency      int     `json:"concurrency,omitempty"`      // Runs in flight at once (default: as many as the server allows)
+	ConfidenceLevel  float64 `json:"confidenceLevel,omitempty"`  // Of the intervals (default 0.95)
+	CacheHitVariance float64 `json:"cacheHitVariance,omitempty"` // Std dev of every cache's per-tick hit ratio, as a fraction of it
+}
+
+// Distribution summarizes one metric over a batch
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	P5     float64 `json:"p5"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+	CILow  float64 `json:"ciLow"` // Confidence interval of the mean
+	CIHigh float64 `json:"ciHigh"`
+}
+
+// BreachProbability is the share of runs that broke an SLA target, with its
+// confidence interval (Wilson score)
+type BreachProbability struct {
+	Target      float64 `json:"target,omitempty"`
+	Breaches    int     `json:"breaches"`
+	Probability float64 `json:"probability"`
+	CILow       float64 `json:"ciLow"`
+	CIHigh      float64 `json:"ciHigh"`
+}
+
+// MonteCarloResult is the outcome of a batch of runs
+type MonteCarloResult struct {
+	Iterations      int                          `json:"iterations"`
+	ConfidenceLevel float64                      `json:"confidenceLevel"`
+	P95Latency      Distribution                 `json:"p95LatencyMs"`
+	P99Latency      Distribution                 `json:"p99LatencyMs"`
+	ErrorRate       Distribution                 `json:"errorRatePercent"`
+	Throughput      Distribution                 `json:"throughputRps"`
+	Cost            Distribution                 `json:"costUsd"`
+	SLABreaches     map[string]BreachProbability `json:"slaBreaches,omitempty"` // SLA target -> chance a run breaks it
+	AnySLABreach    *BreachProbability           `json:"anySlaBreach,omitempty"`
+	Seed            int64                        `json:"seed"` // Seed of the batch (pass it back in workload.seed to replay)
+}
+
+// monteCarloSample is what one run of a batch contributes
+type monteCarloSample struct {
+	p95, p99     float64
+	errorRate    float64 // Percentage
+	availability float64 // Percentage
+	throughput   float64
+	cost         float64
+}
+
+// Validate fills in the defaults of a batch and checks its settings
+func (c *MonteCarloConfig) Validate() error {
+	if c.Iterations == 0 {
+		c.Iterations = DefaultMonteCarloIterations
+	}
+	if c.Iterations < 1 || c.Iterations > MaxMonteCarloIterations {
+		return fmt.Errorf("iterations must be between 1 and %d", MaxMonteCarloIterations)
+	}
+	if c.ConfidenceLevel == 0 {
+		c.ConfidenceLevel = DefaultConfidenceLevel
+	}
+	if c.ConfidenceLevel <= 0 || c.ConfidenceLevel >= 1 {
+		return fmt.Errorf("confidence level must be between 0 and 1")
+	}
+	if c.CacheHitVariance < 0 {
+		return fmt.Errorf("cache hit variance can't be negative")
+	}
+	return nil
+}
+
+// RunMonteCarlo runs the input many times, each with its own seed, burst
+// timing and cache noise, and summarizes the spread of the results. The
+// workload seed seeds the batch, so the same input and seed give the same
+// result whatever the concurrency.
+func RunMonteCarlo(input *SimulationInput, config MonteCarloConfig) (*MonteCarloResult, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	seed := time.Now().UnixNano()
+	if input.Workload.Seed != nil {
+		seed = *input.Workload.Seed
+	}
+	batchRand := rand.New(rand.NewSource(seed))
+	seeds := make([]int64, config.Iterations)
+	offsets := make([]int, config.Iterations)
+	for i := range seeds {
+		seeds[i] = batchRand.Int63()
+		offsets[i] = batchRand.Intn(burstPeriod)
+	}
+
+	// Engines share the input read-only; sort the schedule once up front
+	shared := *input
+	shared.Workload.Shape = input.Workload.Shape.normalized()
+
+	samples := make([]monteCarloSample, config.Iterations)
+	errs := runBatch(config.Iterations, config.Concurrency, func(i int) (err error) {
+		samples[i], err = runMonteCarloIteration(&shared, seeds[i], offsets[i], config.CacheHitVariance)
+		return err
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("iteration %d: %w", i+1, err)
+		}
+	}
+	return summarizeMonteCarlo(samples, input.SLAConfig, config.ConfidenceLevel, seed), nil
+}
+
+// runBatch calls run for 0..count-1 from up to concurrency workers (0 = as
+// many as the server allows), each call holding one of the shared batch
+// slots. It returns each call's error; a call that panics fails with an
+// error instead of taking the process down.
+func runBatch(count, concurrency int, run func(i int) error) []error {
+	workers := cap(batchSlots)
+	if concurrency > 0 && concurrency < workers {
+		workers = concurrency
+	}
+	workers = minInt(workers, count)
+
+	errs := make([]error, count)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = runBatchCall(i, run)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
+
+// runBatchCall makes one call of a batch in a batch slot, recovering a panic
+// into its error
+func runBatchCall(i int, run func(i int) error) (err error) {
+	batchSlots <- struct{}{}
+	defer func() { <-batchSlots }()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("simulation panicked: %v", r)
+		}
+	}()
+	return run(i)
+}
+
+// runMonteCarloIteration runs one seeded sample of a batch
+func runMonteCarloIteration(input *SimulationInput, seed int64, burstOffset int, cacheHitVariance float64) (monteCarloSample, error) {
+	run := *input
+	run.Workload.Seed = &seed
+	engine := NewEngine(&run)
+	engine.burstOffset = burstOffset
+	engine.cacheHitVariance = cacheHitVariance
+
+	output, err := engine.Run()
+	if err != nil {
+		return monteCarloSample{}, err
+	}
+	metrics := output.Metrics
+	availability := 100.0
+	if metrics.TotalRequests > 0 {
+		availability = float64(metrics.SuccessfulRequests) / float64(metrics.TotalRequests) * 100
+	}
+	return monteCarloSample{
+		p95:          metrics.Latency.P95,
+		p99:          metrics.Latency.P99,
+		errorRate:    metrics.ErrorRate * 100,
+		availability: availability,
+		throughput:   metrics.Throughput,
+		cost:         output.CostMetrics.TotalCostUSD,
+	}, nil
+}
+
+// summarizeMonteCarlo builds the distributions of a batch and, with an SLA,
+// the chance of breaking each of its targets
+func summarizeMonteCarlo(samples []monteCarloSample, sla *SLAConfig, confidence float64, seed int64) *MonteCarloResult {
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	metric := func(value func(monteCarloSample) float64) Distribution {
+		values := make([]float64, len(samples))
+		for i, sample := range samples {
+			values[i] = value(sample)
+		}
+		return newDistribution(values, z)
+	}
+
+	result := &MonteCarloResult{
+		Iterations:      len(samples),
+		ConfidenceLevel: confidence,
+		P95Latency:      metric(func(s monteCarloSample) float64 { return s.p95 }),
+		P99Latency:      metric(func(s monteCarloSample) float64 { return s.p99 }),
+		ErrorRate:       metric(func(s monteCarloSample) float64 { return s.errorRate }),
+		Throughput:      metric(func(s monteCarloSample) float64 { return s.throughput }),
+		Cost:            metric(func(s monteCarloSample) float64 { return s.cost }),
+		Seed:            seed,
+	}
+	if sla == nil {
+		return result
+	}
+
+	// Each target with a check telling whether a run breaks it
+	targets := []struct {
+		name     string
+		target   float64
+		breached func(monteCarloSample) bool
+	}{
+		{"p95LatencyMs", sla.P95LatencyMs, func(s monteCarloSample) bool { return s.p95 > sla.P95LatencyMs }},
+		{"p99LatencyMs", sla.P99LatencyMs, func(s monteCarloSample) bool { return s.p99 > sla.P99LatencyMs }},
+		{"errorRatePercent", sla.ErrorRatePercent, func(s monteCarloSample) bool { return s.errorRate > sla.ErrorRatePercent }},
+		{"availabilityPercent", sla.AvailabilityPercent, func(s monteCarloSample) bool { return s.availability < sla.AvailabilityPercent }},
+		{"minThroughputRPS", sla.MinThroughputRPS, func(s monteCarloSample) bool { return s.throughput < sla.MinThroughputRPS }},
+	}
+
+	result.SLABreaches = make(map[string]BreachProbability)
+	anyBreaches := 0
+	for _, sample := range samples {
+		breached := false
+		for _, t := range targets {
+			breached = breached || (t.target > 0 && t.breached(sample))
+		}
+		if breached {
+			anyBreaches++
+		}
+	}
+	for _, t := range targets {
+		if t.target <= 0 {
+			continue
+		}
+		breaches := 0
+		for _, sample := range samples {
+			if t.breached(sample) {
+				breaches++
+			}
+		}
+		result.SLABreaches[t.name] = newBreachProbability(t.target, breaches, len(samples), z)
+	}
+	if len(result.SLABreaches) > 0 {
+		overall := newBreachProbability(0, anyBreaches, len(samples), z)
+		result.AnySLABreach = &overall
+	}
+	return result
+}
+
+// newDistribution summarizes values, with a normal confidence interval of
+// the mean for critical value z
+func newDistribution(values []float64, z float64) Distribution {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+
+	mean := 0.0
+	for _, v := range sorted {
+		mean += v
+	}
+	mean /= n
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - mean) * (v - mean)
+	}
+	if len(sorted) > 1 {
+		variance /= n - 1
+	}
+	stdDev := math.Sqrt(variance)
+	margin := z * stdDev / math.Sqrt(n)
+
+	round := func(v float64) float64 { return math.Round(v*1000) / 1000 }
+	return Distribution{
+		Mean:   round(mean),
+		StdDev: round(stdDev),
+		Min:    round(sorted[0]),
+		P5:     round(percentileOf(sorted, 0.05)),
+		P50:    round(percentileOf(sorted, 0.50)),
+		P95:    round(percentileOf(sorted, 0.95)),
+		Max:    round(sorted[len(sorted)-1]),
+		CILow:  round(mean - margin),
+		CIHigh: round(mean + margin),
+	}
+}
+
+// percentileOf interpolates a percentile of sorted values
+func percentileOf(sorted []float64, p float64) float64 {
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := minInt(lower+1, len(sorted)-1)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// newBreachProbability is breaches out of n runs with its Wilson score
+// interval for critical value z, which stays within [0, 1] even at 0 or n
+func newBreachProbability(target float64, breaches, n int, z float64) BreachProbability {
+	p := float64(breaches) / float64(n)
+	z2 := z * z
+	center := (p + z2/(2*float64(n))) / (1 + z2/float64(n))
+	margin := z / (1 + z2/float64(n)) * math.Sqrt(p*(1-p)/float64(n)+z2/(4*float64(n)*float64(n)))
+
+	round := func(v float64) float64 { return math.Round(v*10000) / 10000 }
+	return BreachProbability{
+		Target:      target,
+		Breaches:    breaches,
+		Probability: round(p),
+		CILow:       round(math.Max(0, center-margin)),
+		CIHigh:      round(math.Min(1, center+margin)),
+	}
+}
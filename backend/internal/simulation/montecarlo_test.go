@@ -0,0 +1,28 @@
+package simulation
+
+import (
+	"errors"
+	"testing"
+)
+
+// A panicking call fails on its own, and every batch slot is given back
+func TestRunBatchRecoversPanics(t *testing.T) {
+	failed := errors.New("failed")
+	for round := 0; round < 2*cap(batchSlots); round++ {
+		errs := runBatch(3, 0, func(i int) error {
+			switch i {
+			case 0:
+				panic("engine bug")
+			case 1:
+				return failed
+			}
+			return nil
+		})
+		if errs[0] == nil || errs[1] != failed || errs[2] != nil {
+			t.Fatalf("round %d: errs = %v", round, errs)
+		}
+	}
+	if len(batchSlots) != 0 {
+		t.Fatalf("%d batch slots still held", len(batchSlots))
+	}
+}
@@ -0,0 +1,447 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Optimization limits
+const (
+	DefaultMaxCandidates      = 200
+	MaxOptimizationCandidates = 1000
+)
+
+// ReplicaRange is the replica counts a node may run with
+type ReplicaRange struct {
+	Min  int `json:"min"`
+	Max  int `json:"max"`
+	Step int `json:"step,omitempty"` // Default 1
+}
+
+// OptimizationSpace lists the knobs the optimizer may turn
+type OptimizationSpace struct {
+	Replicas         map[string]ReplicaRange `json:"replicas,omitempty"`         // Node -> replica counts to try
+	InstanceTypes    map[string][]string     `json:"instanceTypes,omitempty"`    // Node -> instance types to try
+	InstanceFamilies map[string][]string     `json:"instanceFamilies,omitempty"` // Node -> families ("m5", "r5") whose every size is tried
+	OptionalCaches   []string                `json:"optionalCaches,omitempty"`   // Caches that may be dropped, their callers going straight to what they front
+	MaxCandidates    int                     `json:"maxCandidates,omitempty"`    // Designs to simulate (default 200); larger spaces are sampled
+	Concurrency      int                     `json:"concurrency,omitempty"`      // Runs in flight at once (default: as many as the server allows)
+}
+
+// ConfigChange is one knob setting of a design, relative to the submitted one
+type ConfigChange struct {
+	NodeID string      `json:"nodeId"`
+	Field  string      `json:"field"` // "replicas", "instanceType" or "enabled"
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+}
+
+// DesignPoint is one simulated design
+type DesignPoint struct {
+	Changes       []ConfigChange `json:"changes"`
+	CostUSD       float64        `json:"costUsd"`
+	P99Latency    float64        `json:"p99LatencyMs"`
+	ErrorRate     float64        `json:"errorRatePercent"`
+	MeetsSLA      bool           `json:"meetsSla"`
+	SLAViolations []string       `json:"slaViolations"`
+}
+
+// OptimizationResult is the outcome of a design search
+type OptimizationResult struct {
+	SpaceSize   int           `json:"spaceSize"` // Designs in the search space
+	Evaluated   int           `json:"evaluated"` // Designs simulated, baseline included
+	Baseline    DesignPoint   `json:"baseline"`
+	Frontier    []DesignPoint `json:"frontier"`              // Pareto-optimal on cost, P99 and error rate, cheapest first
+	Recommended *DesignPoint  `json:"recommended,omitempty"` // Cheapest design meeting the SLA
+	Seed        int64         `json:"seed"`                  // Every design runs with this seed, so they see the same traffic
+}
+
+// optimizationKnob is one setting the search varies and the values it tries
+type optimizationKnob struct {
+	nodeID  string
+	field   string
+	current interface{}
+	values  []interface{}
+}
+
+// Optimize searches the space for the cheapest designs that keep latency and
+// errors down. Small spaces are searched exhaustively; larger ones are
+// sampled at random (seeded by the workload seed) up to maxCandidates.
+func Optimize(input *SimulationInput, space OptimizationSpace) (*OptimizationResult, error) {
+	if space.MaxCandidates == 0 {
+		space.MaxCandidates = DefaultMaxCandidates
+	}
+	if space.MaxCandidates < 1 || space.MaxCandidates > MaxOptimizationCandidates {
+		return nil, fmt.Errorf("maxCandidates must be between 1 and %d", MaxOptimizationCandidates)
+	}
+	knobs, err := optimizationKnobs(input, space)
+	if err != nil {
+		return nil, err
+	}
+	if len(knobs) == 0 {
+		return nil, fmt.Errorf("nothing to optimize: give replicas, instance types or optional caches")
+	}
+
+	engine := NewEngine(input)
+	seed := engine.Seed()
+	base := *input
+	base.Workload.Seed = &seed
+	base.Workload.Shape = base.Workload.Shape.normalized()
+
+	spaceSize := 1
+	for _, knob := range knobs {
+		if spaceSize > math.MaxInt32/len(knob.values) {
+			spaceSize = math.MaxInt32
+			break
+		}
+		spaceSize *= len(knob.values)
+	}
+	candidates := candidateDesigns(knobs, spaceSize, space.MaxCandidates, rand.New(rand.NewSource(seed)))
+
+	// The submitted design runs first, then every candidate
+	points := make([]DesignPoint, len(candidates)+1)
+	errs := runBatch(len(points), space.Concurrency, func(i int) (err error) {
+		design := []int(nil)
+		if i > 0 {
+			design = candidates[i-1]
+		}
+		points[i], err = evaluateDesign(&base, knobs, design)
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &OptimizationResult{
+		SpaceSize: spaceSize,
+		Evaluated: len(points),
+		Baseline:  points[0],
+		Frontier:  paretoFrontier(points),
+		Seed:      seed,
+	}
+	for i := range result.Frontier {
+		if result.Frontier[i].MeetsSLA {
+			result.Recommended = &result.Frontier[i]
+			break
+		}
+	}
+	return result, nil
+}
+
+// optimizationKnobs turns the space into knobs in a stable order (by node,
+// then field), checking every node, instance type and cache it names
+func optimizationKnobs(input *SimulationInput, space OptimizationSpace) ([]optimizationKnob, error) {
+	nodes := make(map[string]SimNode, len(input.Nodes))
+	for _, node := range input.Nodes {
+		nodes[node.ID] = node
+	}
+	lookup := func(nodeID string) (SimNode, error) {
+		node, ok := nodes[nodeID]
+		if !ok {
+			return node, fmt.Errorf("unknown node %q", nodeID)
+		}
+		return node, nil
+	}
+
+	knobs := []optimizationKnob{}
+	replicaNodes := make([]string, 0, len(space.Replicas))
+	for nodeID := range space.Replicas {
+		replicaNodes = append(replicaNodes, nodeID)
+	}
+	sort.Strings(replicaNodes)
+	for _, nodeID := range replicaNodes {
+		node, err := lookup(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		r := space.Replicas[nodeID]
+		step := r.Step
+		if step <= 0 {
+			step = 1
+		}
+		if r.Min < 1 || r.Max < r.Min {
+			return nil, fmt.Errorf("replicas of %s: need 1 <= min <= max", nodeID)
+		}
+		knob := optimizationKnob{nodeID: nodeID, field: "replicas", current: getInt(node.Data.Config, "replicas", 1)}
+		for replicas := r.Min; replicas <= r.Max; replicas += step {
+			knob.values = append(knob.values, replicas)
+		}
+		knobs = append(knobs, knob)
+	}
+
+	instanceNodes := make(map[string][]string)
+	for nodeID, types := range space.InstanceTypes {
+		instanceNodes[nodeID] = append(instanceNodes[nodeID], types...)
+	}
+	// Families are expanded in a stable order so the knob's values are too
+	familyNodes := make([]string, 0, len(space.InstanceFamilies))
+	for nodeID := range space.InstanceFamilies {
+		familyNodes = append(familyNodes, nodeID)
+	}
+	sort.Strings(familyNodes)
+	for _, nodeID := range familyNodes {
+		families := space.InstanceFamilies[nodeID]
+		node, err := lookup(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, family := range families {
+			types := instanceFamilyTypes(input.hardware(), node.Data.NodeType, family)
+			if len(types) == 0 {
+				return nil, fmt.Errorf("no %s instance types known for %s", family, nodeID)
+			}
+			instanceNodes[nodeID] = append(instanceNodes[nodeID], types...)
+		}
+	}
+	typedNodes := make([]string, 0, len(instanceNodes))
+	for nodeID := range instanceNodes {
+		typedNodes = append(typedNodes, nodeID)
+	}
+	sort.Strings(typedNodes)
+	for _, nodeID := range typedNodes {
+		node, err := lookup(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		knob := optimizationKnob{nodeID: nodeID, field: "instanceType", current: getString(node.Data.Config, "instanceType", "")}
+		seen := make(map[string]bool)
+		for _, instanceType := range instanceNodes[nodeID] {
+			if _, ok := input.hardware().Instance(instanceType); !ok {
+				return nil, fmt.Errorf("unknown instance type %q for %s", instanceType, nodeID)
+			}
+			if !seen[instanceType] {
+				seen[instanceType] = true
+				knob.values = append(knob.values, instanceType)
+			}
+		}
+		knobs = append(knobs, knob)
+	}
+
+	caches := append([]string(nil), space.OptionalCaches...)
+	sort.Strings(caches)
+	for i, nodeID := range caches {
+		node, err := lookup(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		if !isCaching(node.Data.NodeType) {
+			return nil, fmt.Errorf("%s is not a cache", nodeID)
+		}
+		if i > 0 && caches[i-1] == nodeID {
+			continue
+		}
+		knobs = append(knobs, optimizationKnob{nodeID: nodeID, field: "enabled", current: true, values: []interface{}{true, false}})
+	}
+	return knobs, nil
+}
+
+// instanceFamilyTypes lists the known sizes of an instance family for a node
+// type (db.* for databases, cache.* for caches), cheapest first
+func instanceFamilyTypes(hw HardwareProvider, nodeType, family string) []string {
+	prefix, component := family+".", "api_server"
+	switch {
+	case isDatabase(nodeType):
+		prefix, component = "db."+prefix, "database_sql"
+	case isCache(nodeType):
+		prefix, component = "cache."+prefix, "cache_redis"
+	}
+	types := []string{}
+	for _, instanceType := range hw.InstanceTypes(component) {
+		if strings.HasPrefix(instanceType, prefix) {
+			types = append(types, instanceType)
+		}
+	}
+	return types
+}
+
+// candidateDesigns picks the designs to simulate, each a value index per
+// knob: all of them when they fit in limit, otherwise a random sample
+func candidateDesigns(knobs []optimizationKnob, spaceSize, limit int, r *rand.Rand) [][]int {
+	design := func(n int) []int {
+		choice := make([]int, len(knobs))
+		for k := len(knobs) - 1; k >= 0; k-- {
+			choice[k] = n % len(knobs[k].values)
+			n /= len(knobs[k].values)
+		}
+		return choice
+	}
+	if spaceSize <= limit {
+		designs := make([][]int, spaceSize)
+		for n := range designs {
+			designs[n] = design(n)
+		}
+		return designs
+	}
+
+	designs := [][]int{}
+	seen := make(map[string]bool)
+	for attempts := 0; len(designs) < limit && attempts < limit*10; attempts++ {
+		choice := make([]int, len(knobs))
+		for k := range knobs {
+			choice[k] = r.Intn(len(knobs[k].values))
+		}
+		key := fmt.Sprint(choice)
+		if !seen[key] {
+			seen[key] = true
+			designs = append(designs, choice)
+		}
+	}
+	return designs
+}
+
+// evaluateDesign simulates the input with a design's knob settings (nil runs
+// it as submitted)
+func evaluateDesign(input *SimulationInput, knobs []optimizationKnob, design []int) (DesignPoint, error) {
+	changes := []ConfigChange{}
+	for k, choice := range design {
+		knob := knobs[k]
+		if value := knob.values[choice]; value != knob.current {
+			changes = append(changes, ConfigChange{NodeID: knob.nodeID, Field: knob.field, From: knob.current, To: value})
+		}
+	}
+
+	run := applyDesign(input, changes)
+	output, err := NewEngine(run).Run()
+	if err != nil {
+		return DesignPoint{}, fmt.Errorf("simulating %v: %w", changes, err)
+	}
+	return DesignPoint{
+		Changes:       changes,
+		CostUSD:       output.CostMetrics.TotalCostUSD,
+		P99Latency:    output.Metrics.Latency.P99,
+		ErrorRate:     math.Round(output.Metrics.ErrorRate*10000) / 100,
+		MeetsSLA:      len(output.SLAViolations) == 0,
+		SLAViolations: output.SLAViolations,
+	}, nil
+}
+
+// applyDesign returns a copy of the input with the changes made. Configs of
+// changed nodes are copied, so the input itself is never modified. A dropped
+// cache's callers are wired straight to the nodes behind it.
+func applyDesign(input *SimulationInput, changes []ConfigChange) *SimulationInput {
+	run := *input
+	run.Nodes = append([]SimNode(nil), input.Nodes...)
+	dropped := make(map[string]bool)
+	for _, change := range changes {
+		if change.Field == "enabled" {
+			dropped[change.NodeID] = change.To == false
+			continue
+		}
+		for i := range run.Nodes {
+			if run.Nodes[i].ID != change.NodeID {
+				continue
+			}
+			config := make(map[string]interface{}, len(run.Nodes[i].Data.Config)+1)
+			for key, value := range run.Nodes[i].Data.Config {
+				config[key] = value
+			}
+			switch to := change.To.(type) {
+			case int:
+				config[change.Field] = float64(to) // As decoded from JSON
+			default:
+				config[change.Field] = to
+			}
+			run.Nodes[i].Data.Config = config
+		}
+	}
+	if len(dropped) == 0 {
+		return &run
+	}
+
+	nodes := run.Nodes[:0:0]
+	for _, node := range run.Nodes {
+		if !dropped[node.ID] {
+			nodes = append(nodes, node)
+		}
+	}
+	run.Nodes = nodes
+	run.Edges = bypassNodes(input.Edges, dropped)
+	return &run
+}
+
+// bypassNodes removes the edges of dropped nodes, connecting each caller of a
+// dropped node to each node it called (through chains of dropped nodes too)
+func bypassNodes(edges []SimEdge, dropped map[string]bool) []SimEdge {
+	outgoing := make(map[string][]SimEdge)
+	for _, edge := range edges {
+		outgoing[edge.Source] = append(outgoing[edge.Source], edge)
+	}
+	// targets follows a dropped node's edges to the kept nodes behind it
+	var targets func(nodeID string, visiting map[string]bool) []string
+	targets = func(nodeID string, visiting map[string]bool) []string {
+		if visiting[nodeID] {
+			return nil
+		}
+		visiting[nodeID] = true
+		result := []string{}
+		for _, edge := range outgoing[nodeID] {
+			if dropped[edge.Target] {
+				result = append(result, targets(edge.Target, visiting)...)
+			} else {
+				result = append(result, edge.Target)
+			}
+		}
+		return result
+	}
+
+	kept := []SimEdge{}
+	linked := make(map[string]bool)
+	for _, edge := range edges {
+		if dropped[edge.Source] {
+			continue
+		}
+		if !dropped[edge.Target] {
+			kept = append(kept, edge)
+			linked[edge.Source+"->"+edge.Target] = true
+			continue
+		}
+		for _, target := range targets(edge.Target, map[string]bool{edge.Source: true}) {
+			key := edge.Source + "->" + target
+			if linked[key] {
+				continue
+			}
+			linked[key] = true
+			bypass := edge
+			bypass.ID = edge.ID + "-bypass-" + target
+			bypass.Target = target
+			kept = append(kept, bypass)
+		}
+	}
+	return kept
+}
+
+// paretoFrontier keeps the designs no other design beats on cost, P99 and
+// error rate at once, cheapest first
+func paretoFrontier(points []DesignPoint) []DesignPoint {
+	dominates := func(a, b DesignPoint) bool {
+		noWorse := a.CostUSD <= b.CostUSD && a.P99Latency <= b.P99Latency && a.ErrorRate <= b.ErrorRate
+		better := a.CostUSD < b.CostUSD || a.P99Latency < b.P99Latency || a.ErrorRate < b.ErrorRate
+		return noWorse && better
+	}
+
+	frontier := []DesignPoint{}
+	for i, point := range points {
+		dominated := false
+		for j, other := range points {
+			if i != j && dominates(other, point) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, point)
+		}
+	}
+	sort.SliceStable(frontier, func(i, j int) bool {
+		if frontier[i].CostUSD != frontier[j].CostUSD {
+			return frontier[i].CostUSD < frontier[j].CostUSD
+		}
+		return frontier[i].P99Latency < frontier[j].P99Latency
+	})
+	return frontier
+}
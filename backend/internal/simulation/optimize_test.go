@@ -0,0 +1,135 @@
+package simulation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParetoFrontier(t *testing.T) {
+	points := []DesignPoint{
+		{CostUSD: 3, P99Latency: 10},
+		{CostUSD: 1, P99Latency: 50, ErrorRate: 5},
+		{CostUSD: 2, P99Latency: 60, ErrorRate: 5}, // Costs more than the second for nothing
+		{CostUSD: 2, P99Latency: 20},
+		{CostUSD: 3, P99Latency: 10, ErrorRate: 1}, // The first without errors
+	}
+	got := paretoFrontier(points)
+	want := []DesignPoint{points[1], points[3], points[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// optimizeInput overloads a single API server, in front of a database with
+// room to spare, that the optimizer may scale out
+func optimizeInput() *SimulationInput {
+	seed := int64(1)
+	input := validationInput()
+	input.Nodes[2].Data.Config["replicas"] = 20
+	input.Workload.RPS = 3000
+	input.Workload.Seed = &seed
+	input.SLAConfig = &SLAConfig{ErrorRatePercent: 1}
+	return input
+}
+
+// The recommendation is the cheapest design meeting the SLA; every cheaper
+// design on the frontier misses it
+func TestOptimizeRecommendsCheapestPassingDesign(t *testing.T) {
+	space := OptimizationSpace{Replicas: map[string]ReplicaRange{"api": {Min: 1, Max: 8}}}
+	result, err := Optimize(optimizeInput(), space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SpaceSize != 8 || result.Evaluated != 9 || result.Seed != 1 {
+		t.Errorf("space %d, evaluated %d, seed %d, want 8, 9 and 1", result.SpaceSize, result.Evaluated, result.Seed)
+	}
+	if result.Baseline.MeetsSLA || len(result.Baseline.Changes) != 0 {
+		t.Fatalf("baseline %+v, want the submitted design missing the SLA", result.Baseline)
+	}
+	recommended := result.Recommended
+	if recommended == nil || !recommended.MeetsSLA {
+		t.Fatalf("recommended %+v, want a design meeting the SLA", recommended)
+	}
+	if changes := recommended.Changes; len(changes) != 1 || changes[0].NodeID != "api" || changes[0].From != 1 || changes[0].To.(int) >= 8 {
+		t.Errorf("recommended changes %+v, want fewer than 8 api replicas", changes)
+	}
+
+	for i, point := range result.Frontier {
+		if i > 0 && point.CostUSD < result.Frontier[i-1].CostUSD {
+			t.Errorf("frontier not cheapest first: %+v", result.Frontier)
+		}
+		if point.CostUSD < recommended.CostUSD && point.MeetsSLA {
+			t.Errorf("%+v meets the SLA for less than the recommendation", point)
+		}
+	}
+
+	again, err := Optimize(optimizeInput(), space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, result) {
+		t.Error("same input and space: want the same result")
+	}
+}
+
+// Spaces larger than maxCandidates are sampled
+func TestOptimizeSamplesLargeSpaces(t *testing.T) {
+	space := OptimizationSpace{
+		Replicas:      map[string]ReplicaRange{"api": {Min: 1, Max: 20}},
+		InstanceTypes: map[string][]string{"api": {"t3.medium", "c5.large", "m5.large"}},
+		MaxCandidates: 10,
+	}
+	result, err := Optimize(optimizeInput(), space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SpaceSize != 60 || result.Evaluated != 11 {
+		t.Errorf("space %d with %d evaluated, want 60 and 11", result.SpaceSize, result.Evaluated)
+	}
+}
+
+func TestOptimizeRejectsBadSpaces(t *testing.T) {
+	for name, space := range map[string]OptimizationSpace{
+		"empty":          {},
+		"unknown node":   {Replicas: map[string]ReplicaRange{"web": {Min: 1, Max: 2}}},
+		"bad range":      {Replicas: map[string]ReplicaRange{"api": {Min: 3, Max: 2}}},
+		"unknown type":   {InstanceTypes: map[string][]string{"api": {"t9.huge"}}},
+		"not a cache":    {OptionalCaches: []string{"db"}},
+		"too many":       {Replicas: map[string]ReplicaRange{"api": {Min: 1, Max: 2}}, MaxCandidates: MaxOptimizationCandidates + 1},
+		"unknown family": {InstanceFamilies: map[string][]string{"api": {"zz9"}}},
+	} {
+		if _, err := Optimize(optimizeInput(), space); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+// Dropping a cache wires its callers to what it fronted, leaving the
+// submitted input untouched
+func TestApplyDesignBypassesDroppedCache(t *testing.T) {
+	input := validationInput()
+	input.Nodes = append(input.Nodes, SimNode{ID: "cache", Data: SimNodeData{NodeType: "cache_redis", Config: map[string]interface{}{}}})
+	input.Edges = []SimEdge{
+		{ID: "e1", Source: "client", Target: "api"},
+		{ID: "e2", Source: "api", Target: "cache"},
+		{ID: "e3", Source: "cache", Target: "db"},
+	}
+
+	run := applyDesign(input, []ConfigChange{
+		{NodeID: "cache", Field: "enabled", From: true, To: false},
+		{NodeID: "api", Field: "replicas", From: 1, To: 3},
+	})
+	edges := [][2]string{}
+	for _, edge := range run.Edges {
+		edges = append(edges, [2]string{edge.Source, edge.Target})
+	}
+	if want := [][2]string{{"client", "api"}, {"api", "db"}}; !reflect.DeepEqual(edges, want) || len(run.Nodes) != 3 {
+		t.Errorf("edges %v over %d nodes, want %v over 3", edges, len(run.Nodes), want)
+	}
+	if replicas := run.Nodes[1].Data.Config["replicas"]; replicas != 3.0 {
+		t.Errorf("api replicas %v, want 3", replicas)
+	}
+	if len(input.Nodes) != 4 || len(input.Edges) != 3 || len(input.Nodes[1].Data.Config) != 0 {
+		t.Error("the submitted input was modified")
+	}
+}
@@ -0,0 +1,165 @@
+package simulation
+
+import (
+	"sort"
+	"strings"
+)
+
+// HardwareProvider supplies the specs and prices the simulation sizes and
+// bills nodes with; SimulationInput.Hardware picks one per input. Lookups
+// report false for types the provider doesn't know; implementations must be
+// safe for concurrent use, since batches run simulations in parallel.
+type HardwareProvider interface {
+	// Instance returns the specs and hourly price of an instance type
+	Instance(instanceType string) (InstancePerformance, bool)
+	// InstanceTypes lists the instance types of a catalog component
+	// (api_server, database_sql, cache_redis), cheapest first
+	InstanceTypes(componentType string) []string
+	Storage(storageType string) (StoragePerformance, bool)
+	LoadBalancer(lbType string) (LoadBalancerPerformance, bool)
+	Queue(queueType string) (QueuePerformance, bool)
+	CDN(cdnType string) (CDNPerformance, bool)
+	Search(searchType string) (SearchPerformance, bool)
+}
+
+// hardware returns the provider an input is simulated with
+func (input *SimulationInput) hardware() HardwareProvider {
+	if input == nil {
+		return OfflineHardware()
+	}
+	return orOffline(input.Hardware)
+}
+
+// orOffline returns the provider, or the built-in tables when it is nil
+func orOffline(provider HardwareProvider) HardwareProvider {
+	if provider == nil {
+		return OfflineHardware()
+	}
+	return provider
+}
+
+// offlineLoadBalancers is the load balancer types known without the catalog
+var offlineLoadBalancers = map[string]LoadBalancerPerformance{
+	"alb":     {CapacityRPS: 50000, LatencyMs: 5, HourlyCostUSD: 0.0225},
+	"nlb":     {CapacityRPS: 500000, LatencyMs: 1, HourlyCostUSD: 0.0225},
+	"classic": {CapacityRPS: 25000, LatencyMs: 10, HourlyCostUSD: 0.025},
+}
+
+// offlineQueues is the queue types known without the catalog
+var offlineQueues = map[string]QueuePerformance{
+	"sqs-standard":   {ThroughputRPS: 3000, LatencyMs: 20, CostPerMillionUSD: 0.40}, // SQS batch limit
+	"sqs-fifo":       {ThroughputRPS: 3000, LatencyMs: 20, CostPerMillionUSD: 0.50},
+	"kafka-small":    {ThroughputRPS: 10000, LatencyMs: 5, CostPerMillionUSD: 0.10},
+	"kafka-medium":   {ThroughputRPS: 50000, LatencyMs: 3, CostPerMillionUSD: 0.15},
+	"kafka-large":    {ThroughputRPS: 200000, LatencyMs: 2, CostPerMillionUSD: 0.20},
+	"kafka-standard": {ThroughputRPS: 100000, LatencyMs: 5, CostPerMillionUSD: 0.15},
+	"kafka-premium":  {ThroughputRPS: 100000, LatencyMs: 5, CostPerMillionUSD: 0.20},
+	"rabbitmq-basic": {ThroughputRPS: 50000, LatencyMs: 10, CostPerMillionUSD: 0.10},
+	"rabbitmq-ha":    {ThroughputRPS: 50000, LatencyMs: 10, CostPerMillionUSD: 0.20},
+}
+
+// offlineCDNs is the CDN types known without the catalog
+var offlineCDNs = map[string]CDNPerformance{
+	"cloudfront-basic":      {LatencyMs: 50, CostPerGBUSD: 0.085},
+	"cloudfront-premium":    {LatencyMs: 20, CostPerGBUSD: 0.12},
+	"cloudfront-enterprise": {LatencyMs: 10, CostPerGBUSD: 0.15},
+}
+
+// offlineSearch is the search cluster types known without the catalog
+var offlineSearch = map[string]SearchPerformance{
+	"es-small":  {QueriesPerSec: 500, LatencyMs: 50, HourlyCostUSD: 0.12},
+	"es-medium": {QueriesPerSec: 2000, LatencyMs: 30, HourlyCostUSD: 0.48},
+	"es-large":  {QueriesPerSec: 5000, LatencyMs: 20, HourlyCostUSD: 0.96},
+}
+
+// offlineHardware answers from the built-in tables
+type offlineHardware struct{}
+
+// OfflineHardware returns the provider backed by the built-in tables, used
+// when no catalog is configured and for types the catalog lacks
+func OfflineHardware() HardwareProvider {
+	return offlineHardware{}
+}
+
+func (offlineHardware) Instance(instanceType string) (InstancePerformance, bool) {
+	perf, ok := offlineInstances[instanceType]
+	return perf, ok
+}
+
+func (offlineHardware) InstanceTypes(componentType string) []string {
+	types := []string{}
+	for instanceType := range offlineInstances {
+		if instanceComponent(instanceType) == componentType {
+			types = append(types, instanceType)
+		}
+	}
+	sortByHourlyCost(types, offlineInstances)
+	return types
+}
+
+func (offlineHardware) Storage(storageType string) (StoragePerformance, bool) {
+	perf, ok := offlineStorage[storageType]
+	return perf, ok
+}
+
+func (offlineHardware) LoadBalancer(lbType string) (LoadBalancerPerformance, bool) {
+	perf, ok := offlineLoadBalancers[lbType]
+	return perf, ok
+}
+
+func (offlineHardware) Queue(queueType string) (QueuePerformance, bool) {
+	perf, ok := offlineQueues[queueType]
+	return perf, ok
+}
+
+func (offlineHardware) CDN(cdnType string) (CDNPerformance, bool) {
+	perf, ok := offlineCDNs[cdnType]
+	return perf, ok
+}
+
+func (offlineHardware) Search(searchType string) (SearchPerformance, bool) {
+	perf, ok := offlineSearch[searchType]
+	return perf, ok
+}
+
+// instanceComponent is the catalog component an instance type belongs to,
+// going by its name: db.* for databases, cache.* for caches
+func instanceComponent(instanceType string) string {
+	switch {
+	case strings.HasPrefix(instanceType, "db."):
+		return "database_sql"
+	case strings.HasPrefix(instanceType, "cache."):
+		return "cache_redis"
+	default:
+		return "api_server"
+	}
+}
+
+// instanceFamily is the family of an instance type ("m5" for db.m5.large)
+func instanceFamily(instanceType string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(instanceType, "db."), "cache.")
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// instanceClass is the letter of an instance family saying what it is built
+// for: t (burstable), m (general purpose), c (compute), r (memory)
+func instanceClass(instanceType string) string {
+	if family := instanceFamily(instanceType); family != "" {
+		return family[:1]
+	}
+	return ""
+}
+
+// sortByHourlyCost orders instance types cheapest first, then by name
+func sortByHourlyCost(types []string, specs map[string]InstancePerformance) {
+	sort.Slice(types, func(i, j int) bool {
+		a, b := specs[types[i]].HourlyCostUSD, specs[types[j]].HourlyCostUSD
+		if a != b {
+			return a < b
+		}
+		return types[i] < types[j]
+	})
+}
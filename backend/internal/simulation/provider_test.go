@@ -0,0 +1,55 @@
+package simulation
+
+import (
+	"sync"
+	"testing"
+)
+
+// customHardware is the built-in tables plus one instance type of its own
+type customHardware struct {
+	HardwareProvider
+}
+
+func (customHardware) Instance(instanceType string) (InstancePerformance, bool) {
+	if instanceType == "x9.custom" {
+		return InstancePerformance{VCPU: 64, MemoryGB: 256, NetworkGbps: 25, HourlyCostUSD: 3}, true
+	}
+	return OfflineHardware().Instance(instanceType)
+}
+
+// Engines running side by side each size nodes with their own input's hardware
+func TestEnginesUseTheirOwnHardware(t *testing.T) {
+	inputs := map[string]*SimulationInput{"offline": validationInput(), "custom": validationInput()}
+	inputs["custom"].Hardware = customHardware{OfflineHardware()}
+	capacity := map[string]float64{}
+	cost := map[string]float64{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, input := range inputs {
+		input.Nodes[1].Data.Config["instanceType"] = "x9.custom"
+		wg.Add(1)
+		go func(name string, input *SimulationInput) {
+			defer wg.Done()
+			engine := NewEngine(input)
+			output, err := engine.Run()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			capacity[name] = engine.state.NodeStates["api"].BaseCapacityRPS
+			cost[name] = output.CostMetrics.TotalCostUSD
+			mu.Unlock()
+		}(name, input)
+	}
+	wg.Wait()
+
+	if fallback, _ := computeInstances.size(OfflineHardware(), "x9.custom"); capacity["offline"] != fallback {
+		t.Errorf("offline: got %v RPS, want the fallback's %v", capacity["offline"], fallback)
+	}
+	if capacity["custom"] <= capacity["offline"] || cost["custom"] <= cost["offline"] {
+		t.Errorf("custom: got %v RPS for $%.4f, want more than offline's %v RPS for $%.4f",
+			capacity["custom"], cost["custom"], capacity["offline"], cost["offline"])
+	}
+}
@@ -72,6 +72,23 @@ var RegionLatencyMatrix = map[string]map[string]float64{
 	},
 }
 
+// Availability zone latency, on top of RegionLatencyMatrix. AZs of a region sit
+// in separate data centers a few km to tens of km apart: crossing AZs costs
+// roughly 1ms round trip, while traffic inside one AZ is already covered by
+// the components' base latency.
+const (
+	SameZoneLatencyMs  = 0.0
+	CrossZoneLatencyMs = 1.0
+)
+
+// GlobalRegion marks edge/global services (DNS, CDN) that aren't placed in a
+// single region: they add no cross-region latency and regional outages skip them
+const GlobalRegion = "global"
+
+// DefaultRegion is the region of nodes with no placement when the workload
+// lists no regions. It is treated as "wherever the caller is".
+const DefaultRegion = "default"
+
 // DataTransferCost defines real-world data transfer costs (USD per GB)
 // Based on AWS pricing as of 2024
 var DataTransferCost = map[string]map[string]float64{
@@ -164,6 +181,55 @@ func GetRegionLatency(sourceRegion, targetRegion string) float64 {
 	return 100.0 // Default cross-region latency
 }
 
+// GetPlacementLatency returns the network latency between two placements:
+// the region latency across regions, the AZ latency within one
+func GetPlacementLatency(sourceRegion, sourceZone, targetRegion, targetZone string) float64 {
+	for _, region := range []string{sourceRegion, targetRegion} {
+		if region == GlobalRegion || region == DefaultRegion {
+			return 0
+		}
+	}
+	if IsCrossRegion(sourceRegion, targetRegion) {
+		return GetRegionLatency(sourceRegion, targetRegion)
+	}
+	if sourceZone != "" && targetZone != "" && sourceZone != targetZone {
+		return CrossZoneLatencyMs
+	}
+	return SameZoneLatencyMs
+}
+
+// IsCrossPlacement checks if traffic between two placements leaves a region or
+// crosses AZs. Unknown AZs are assumed to be the same one.
+func IsCrossPlacement(sourceRegion, sourceZone, targetRegion, targetZone string) bool {
+	return GetPlacementLatency(sourceRegion, sourceZone, targetRegion, targetZone) > 0
+}
+
+// nodePlacement resolves where a node runs. The node's "region" and
+// "availabilityZone" config win; DNS/traffic managers default to global and
+// other nodes without a region are spread round-robin over the workload regions.
+func (e *Engine) nodePlacement(idx int, node SimNode) (region, zone string) {
+	region = getString(node.Data.Config, "region", "")
+	if region == "" && isGlobalTrafficManager(node.Data.NodeType) {
+		region = GlobalRegion
+	}
+	if region == "" {
+		region = DefaultRegion
+		if len(e.config.Regions) > 0 {
+			region = e.config.Regions[idx%len(e.config.Regions)]
+		}
+	}
+	zone = getString(node.Data.Config, "availabilityZone", getString(node.Data.Config, "az", ""))
+	return region, zone
+}
+
+// placedIn reports whether a node runs in a region, and in zone when one is given
+func (n *NodeState) placedIn(region, zone string) bool {
+	if n.Region != region {
+		return false
+	}
+	return zone == "" || n.Zone == zone
+}
+
 // GetDataTransferCost returns the cost per GB for data transfer between regions
 func GetDataTransferCost(sourceRegion, targetRegion string) float64 {
 	// Default to us-east if region not specified
@@ -0,0 +1,92 @@
+package simulation
+
+import "testing"
+
+func TestPlacementLatency(t *testing.T) {
+	tests := []struct {
+		sourceRegion, sourceZone, targetRegion, targetZone string
+		latency                                            float64
+	}{
+		{"us-east", "us-east-1a", "us-east", "us-east-1a", SameZoneLatencyMs},
+		{"us-east", "us-east-1a", "us-east", "us-east-1b", CrossZoneLatencyMs},
+		{"us-east", "", "us-east", "us-east-1b", SameZoneLatencyMs}, // Unknown AZ: assumed the same
+		{"us-east", "us-east-1a", "eu-west", "eu-west-1a", RegionLatencyMatrix["us-east"]["eu-west"]},
+		{GlobalRegion, "", "ap-south", "", 0},
+		{DefaultRegion, "", "us-west", "", 0},
+	}
+	for _, tt := range tests {
+		got := GetPlacementLatency(tt.sourceRegion, tt.sourceZone, tt.targetRegion, tt.targetZone)
+		if got != tt.latency {
+			t.Errorf("%s/%s -> %s/%s: got %vms, want %vms", tt.sourceRegion, tt.sourceZone, tt.targetRegion, tt.targetZone, got, tt.latency)
+		}
+	}
+}
+
+// placementInput balances two APIs in different placements in front of a
+// database; the workload's own region list names none of them
+func placementInput(failures ...FailureInjection) *SimulationInput {
+	seed := int64(1)
+	placed := func(region, zone string) map[string]interface{} {
+		return map[string]interface{}{"region": region, "availabilityZone": zone, "replicas": 4}
+	}
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: placed("us-east", "")}},
+			{ID: "lb", Data: SimNodeData{NodeType: "load_balancer", Config: placed("us-east", "")}},
+			{ID: "east", Data: SimNodeData{NodeType: "api_server", Config: placed("us-east", "us-east-1a")}},
+			{ID: "west", Data: SimNodeData{NodeType: "api_server", Config: placed("eu-west", "eu-west-1a")}},
+			{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: placed("us-east", "us-east-1b")}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "lb"},
+			{ID: "e2", Source: "lb", Target: "east"},
+			{ID: "e3", Source: "lb", Target: "west"},
+			{ID: "e4", Source: "east", Target: "db"},
+			{ID: "e5", Source: "west", Target: "db"},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 5, Seed: &seed, Regions: []string{"ap-south"}, Failures: failures},
+	}
+}
+
+func TestNodesRunWhereTheyArePlaced(t *testing.T) {
+	engine := NewEngine(placementInput())
+	output, err := engine.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if west := engine.state.NodeStates["west"]; west.Region != "eu-west" || west.Zone != "eu-west-1a" {
+		t.Errorf("west placed in %s/%s, want eu-west/eu-west-1a", west.Region, west.Zone)
+	}
+	regions := output.TimeSeries[len(output.TimeSeries)-1].RegionLatencyMap
+	if _, ok := regions["ap-south"]; ok || len(regions) != 2 {
+		t.Errorf("got region latencies %v, want us-east and eu-west only", regions)
+	}
+	// Crossing the Atlantic shows on the hop into west
+	if got, want := engine.hopLatency("lb", "west")-engine.hopLatency("lb", "east"), RegionLatencyMatrix["us-east"]["eu-west"]; got != want {
+		t.Errorf("west is %vms slower than east to reach, want %vms", got, want)
+	}
+}
+
+// Region and AZ failures take down only what runs there
+func TestPlacementFailuresHitOnlyTheirNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure FailureInjection
+		failed  string
+	}{
+		{"region", FailureInjection{Type: FailureRegionFail, Region: "eu-west"}, "west"},
+		{"az", FailureInjection{Type: FailureAZOutage, Region: "us-east", Zone: "us-east-1a"}, "east"},
+	}
+	for _, tt := range tests {
+		output, err := NewEngine(placementInput(tt.failure)).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nodeID := range []string{"client", "lb", "east", "west", "db"} {
+			node := lastTickMetrics(t, output, nodeID)
+			if failed := node.Errors > 0; failed != (nodeID == tt.failed) {
+				t.Errorf("%s outage: %s has %d errors", tt.name, nodeID, node.Errors)
+			}
+		}
+	}
+}
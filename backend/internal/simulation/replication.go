@@ -0,0 +1,291 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Replication modes
+const (
+	ReplicationSync  = "sync"  // A write commits once replicas acknowledge it: no lag, slower writes
+	ReplicationAsync = "async" // Replicas apply writes after the fact: fast writes, lagging reads
+)
+
+// Database replication defaults
+const (
+	DefaultReplicationLagMs = 20.0 // Apply delay of a healthy async replica
+	DefaultFailoverSeconds  = 60   // RDS Multi-AZ fails over in 60-120s
+)
+
+// replicationGroup is a primary database with its standby and read replicas
+type replicationGroup struct {
+	primary         string
+	replicas        []string           // Replica node IDs, in input order
+	sync            map[string]bool    // Replica -> replicates synchronously
+	baseLagMS       map[string]float64 // Replica -> apply delay when keeping up
+	backlog         map[string]float64 // Replica -> writes not applied yet
+	multiAZ         bool               // Synchronous standby in another AZ (not readable)
+	standbyZone     string             // Standby AZ, when known
+	autoFailover    bool
+	failoverSeconds int
+	downSince       int  // Tick the primary went down (0 = up)
+	promoted        bool // The writer endpoint moved to a standby/replica during the current outage
+}
+
+// buildReplicationGroups finds primary databases with a Multi-AZ standby or
+// read replicas. Replicas name their primary with "replicaOf"; without it a
+// replica joins the graph's only primary database.
+func (e *Engine) buildReplicationGroups() []*replicationGroup {
+	configs := make(map[string]map[string]interface{}, len(e.input.Nodes))
+	primaries := []string{}
+	for _, node := range e.input.Nodes {
+		configs[node.ID] = node.Data.Config
+		if state := e.state.NodeStates[node.ID]; state != nil && isDatabase(state.Type) && !state.isReadReplica() {
+			primaries = append(primaries, node.ID)
+		}
+	}
+
+	replicasOf := make(map[string][]string)
+	for _, node := range e.input.Nodes {
+		state := e.state.NodeStates[node.ID]
+		if state == nil || !isDatabase(state.Type) || !state.isReadReplica() {
+			continue
+		}
+		primaryID := getString(node.Data.Config, "replicaOf", "")
+		if primaryID == "" && len(primaries) == 1 {
+			primaryID = primaries[0]
+		}
+		if primary := e.state.NodeStates[primaryID]; primary != nil && isDatabase(primary.Type) && !primary.isReadReplica() {
+			replicasOf[primaryID] = append(replicasOf[primaryID], node.ID)
+		}
+	}
+
+	groups := []*replicationGroup{}
+	for _, primaryID := range primaries {
+		config := configs[primaryID]
+		g := &replicationGroup{
+			primary:         primaryID,
+			replicas:        replicasOf[primaryID],
+			sync:            make(map[string]bool),
+			baseLagMS:       make(map[string]float64),
+			backlog:         make(map[string]float64),
+			multiAZ:         getBool(config, "multiAZ", false) || getString(config, "deployment", "") == "multi-az",
+			standbyZone:     getString(config, "standbyZone", ""),
+			autoFailover:    getBool(config, "autoFailover", true),
+			failoverSeconds: getInt(config, "failoverSeconds", DefaultFailoverSeconds),
+		}
+		if !g.multiAZ && len(g.replicas) == 0 {
+			continue // Single instance: nothing to replicate to or fail over to
+		}
+		for _, replicaID := range g.replicas {
+			g.sync[replicaID] = replicationMode(configs[replicaID], config) == ReplicationSync
+			g.baseLagMS[replicaID] = getFloat(configs[replicaID], "replicationLagMs", DefaultReplicationLagMs)
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// replicationMode resolves a replica's mode: its own "replication" setting,
+// then an explicit "consistency" (strong = sync, eventual = async), then the
+// primary's "replication" setting. Read replicas are async by default.
+func replicationMode(replicaConfig, primaryConfig map[string]interface{}) string {
+	if mode := getString(replicaConfig, "replication", ""); mode == ReplicationSync || mode == ReplicationAsync {
+		return mode
+	}
+	switch getString(replicaConfig, "consistency", "") {
+	case "strong":
+		return ReplicationSync
+	case "eventual":
+		return ReplicationAsync
+	}
+	if getString(primaryConfig, "replication", "") == ReplicationSync {
+		return ReplicationSync
+	}
+	return ReplicationAsync
+}
+
+// applyFailover runs after failures are injected. A failed primary stays
+// down (writes and reads to it fail) for failoverSeconds; then, if its
+// standby or a healthy replica is available, that one is promoted and the
+// primary's endpoint serves again for the rest of the outage.
+func (e *Engine) applyFailover(tick int) {
+	for _, g := range e.replicationGroups {
+		primary := e.state.NodeStates[g.primary]
+		if !primary.Failed {
+			g.downSince, g.promoted = 0, false
+			continue
+		}
+		if g.downSince == 0 {
+			g.downSince = tick
+		}
+		if g.promoted {
+			primary.Failed = false
+			continue
+		}
+		if !g.autoFailover || tick-g.downSince < g.failoverSeconds {
+			continue
+		}
+
+		candidate, ok := e.failoverCandidate(g, tick)
+		if !ok {
+			continue
+		}
+		g.promoted = true
+		primary.Failed = false
+
+		event := FailoverEvent{Tick: tick, NodeID: g.primary, DowntimeSeconds: tick - g.downSince}
+		if candidate == "" {
+			event.Reason = "Multi-AZ standby promoted"
+		} else {
+			// The replica now takes writes and leaves the read pool
+			replica := e.state.NodeStates[candidate]
+			replica.Role = "primary"
+			replica.ReplicaLagMS = 0
+			g.removeReplica(candidate)
+			event.PromotedID = candidate
+			event.Reason = fmt.Sprintf("replica %s promoted", candidate)
+		}
+		e.state.failovers = append(e.state.failovers, event)
+	}
+}
+
+// failoverCandidate picks what to promote: the Multi-AZ standby ("") unless
+// its AZ is down too, otherwise the healthy replica with the least lag
+// (synchronous replicas first, as they lose no writes)
+func (e *Engine) failoverCandidate(g *replicationGroup, tick int) (string, bool) {
+	primary := e.state.NodeStates[g.primary]
+	if g.multiAZ && !e.zoneDown(primary.Region, g.standbyZone, tick) {
+		return "", true
+	}
+
+	best, bestLag := "", math.Inf(1)
+	for _, replicaID := range g.replicas {
+		replica := e.state.NodeStates[replicaID]
+		if !isHealthy(replica) {
+			continue
+		}
+		lag := replica.ReplicaLagMS
+		if g.sync[replicaID] {
+			lag = -1
+		}
+		if lag < bestLag {
+			best, bestLag = replicaID, lag
+		}
+	}
+	return best, best != ""
+}
+
+// zoneDown reports whether an active region or AZ outage covers an AZ of a
+// region. An unknown zone is only down when the whole region is.
+func (e *Engine) zoneDown(region, zone string, tick int) bool {
+	for _, window := range e.activeFaults(tick) {
+		failure := e.config.Failures[window.failure]
+		if (failure.Type != FailureRegionFail && failure.Type != FailureAZOutage) || failure.Region != region {
+			continue
+		}
+		if failure.Zone == "" || (zone != "" && failure.Zone == zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeReplica takes a promoted replica out of the group
+func (g *replicationGroup) removeReplica(replicaID string) {
+	remaining := g.replicas[:0]
+	for _, id := range g.replicas {
+		if id != replicaID {
+			remaining = append(remaining, id)
+		}
+	}
+	g.replicas = remaining
+	delete(g.backlog, replicaID)
+}
+
+// syncCommitLatency is the extra latency of a write on a primary that waits
+// for its synchronous standby/replicas to acknowledge
+func (e *Engine) syncCommitLatency(node *NodeState) float64 {
+	for _, g := range e.replicationGroups {
+		if g.primary != node.ID {
+			continue
+		}
+		commit := 0.0
+		if g.multiAZ {
+			commit = CrossZoneLatencyMs
+		}
+		for _, replicaID := range g.replicas {
+			replica := e.state.NodeStates[replicaID]
+			if !g.sync[replicaID] || !isHealthy(replica) {
+				continue
+			}
+			latency := GetPlacementLatency(node.Region, node.Zone, replica.Region, replica.Zone)
+			if latency == 0 && (node.Zone == "" || replica.Zone == "") {
+				latency = CrossZoneLatencyMs // Synchronous replicas normally sit in another AZ
+			}
+			commit = math.Max(commit, latency)
+		}
+		return commit
+	}
+	return 0
+}
+
+// updateReplication runs after the tick's traffic is served. Async replicas
+// fall behind when the primary writes faster than they can apply (capacity
+// left after serving reads); their reads are counted as potentially stale.
+// Primaries that can't take writes are recorded for the write-unavailability
+// timeline.
+func (e *Engine) updateReplication() {
+	e.tickWriteDown = nil
+	for _, node := range e.orderedNodes() {
+		if isDatabase(node.Type) && !node.isReadReplica() && node.Failed {
+			e.tickWriteDown = append(e.tickWriteDown, node.ID)
+			e.state.writeDowntime[node.ID]++
+		}
+		node.StaleReadRPS = 0
+	}
+
+	for _, g := range e.replicationGroups {
+		primary := e.state.NodeStates[g.primary]
+		writes := 0.0
+		if !primary.Failed && primary.RPSIn > 0 {
+			writes = primary.WriteRPS * math.Max(0, 1-primary.RejectedRPS/primary.RPSIn)
+		}
+
+		for _, replicaID := range g.replicas {
+			replica := e.state.NodeStates[replicaID]
+			if g.sync[replicaID] {
+				replica.ReplicaLagMS = 0
+				continue
+			}
+
+			capacity := replica.CapacityRPS * float64(replica.Replicas)
+			servedReads := math.Max(0, replica.ReadRPS-math.Max(0, replica.RejectedRPS-replica.WriteRPS))
+			applyRate := 0.0
+			if isHealthy(replica) {
+				applyRate = math.Max(0, capacity-servedReads)
+			}
+			g.backlog[replicaID] = math.Max(0, g.backlog[replicaID]+writes-applyRate)
+
+			network := GetPlacementLatency(primary.Region, primary.Zone, replica.Region, replica.Zone)
+			replica.ReplicaLagMS = g.baseLagMS[replicaID] + network + g.backlog[replicaID]/math.Max(1, capacity)*1000
+
+			if isHealthy(replica) {
+				replica.StaleReadRPS = servedReads
+				e.state.StaleReads += int(servedReads)
+			}
+		}
+	}
+}
+
+// writeDowntime reports, per primary database, the seconds it couldn't take writes
+func (e *Engine) writeDowntime() map[string]int {
+	if len(e.state.writeDowntime) == 0 {
+		return nil
+	}
+	downtime := make(map[string]int, len(e.state.writeDowntime))
+	for nodeID, seconds := range e.state.writeDowntime {
+		downtime[nodeID] = seconds
+	}
+	return downtime
+}
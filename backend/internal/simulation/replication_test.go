@@ -0,0 +1,92 @@
+package simulation
+
+import "testing"
+
+// replicationInput sends reads and writes to a primary database, and reads
+// to its replica when one is given
+func replicationInput(primary, replica map[string]interface{}, failures ...FailureInjection) *SimulationInput {
+	primary["replicas"] = 4
+	tier := []SimNode{{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: primary}}}
+	if replica != nil {
+		replica["replicas"], replica["role"], replica["replicaOf"] = 4, "replica", "db"
+		tier = append(tier, SimNode{ID: "replica", Data: SimNodeData{NodeType: "database_postgres", Config: replica}})
+	}
+	input := readWriteInput(tier...)
+	for _, node := range tier {
+		input.Edges = append(input.Edges, SimEdge{ID: "api>" + node.ID, Source: "api", Target: node.ID})
+	}
+	input.Workload.DurationSeconds = 20
+	input.Workload.Failures = failures
+	return input
+}
+
+// A Multi-AZ primary is back taking writes once its standby is promoted; a
+// single instance stays down for the whole outage
+func TestMultiAZFailover(t *testing.T) {
+	outage := FailureInjection{Type: FailureDBFail, NodeID: "db", StartTick: 3}
+
+	output, err := NewEngine(replicationInput(map[string]interface{}{"multiAZ": true, "failoverSeconds": 5}, nil, outage)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failovers := output.Metrics.Failovers
+	if len(failovers) != 1 || failovers[0].Tick != 8 || failovers[0].DowntimeSeconds != 5 || failovers[0].PromotedID != "" {
+		t.Fatalf("got failovers %+v, want the standby promoted at tick 8 after 5s", failovers)
+	}
+	for _, point := range output.TimeSeries {
+		down := len(point.WriteUnavailable) == 1 && point.WriteUnavailable[0] == "db"
+		if want := point.Tick >= 3 && point.Tick < 8; down != want {
+			t.Errorf("tick %d: writes unavailable %v", point.Tick, point.WriteUnavailable)
+		}
+	}
+	if got := output.Metrics.WriteDowntime["db"]; got != 5 {
+		t.Errorf("Multi-AZ: %ds without writes, want 5", got)
+	}
+
+	output, err = NewEngine(replicationInput(map[string]interface{}{}, nil, outage)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Metrics.Failovers) != 0 || output.Metrics.WriteDowntime["db"] != 18 {
+		t.Errorf("single instance: failovers %+v and %ds without writes, want none and 18", output.Metrics.Failovers, output.Metrics.WriteDowntime["db"])
+	}
+}
+
+// Without a standby the least-lagged replica takes over the writes
+func TestReplicaPromotion(t *testing.T) {
+	outage := FailureInjection{Type: FailureDBFail, NodeID: "db", StartTick: 3}
+	output, err := NewEngine(replicationInput(map[string]interface{}{"failoverSeconds": 2}, map[string]interface{}{}, outage)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failovers := output.Metrics.Failovers; len(failovers) != 1 || failovers[0].PromotedID != "replica" || failovers[0].Tick != 5 {
+		t.Fatalf("got failovers %+v, want the replica promoted at tick 5", failovers)
+	}
+	if replica := lastTickMetrics(t, output, "replica"); replica.WriteRPS == 0 || replica.Errors != 0 {
+		t.Errorf("promoted replica: %.1f writes with %d errors, want it taking writes", replica.WriteRPS, replica.Errors)
+	}
+}
+
+// Async replicas lag and their reads may be stale; sync ones cost the
+// primary's writes a commit round trip instead
+func TestReplicationModes(t *testing.T) {
+	async, err := NewEngine(replicationInput(map[string]interface{}{}, map[string]interface{}{"replication": ReplicationAsync})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync, err := NewEngine(replicationInput(map[string]interface{}{}, map[string]interface{}{"replication": ReplicationSync})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asyncReplica, syncReplica := lastTickMetrics(t, async, "replica"), lastTickMetrics(t, sync, "replica")
+	if asyncReplica.ReplicationLag < DefaultReplicationLagMs || asyncReplica.StaleReadRPS == 0 || async.Metrics.StaleReads == 0 {
+		t.Errorf("async: %.1fms lag, %.1f stale reads/s, want lag and stale reads", asyncReplica.ReplicationLag, asyncReplica.StaleReadRPS)
+	}
+	if syncReplica.ReplicationLag != 0 || syncReplica.StaleReadRPS != 0 || sync.Metrics.StaleReads != 0 {
+		t.Errorf("sync: %.1fms lag, %.1f stale reads/s, want neither", syncReplica.ReplicationLag, syncReplica.StaleReadRPS)
+	}
+	if asyncPrimary, syncPrimary := lastTickMetrics(t, async, "db"), lastTickMetrics(t, sync, "db"); syncPrimary.LatencyMs <= asyncPrimary.LatencyMs {
+		t.Errorf("primary latency %.1fms with a sync replica, want above %.1fms with an async one", syncPrimary.LatencyMs, asyncPrimary.LatencyMs)
+	}
+}
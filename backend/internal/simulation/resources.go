@@ -18,115 +18,7 @@ func calculateResourceUsage(node *NodeState, incomingRPS float64, effectiveCapac
 	}
 
 	loadRatio := incomingRPS / effectiveCapacity
-
-	switch {
-	// ==================== COMPUTE SERVICES ====================
-	case node.Type == "api_server" || node.Type == "web_server" || node.Type == "microservice":
-		return calculateComputeResources(loadRatio)
-
-	// ==================== DATABASES ====================
-	case node.Type == "database_sql" || node.Type == "database_postgres" || node.Type == "database_mysql":
-		return calculateSQLDatabaseResources(node, loadRatio)
-
-	case node.Type == "database_nosql" || node.Type == "database_mongodb":
-		return calculateNoSQLDatabaseResources(node, loadRatio)
-
-	case node.Type == "database_graph":
-		return calculateGraphDatabaseResources(loadRatio)
-
-	case node.Type == "database_timeseries":
-		return calculateTimeSeriesDatabaseResources(loadRatio)
-
-	// ==================== CACHES ====================
-	case node.Type == "cache_redis" || node.Type == "cache_memcached":
-		return calculateCacheResources(node, loadRatio)
-
-	// ==================== QUEUES & MESSAGE BROKERS ====================
-	case node.Type == "queue" || node.Type == "message_broker" || node.Type == "event_bus":
-		return calculateQueueResources(node, loadRatio)
-
-	// ==================== LOAD BALANCERS ====================
-	case node.Type == "load_balancer" || node.Type == "api_gateway" || node.Type == "reverse_proxy":
-		return calculateLoadBalancerResources(loadRatio)
-
-	// ==================== WORKERS ====================
-	case node.Type == "worker":
-		return calculateWorkerResources(loadRatio)
-
-	// ==================== STORAGE ====================
-	case node.Type == "object_storage" || node.Type == "file_storage":
-		return calculateStorageResources(loadRatio)
-
-	// ==================== CDN ====================
-	case node.Type == "cdn":
-		return calculateCDNResources(loadRatio)
-
-	// ==================== SEARCH ====================
-	case node.Type == "search":
-		return calculateSearchResources(loadRatio)
-
-	// ==================== TELEMETRY ====================
-	case node.Type == "monitoring" || node.Type == "logging" || node.Type == "analytics_service":
-		return calculateTelemetryResources(loadRatio)
-
-	// ==================== SRE PRODUCTION FIX: NEW COMPONENTS ====================
-	case node.Type == "apm":
-		return calculateAPMResources(loadRatio)
-
-	case node.Type == "sidecar_proxy":
-		return calculateSidecarProxyResources(loadRatio)
-
-	// MEDIUM PRIORITY: Additional Observability
-	case node.Type == "rum":
-		return calculateRUMResources(loadRatio)
-
-	case node.Type == "synthetic_monitoring":
-		return calculateSyntheticMonitoringResources(loadRatio)
-
-	// MEDIUM PRIORITY: Modern API Patterns
-	case node.Type == "graphql_gateway":
-		return calculateGraphQLGatewayResources(loadRatio)
-
-	case node.Type == "grpc_server":
-		return calculateGRPCServerResources(loadRatio)
-
-	// LOW PRIORITY: Edge & Web3
-	case node.Type == "wasm_runtime":
-		return calculateWASMRuntimeResources(loadRatio)
-
-	case node.Type == "blockchain_node":
-		return calculateBlockchainNodeResources(loadRatio)
-
-	// PRIORITY 2: Serverless Functions
-	case node.Type == "lambda_function" || node.Type == "cloud_function" || node.Type == "azure_function":
-		return calculateServerlessResources(loadRatio)
-
-	// PRIORITY 2: AI/ML Model Serving
-	case node.Type == "sagemaker_endpoint" || node.Type == "vertex_ai_endpoint" || node.Type == "azure_ml_endpoint":
-		return calculateMLEndpointResources(loadRatio)
-
-	// PRIORITY 2: Kubernetes Components
-	case node.Type == "k8s_pod":
-		return calculateK8sPodResources(loadRatio)
-	case node.Type == "k8s_service":
-		return calculateK8sServiceResources(loadRatio)
-	case node.Type == "k8s_ingress":
-		return calculateK8sIngressResources(loadRatio)
-
-	// PRIORITY 2: Multi-cloud Equivalents
-	case node.Type == "azure_app_service" || node.Type == "gcp_app_engine":
-		return calculatePaaSWebAppResources(loadRatio)
-	case node.Type == "azure_cosmos_db":
-		return calculateCosmosDBResources(loadRatio)
-	case node.Type == "gcp_firestore":
-		return calculateFirestoreResources(loadRatio)
-	case node.Type == "azure_service_bus" || node.Type == "gcp_pub_sub":
-		return calculateManagedMessagingResources(loadRatio)
-
-	// ==================== DEFAULT ====================
-	default:
-		return calculateDefaultResources(loadRatio)
-	}
+	return componentModel(node.Type).ResourceUsage(node, loadRatio)
 }
 
 // ==================== COMPUTE RESOURCES ====================
@@ -358,7 +250,7 @@ func calculateQueueResources(node *NodeState, loadRatio float64) ResourceUsage {
 
 	// Disk I/O for persistent queues (Kafka)
 	diskIO := 0.0
-	if node.Type == "message_broker" { // Kafka uses disk
+	if node.Type == "message_broker" || node.Type == "queue_kafka" { // Kafka uses disk
 		diskIO = 10.0 + (loadRatio * 60.0)
 		diskIO = math.Min(100, diskIO)
 	}
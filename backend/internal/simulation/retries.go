@@ -0,0 +1,231 @@
+package simulation
+
+import "math"
+
+// DefaultBackoffMultiplier makes retry backoff exponential unless an edge or
+// node asks for constant (1.0) backoff
+const DefaultBackoffMultiplier = 2.0
+
+// retryPolicy is the resolved timeout/retry behaviour of a caller on one edge
+type retryPolicy struct {
+	timeoutMS         float64 // 0 = no client-side timeout
+	retries           int     // Attempts after the first one
+	backoffMS         float64 // Delay before the first retry
+	backoffMultiplier float64 // Delay growth per attempt (2 = exponential, 1 = constant)
+	jitter            float64 // 0-1: share of the delay that is randomized ("full jitter" = 1)
+}
+
+// pendingRetry is failed traffic waiting to be re-sent on an edge
+type pendingRetry struct {
+	source  string
+	target  string
+	attempt int // 1 = first retry
+	traffic trafficMix
+}
+
+// resolveRetryPolicy merges an edge's retry settings over the calling node's config
+func (e *Engine) resolveRetryPolicy(edge SimEdge) retryPolicy {
+	config := map[string]interface{}{}
+	for _, node := range e.input.Nodes {
+		if node.ID == edge.Source && node.Data.Config != nil {
+			config = node.Data.Config
+			break
+		}
+	}
+
+	policy := retryPolicy{
+		timeoutMS:         getFloat(config, "timeoutMs", 0),
+		retries:           getInt(config, "retries", 0),
+		backoffMS:         getFloat(config, "backoffMs", 0),
+		backoffMultiplier: getFloat(config, "backoffMultiplier", DefaultBackoffMultiplier),
+		jitter:            getFloat(config, "jitter", 0),
+	}
+	if edge.Data.TimeoutMs > 0 {
+		policy.timeoutMS = edge.Data.TimeoutMs
+	}
+	if edge.Data.Retries != nil {
+		policy.retries = *edge.Data.Retries
+	}
+	if edge.Data.BackoffMs > 0 {
+		policy.backoffMS = edge.Data.BackoffMs
+	}
+	if edge.Data.BackoffMultiplier > 0 {
+		policy.backoffMultiplier = edge.Data.BackoffMultiplier
+	}
+	if edge.Data.Jitter > 0 {
+		policy.jitter = edge.Data.Jitter
+	}
+
+	if policy.retries < 0 {
+		policy.retries = 0
+	}
+	if policy.backoffMultiplier < 1 {
+		policy.backoffMultiplier = 1
+	}
+	policy.jitter = math.Max(0, math.Min(1, policy.jitter))
+	return policy
+}
+
+// takeRetries removes and returns the retries due at a tick
+func (e *Engine) takeRetries(tick int) []pendingRetry {
+	due := e.state.retries[tick]
+	delete(e.state.retries, tick)
+	return due
+}
+
+// attemptFailureRate returns the share of calls to a node that fail from the
+// caller's point of view this tick: rejected by the node (down, overloaded,
+// read-only) or served slower than the caller's timeout
+func attemptFailureRate(target *NodeState, timeoutMS float64) (rejected, timedOut float64) {
+	if target.RPSIn > 0 {
+		rejected = math.Min(1, target.RejectedRPS/target.RPSIn)
+	}
+	if timeoutMS > 0 && target.LatencyMS > timeoutMS {
+		timedOut = 1 - rejected
+	}
+	return rejected, timedOut
+}
+
+// scheduleRetries looks at every edge with a retry or timeout policy after
+// the tick's traffic has been served, counts the caller-visible failures and
+// queues the retryable part for later ticks. Retried attempts are taken back
+// out of FailedRequests: a request only fails once it runs out of retries.
+func (e *Engine) scheduleRetries(traffic *tickTraffic, injected []pendingRetry) {
+	seen := make(map[string]map[string]bool)
+	for _, sourceID := range e.state.NodeOrder {
+		for _, route := range e.routes[sourceID] {
+			target := e.state.NodeStates[route.target]
+			if route.mirror || target == nil || seen[sourceID][route.target] {
+				continue
+			}
+			if seen[sourceID] == nil {
+				seen[sourceID] = make(map[string]bool)
+			}
+			seen[sourceID][route.target] = true
+
+			policy := route.retry
+			if policy.retries == 0 && policy.timeoutMS == 0 {
+				continue
+			}
+			rejected, timedOut := attemptFailureRate(target, policy.timeoutMS)
+			if rejected+timedOut <= 0 {
+				continue
+			}
+
+			// First attempts on this edge, then retries that were re-sent on it.
+			// Only what the edge's guards and the network let through reached
+			// the target; the rest already failed.
+			delivered := traffic.retried[sourceID][route.target]
+			first := traffic.flows[sourceID][route.target]
+			first = trafficMix{Reads: math.Max(0, first.Reads-delivered.Reads), Writes: math.Max(0, first.Writes-delivered.Writes)}
+			attempts := []pendingRetry{{source: sourceID, target: route.target, traffic: first}}
+			resent := 0.0
+			for _, retry := range injected {
+				if retry.source == sourceID && retry.target == route.target {
+					resent += retry.traffic.Total()
+				}
+			}
+			for _, retry := range injected {
+				if retry.source == sourceID && retry.target == route.target && resent > 0 {
+					retry.traffic = retry.traffic.scale(delivered.Total() / resent)
+					attempts = append(attempts, retry)
+				}
+			}
+
+			for _, attempt := range attempts {
+				if attempt.traffic.Total() <= 0 {
+					continue
+				}
+				// Rejections were already counted by the target; timeouts are new failures
+				e.state.FailedRequests += int(attempt.traffic.Total() * timedOut)
+
+				if attempt.attempt >= policy.retries {
+					continue
+				}
+				failed := attempt.traffic.scale(rejected + timedOut)
+				e.state.FailedRequests -= int(failed.Total())
+				if e.state.FailedRequests < 0 {
+					e.state.FailedRequests = 0
+				}
+				e.state.RetriedRequests += int(failed.Total())
+				e.deferRetry(policy, pendingRetry{
+					source:  sourceID,
+					target:  route.target,
+					attempt: attempt.attempt + 1,
+					traffic: failed,
+				})
+			}
+		}
+	}
+}
+
+// deferRetry spreads a retry over the ticks its backoff window falls into.
+// Without backoff everything lands on the next tick (a retry storm). Jittered
+// delays are uniform over [delay*(1-jitter), delay], so the retry volume is
+// split across ticks by how much of that window each one covers.
+func (e *Engine) deferRetry(policy retryPolicy, retry pendingRetry) {
+	delay := policy.backoffMS * math.Pow(policy.backoffMultiplier, float64(retry.attempt-1))
+	low, high := delay*(1-policy.jitter), delay
+
+	if high-low < 1 {
+		tick := e.state.Tick + 1 + int(low/1000)
+		e.state.retries[tick] = append(e.state.retries[tick], retry)
+		return
+	}
+
+	for offset := 1 + int(low/1000); float64(offset-1)*1000 < high; offset++ {
+		windowStart, windowEnd := float64(offset-1)*1000, float64(offset)*1000
+		overlap := math.Min(high, windowEnd) - math.Max(low, windowStart)
+		if overlap <= 0 {
+			continue
+		}
+		share := retry
+		share.traffic = retry.traffic.scale(overlap / (high - low))
+		tick := e.state.Tick + offset
+		e.state.retries[tick] = append(e.state.retries[tick], share)
+	}
+}
+
+// recordRetryAmplification compares each node's load with and without retried
+// traffic. baseline is nil on ticks with no retries in flight.
+func (e *Engine) recordRetryAmplification(traffic, baseline *tickTraffic) {
+	for _, nodeID := range e.state.NodeOrder {
+		node := e.state.NodeStates[nodeID]
+		attempted := traffic.incoming[nodeID].Total()
+		fresh := attempted
+		if baseline != nil {
+			fresh = baseline.incoming[nodeID].Total()
+		}
+
+		node.Amplification = 1
+		if fresh > 0 {
+			node.Amplification = attempted / fresh
+		}
+		e.state.attemptedLoad[nodeID] += attempted
+		e.state.freshLoad[nodeID] += fresh
+	}
+}
+
+// retryAmplification reports, per node, total load over the load it would have
+// seen without retries. Only nodes that actually received retried traffic are listed.
+func (e *Engine) retryAmplification() map[string]float64 {
+	amplification := make(map[string]float64)
+	for _, nodeID := range e.state.NodeOrder {
+		fresh, attempted := e.state.freshLoad[nodeID], e.state.attemptedLoad[nodeID]
+		if fresh > 0 && attempted > fresh*(1+feedbackTolerance) {
+			amplification[nodeID] = math.Round(attempted/fresh*1000) / 1000
+		}
+	}
+	return amplification
+}
+
+// abandonPendingRetries counts retries still waiting when the run ends as
+// failed, so long backoffs can't hide failures past the simulated window
+func (e *Engine) abandonPendingRetries() {
+	for tick, retries := range e.state.retries {
+		for _, retry := range retries {
+			e.state.FailedRequests += int(retry.traffic.Total())
+		}
+		delete(e.state.retries, tick)
+	}
+}
@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// retryInput calls a database that is down the whole run through an edge
+// retrying failed calls three times
+func retryInput(backoffMs float64) *SimulationInput {
+	input := breakerInput(0)
+	input.Edges[1].Data = SimEdgeData{Retries: input.Edges[1].Data.Retries, BackoffMs: backoffMs}
+	input.Workload.DurationSeconds = 20
+	return input
+}
+
+// Without backoff every failed call comes straight back: once three rounds
+// of retries are in flight the database sees four times its load
+func TestRetryStormAmplifiesLoad(t *testing.T) {
+	output, err := NewEngine(retryInput(0)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, point := range output.TimeSeries[3:] {
+		if db := tickMetrics(t, point, "db"); math.Abs(db.RPSIn-400) > 1 || math.Abs(db.Amplification-4) > 0.01 {
+			t.Errorf("tick %d: db got %.1f RPS (%.2fx), want 400 (4x)", point.Tick, db.RPSIn, db.Amplification)
+		}
+	}
+	if got := output.Metrics.RetryAmplification["db"]; got < 3.5 || got > 4 {
+		t.Errorf("run amplification %.3f, want close to 4", got)
+	}
+	if _, ok := output.Metrics.RetryAmplification["api"]; ok {
+		t.Error("api got no retries, want it left out of the amplification")
+	}
+	// Every request fails exactly once, after its last retry
+	if output.Metrics.FailedRequests > output.Metrics.TotalRequests {
+		t.Errorf("%d failed of %d requests: retried attempts counted as failures", output.Metrics.FailedRequests, output.Metrics.TotalRequests)
+	}
+	if want := 3 * 100 * 17; output.Metrics.RetriedRequests < want {
+		t.Errorf("got %d retried attempts, want at least %d", output.Metrics.RetriedRequests, want)
+	}
+}
+
+// Exponential backoff spreads retries out, so the database peaks lower
+func TestBackoffDampensRetryStorm(t *testing.T) {
+	storm, err := NewEngine(retryInput(0)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backoff, err := NewEngine(retryInput(2000)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storm, backoff := storm.Metrics.RetryAmplification["db"], backoff.Metrics.RetryAmplification["db"]; backoff >= storm {
+		t.Errorf("amplification %.3f with backoff, want below the storm's %.3f", backoff, storm)
+	}
+	// Failures surface at the end of a tick; the first retries wait 2s more
+	for _, point := range backoff.TimeSeries[:3] {
+		if db := tickMetrics(t, point, "db"); db.RetryRPS != 0 {
+			t.Errorf("tick %d: %.1f retried RPS, want none before the 2s backoff", point.Tick, db.RetryRPS)
+		}
+	}
+	if db := tickMetrics(t, backoff.TimeSeries[3], "db"); db.RetryRPS == 0 {
+		t.Error("tick 4: want the first retries after the backoff")
+	}
+}
+
+// Calls slower than the caller's timeout fail and are retried even though
+// the target served them
+func TestTimeoutsAreRetried(t *testing.T) {
+	input := breakerInput(0)
+	input.Edges[1].Data = SimEdgeData{Retries: input.Edges[1].Data.Retries, TimeoutMs: 100}
+	input.Workload.Failures = []FailureInjection{{Type: FailureNodeLatency, NodeID: "db", DelayMs: 500}}
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := lastTickMetrics(t, output, "db")
+	if db.Errors != 0 || db.RetryRPS == 0 {
+		t.Errorf("db: %d errors and %.1f retried RPS, want no errors on the db and retries from the caller", db.Errors, db.RetryRPS)
+	}
+	if output.Metrics.FailedRequests == 0 {
+		t.Error("want the calls that timed out on every attempt failed")
+	}
+}
@@ -0,0 +1,262 @@
+package simulation
+
+import "sort"
+
+// Routing policies a parent can apply to its outgoing edges
+const (
+	RoutingWeighted    = "weighted"     // Split by edge weight (default; equal weights = even split)
+	RoutingRoundRobin  = "round_robin"  // Even split across targets, weights ignored
+	RoutingLeastLoaded = "least_loaded" // Split by healthy targets' effective capacity
+	RoutingFailover    = "failover"     // All traffic to the healthy target with the lowest priority value
+	RoutingMirror      = "mirror"       // Copy of the parent's traffic; doesn't consume its share
+	RoutingGeo         = "geo"          // Users go to a target in their own region, the nearest healthy one otherwise
+	RoutingLatency     = "latency"      // Users go to the healthy target with the lowest network latency from their region
+)
+
+// telemetryMirrorPercent is the default sample copied to monitoring/logging
+// targets (sidecar pattern), matching what the old processNode approximated
+const telemetryMirrorPercent = 10.0
+
+// trafficMix is RPS split into reads and writes as it flows through the graph
+type trafficMix struct {
+	Reads  float64
+	Writes float64
+}
+
+// Total returns the combined RPS
+func (m trafficMix) Total() float64 {
+	return m.Reads + m.Writes
+}
+
+// scale returns the mix multiplied by a factor
+func (m trafficMix) scale(factor float64) trafficMix {
+	return trafficMix{Reads: m.Reads * factor, Writes: m.Writes * factor}
+}
+
+// add returns the sum of two mixes
+func (m trafficMix) add(other trafficMix) trafficMix {
+	return trafficMix{Reads: m.Reads + other.Reads, Writes: m.Writes + other.Writes}
+}
+
+// routeEdge is a resolved outgoing edge of a node
+type routeEdge struct {
+	target        string
+	weight        float64
+	priority      int
+	mirror        bool
+	mirrorPercent float64
+	retry         retryPolicy
+}
+
+// buildRoutes resolves every node's outgoing edges into routing rules
+func (e *Engine) buildRoutes() map[string][]routeEdge {
+	routes := make(map[string][]routeEdge)
+
+	for _, edge := range e.input.Edges {
+		route := routeEdge{
+			target:   edge.Target,
+			weight:   1.0,
+			priority: edge.Data.Priority,
+			retry:    e.resolveRetryPolicy(edge),
+		}
+		if edge.Data.Weight != nil {
+			route.weight = *edge.Data.Weight
+			if route.weight < 0 {
+				route.weight = 0
+			}
+		}
+
+		policy := edge.Data.RoutingPolicy
+		if policy == "" && e.isTelemetryNode(edge.Target) {
+			// Telemetry sidecars get a sampled copy unless the edge says otherwise
+			policy = RoutingMirror
+			route.mirrorPercent = telemetryMirrorPercent
+		}
+		if policy == RoutingMirror {
+			route.mirror = true
+			if edge.Data.MirrorPercent != 0 {
+				route.mirrorPercent = edge.Data.MirrorPercent
+			}
+			if route.mirrorPercent <= 0 || route.mirrorPercent > 100 {
+				route.mirrorPercent = 100
+			}
+		}
+
+		routes[edge.Source] = append(routes[edge.Source], route)
+	}
+
+	return routes
+}
+
+// routingPolicy returns the policy a parent applies to its non-mirror edges.
+// The first edge that names a policy sets it for the whole fan-out.
+func (e *Engine) routingPolicy(parentID string) string {
+	for _, edge := range e.input.Edges {
+		if edge.Source != parentID {
+			continue
+		}
+		switch edge.Data.RoutingPolicy {
+		case RoutingRoundRobin, RoutingLeastLoaded, RoutingFailover, RoutingWeighted, RoutingGeo, RoutingLatency:
+			return edge.Data.RoutingPolicy
+		}
+	}
+	if node := e.state.NodeStates[parentID]; node != nil && isGlobalTrafficManager(node.Type) {
+		return RoutingLatency
+	}
+	return RoutingWeighted
+}
+
+// splitTraffic distributes a parent's outgoing traffic over its edges according
+// to its routing policy and returns target -> traffic. Reads are balanced over
+// every target; writes skip read replicas so they land on primaries. Mirror
+// edges receive a copy on top of the split; traffic routed to targets missing
+// from the graph is lost. The second result is the traffic no edge would take
+// (every weight is zero), which fails at the parent.
+func (e *Engine) splitTraffic(parentID string, outgoing trafficMix) (map[string]trafficMix, trafficMix) {
+	flows := make(map[string]trafficMix)
+	unrouted := trafficMix{}
+	if outgoing.Total() <= 0 {
+		return flows, unrouted
+	}
+
+	primary := []routeEdge{}
+	writable := []routeEdge{}
+	for _, route := range e.routes[parentID] {
+		if route.mirror {
+			flows[route.target] = flows[route.target].add(outgoing.scale(route.mirrorPercent / 100.0))
+			continue
+		}
+		primary = append(primary, route)
+		if node := e.state.NodeStates[route.target]; node == nil || !node.isReadReplica() {
+			writable = append(writable, route)
+		}
+	}
+	if len(primary) == 0 {
+		return flows, unrouted
+	}
+	if len(writable) == 0 {
+		// Only replicas downstream: writes still go there and get rejected
+		writable = primary
+	}
+
+	reads, unroutedReads := e.splitRPS(parentID, outgoing.Reads, primary)
+	for target, rps := range reads {
+		flow := flows[target]
+		flow.Reads += rps
+		flows[target] = flow
+	}
+	writes, unroutedWrites := e.splitRPS(parentID, outgoing.Writes, writable)
+	for target, rps := range writes {
+		flow := flows[target]
+		flow.Writes += rps
+		flows[target] = flow
+	}
+
+	return flows, trafficMix{Reads: unroutedReads, Writes: unroutedWrites}
+}
+
+// splitRPS applies the parent's routing policy to one traffic class and
+// returns the split plus the RPS it couldn't route
+func (e *Engine) splitRPS(parentID string, rps float64, routes []routeEdge) (map[string]float64, float64) {
+	flows := make(map[string]float64)
+	if rps <= 0 {
+		return flows, 0
+	}
+
+	switch e.routingPolicies[parentID] {
+	case RoutingRoundRobin:
+		share := rps / float64(len(routes))
+		for _, route := range routes {
+			flows[route.target] += share
+		}
+
+	case RoutingLeastLoaded:
+		// Least-outstanding-requests balancing settles on a capacity-proportional
+		// split across healthy targets
+		totalCapacity := 0.0
+		capacities := make([]float64, len(routes))
+		for i, route := range routes {
+			if node := e.state.NodeStates[route.target]; node != nil && isHealthy(node) {
+				capacities[i] = node.CapacityRPS * float64(node.Replicas)
+				totalCapacity += capacities[i]
+			}
+		}
+		if totalCapacity == 0 {
+			share := rps / float64(len(routes))
+			for _, route := range routes {
+				flows[route.target] += share
+			}
+			break
+		}
+		for i, route := range routes {
+			flows[route.target] += rps * capacities[i] / totalCapacity
+		}
+
+	case RoutingFailover:
+		// Lowest priority value is the primary; stable sort keeps edge order on ties
+		ordered := make([]routeEdge, len(routes))
+		copy(ordered, routes)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].priority < ordered[j].priority
+		})
+		chosen := ordered[0]
+		for _, route := range ordered {
+			if node := e.state.NodeStates[route.target]; node != nil && isHealthy(node) {
+				chosen = route
+				break
+			}
+		}
+		flows[chosen.target] += rps
+
+	default:
+		totalWeight := 0.0
+		for _, route := range routes {
+			totalWeight += route.weight
+		}
+		if totalWeight == 0 {
+			// Every edge drained (e.g. 0/0 blue-green): nothing leaves the
+			// parent and its requests fail there
+			return flows, rps
+		}
+		for _, route := range routes {
+			flows[route.target] += rps * route.weight / totalWeight
+		}
+	}
+
+	return flows, 0
+}
+
+// countUnrouted fails the requests a node had no edge to send to this tick
+func (e *Engine) countUnrouted(traffic *tickTraffic) {
+	for _, nodeID := range e.state.NodeOrder {
+		unrouted := traffic.unrouted[nodeID].Total()
+		if unrouted <= 0 {
+			continue
+		}
+		node := e.state.NodeStates[nodeID]
+		node.ErrorCount += int(unrouted)
+		node.RejectedRPS += unrouted
+		e.state.FailedRequests += int(unrouted)
+	}
+}
+
+// isTelemetryNode reports whether a node only observes traffic (monitoring/logging)
+func (e *Engine) isTelemetryNode(nodeID string) bool {
+	node := e.state.NodeStates[nodeID]
+	return node != nil && isTelemetry(node.Type)
+}
+
+// isTelemetry reports whether a node type only observes traffic
+func isTelemetry(nodeType string) bool {
+	return ComponentCategory(nodeType) == ComponentTelemetry
+}
+
+// isHealthy reports whether a node can currently accept traffic
+func isHealthy(node *NodeState) bool {
+	return !node.Failed && !node.Partitioned
+}
+
+// isReadReplica reports whether a node is configured as a read-only replica
+func (n *NodeState) isReadReplica() bool {
+	return n.Role == "replica" || n.Role == "read_replica"
+}
@@ -0,0 +1,169 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// routingInput sends a steady load through a load balancer to two servers
+// over the given edges
+func routingInput(a, b SimEdgeData, failures ...FailureInjection) *SimulationInput {
+	seed := int64(1)
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "lb", Data: SimNodeData{NodeType: "load_balancer", Config: map[string]interface{}{}}},
+			{ID: "a", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+			{ID: "b", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "lb"},
+			{ID: "e2", Source: "lb", Target: "a", Data: a},
+			{ID: "e3", Source: "lb", Target: "b", Data: b},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 10, Seed: &seed, Failures: failures},
+	}
+}
+
+// lastTickMetrics returns a node's metrics from the last tick of a run
+func lastTickMetrics(t *testing.T, output *SimulationOutput, nodeID string) NodeMetrics {
+	t.Helper()
+	return tickMetrics(t, output.TimeSeries[len(output.TimeSeries)-1], nodeID)
+}
+
+// tickMetrics returns a node's metrics from one tick
+func tickMetrics(t *testing.T, point TimeSeriesPoint, nodeID string) NodeMetrics {
+	t.Helper()
+	for _, node := range point.NodeMetrics {
+		if node.NodeID == nodeID {
+			return node
+		}
+	}
+	t.Fatalf("tick %d: no metrics for %s", point.Tick, nodeID)
+	return NodeMetrics{}
+}
+
+func weight(w float64) *float64 {
+	return &w
+}
+
+func TestWeightedSplit(t *testing.T) {
+	output, err := NewEngine(routingInput(SimEdgeData{Weight: weight(90)}, SimEdgeData{Weight: weight(10)})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := lastTickMetrics(t, output, "a"), lastTickMetrics(t, output, "b")
+	if math.Abs(a.RPSIn-90) > 1 || math.Abs(b.RPSIn-10) > 1 {
+		t.Errorf("got a=%.1f b=%.1f RPS, want a 90/10 split", a.RPSIn, b.RPSIn)
+	}
+}
+
+func TestFailoverSplit(t *testing.T) {
+	primary := SimEdgeData{RoutingPolicy: RoutingFailover, Priority: 1}
+	secondary := SimEdgeData{RoutingPolicy: RoutingFailover, Priority: 2}
+
+	output, err := NewEngine(routingInput(primary, secondary)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := lastTickMetrics(t, output, "a"), lastTickMetrics(t, output, "b"); a.RPSIn < 99 || b.RPSIn != 0 {
+		t.Errorf("healthy: got a=%.1f b=%.1f RPS, want everything on the primary", a.RPSIn, b.RPSIn)
+	}
+
+	output, err = NewEngine(routingInput(primary, secondary, FailureInjection{Type: FailureNodeFail, NodeID: "a"})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := lastTickMetrics(t, output, "a"), lastTickMetrics(t, output, "b"); a.RPSIn != 0 || b.RPSIn < 99 {
+		t.Errorf("primary down: got a=%.1f b=%.1f RPS, want everything on the secondary", a.RPSIn, b.RPSIn)
+	}
+}
+
+// Traffic a parent can't route anywhere fails at the parent instead of vanishing
+func TestZeroWeightsFailAtSource(t *testing.T) {
+	output, err := NewEngine(routingInput(SimEdgeData{Weight: weight(0)}, SimEdgeData{Weight: weight(0)})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Metrics.ErrorRate < 0.99 {
+		t.Errorf("got %.0f%% errors, want every request failed", output.Metrics.ErrorRate*100)
+	}
+	if lb := lastTickMetrics(t, output, "lb"); lb.Errors == 0 {
+		t.Error("want the errors counted on the load balancer")
+	}
+	if a := lastTickMetrics(t, output, "a"); a.RPSIn != 0 {
+		t.Errorf("got %.1f RPS into a drained edge", a.RPSIn)
+	}
+}
+
+// readWriteInput sends 800 reads and 200 writes a second from an API to the
+// given data tier, listed after it and fed by edges from the API
+func readWriteInput(tier ...SimNode) *SimulationInput {
+	seed := int64(1)
+	input := &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{"replicas": 4}}},
+		},
+		Edges: []SimEdge{{ID: "e1", Source: "client", Target: "api"}},
+		Workload: WorkloadConfig{
+			RPS: 1000, Mode: "constant", DurationSeconds: 10, Seed: &seed,
+			ReadWriteRatio: ReadWriteRatio{Read: 80, Write: 20},
+		},
+	}
+	input.Nodes = append(input.Nodes, tier...)
+	return input
+}
+
+// A cache in front of the database takes the hits off its reads; every write
+// still reaches it
+func TestCacheAbsorbsOnlyReads(t *testing.T) {
+	input := readWriteInput(
+		SimNode{ID: "cache", Data: SimNodeData{NodeType: "cache_redis", Config: map[string]interface{}{"prewarmed": true}}},
+		SimNode{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{"replicas": 4}}},
+	)
+	input.Edges = append(input.Edges, SimEdge{ID: "e2", Source: "api", Target: "cache"}, SimEdge{ID: "e3", Source: "cache", Target: "db"})
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point := output.TimeSeries[len(output.TimeSeries)-1]
+	if point.CacheHitRatio <= 0 {
+		t.Fatal("want a warm cache")
+	}
+	api, db := lastTickMetrics(t, output, "api"), lastTickMetrics(t, output, "db")
+	if math.Abs(api.ReadRPS-800) > 1 || math.Abs(api.WriteRPS-200) > 1 {
+		t.Errorf("api: got %.1f reads and %.1f writes, want 800 and 200", api.ReadRPS, api.WriteRPS)
+	}
+	if math.Abs(db.WriteRPS-200) > 1 {
+		t.Errorf("db: got %.1f writes, want all 200", db.WriteRPS)
+	}
+	if want := 800 * (1 - point.CacheHitRatio); math.Abs(db.ReadRPS-want) > 1 {
+		t.Errorf("db: got %.1f reads, want the %.1f misses", db.ReadRPS, want)
+	}
+}
+
+// Writes go to the primary; replicas only share the reads
+func TestReplicasTakeOnlyReads(t *testing.T) {
+	input := readWriteInput(
+		SimNode{ID: "primary", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{"replicas": 4}}},
+		SimNode{ID: "replica", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{"replicas": 4, "role": "replica", "replicaOf": "primary"}}},
+	)
+	input.Edges = append(input.Edges, SimEdge{ID: "e2", Source: "api", Target: "primary"}, SimEdge{ID: "e3", Source: "api", Target: "replica"})
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primary, replica := lastTickMetrics(t, output, "primary"), lastTickMetrics(t, output, "replica")
+	if math.Abs(primary.WriteRPS-200) > 1 || replica.WriteRPS != 0 {
+		t.Errorf("got %.1f writes on the primary and %.1f on the replica, want 200 and 0", primary.WriteRPS, replica.WriteRPS)
+	}
+	if math.Abs(primary.ReadRPS-400) > 1 || math.Abs(replica.ReadRPS-400) > 1 {
+		t.Errorf("got %.1f reads on the primary and %.1f on the replica, want 400 each", primary.ReadRPS, replica.ReadRPS)
+	}
+	if replica.Errors != 0 {
+		t.Errorf("replica rejected %d requests", replica.Errors)
+	}
+}
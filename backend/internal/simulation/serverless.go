@@ -0,0 +1,146 @@
+package simulation
+
+import "math"
+
+// Serverless function defaults (AWS Lambda)
+const (
+	DefaultFunctionConcurrency = 1000 // Account concurrency limit per region
+	DefaultFunctionMemoryMB    = 512
+	DefaultFunctionRuntime     = "nodejs18"
+	DefaultFunctionIdleSeconds = 300 // Idle environments are reclaimed after ~5 minutes
+)
+
+// Serverless pricing (AWS Lambda, x86)
+const (
+	functionRequestPriceUSD     = 0.20 / 1000000 // Per invocation
+	functionGBSecondPriceUSD    = 0.0000166667   // Per GB-second of execution, init included
+	functionProvisionedPriceUSD = 0.0000041667   // Per GB-second of provisioned concurrency, used or not
+)
+
+// functionModel simulates the execution environments of a serverless
+// function. Each environment runs one invocation at a time; invocations no
+// warm environment can take start a new one, paying a cold start, until the
+// concurrency limit is reached and the rest are throttled. Environments that
+// sit idle are reclaimed over time, down to the provisioned ones, so a
+// traffic spike after a quiet spell starts cold again.
+type functionModel struct {
+	concurrencyLimit float64 // Reserved concurrency, or the account limit
+	provisioned      float64 // Provisioned concurrency: environments kept initialized
+	durationMS       float64 // Time a warm invocation runs
+	coldStartMS      float64 // Extra time of an invocation that starts an environment
+	memoryGB         float64
+	idleSeconds      float64 // Mean time an idle environment lives
+
+	warm         float64 // Initialized environments, provisioned ones included
+	coldFraction float64 // Share of this tick's invocations that started cold
+
+	// Totals over the run, for billing
+	ticks       int
+	invocations float64
+	gbSeconds   float64
+}
+
+// newFunctionModel sizes a function from its config
+func newFunctionModel(config map[string]interface{}) *functionModel {
+	memoryMB := getInt(config, "memoryMB", DefaultFunctionMemoryMB)
+	limit := math.Max(1, float64(getInt(config, "reservedConcurrency", DefaultFunctionConcurrency)))
+	provisioned := math.Min(math.Max(0, float64(getInt(config, "provisionedConcurrency", 0))), limit)
+	return &functionModel{
+		concurrencyLimit: limit,
+		provisioned:      provisioned,
+		durationMS:       math.Max(1, getFloat(config, "durationMs", GetLambdaWarmLatency())),
+		coldStartMS:      GetLambdaColdStartLatency(getString(config, "runtime", DefaultFunctionRuntime), memoryMB),
+		memoryGB:         float64(memoryMB) / 1024,
+		idleSeconds:      math.Max(1, getFloat(config, "idleTimeoutSec", DefaultFunctionIdleSeconds)),
+		warm:             provisioned,
+	}
+}
+
+// functionPerformance is a function's capacity once its environments are
+// all warm, and the latency of a warm invocation
+func functionPerformance(_ HardwareProvider, config map[string]interface{}) (float64, float64) {
+	f := newFunctionModel(config)
+	return f.concurrencyLimit / (f.durationMS / 1000), f.durationMS
+}
+
+// buildFunctionModels sets up the model of every serverless function node
+func (e *Engine) buildFunctionModels() {
+	for _, node := range e.input.Nodes {
+		state := e.state.NodeStates[node.ID]
+		if state == nil || ComponentCategory(state.Type) != ComponentServerless {
+			continue
+		}
+		state.function = newFunctionModel(node.Data.Config)
+	}
+}
+
+// newEnvironmentRPS is how many invocations an environment started this
+// second serves in it: the cold one, then warm ones in the time left
+func (f *functionModel) newEnvironmentRPS() float64 {
+	coldSeconds := (f.durationMS + f.coldStartMS) / 1000
+	return 1 + math.Max(0, 1-coldSeconds)/(f.durationMS/1000)
+}
+
+// capacity is the invocations per second the function can take this tick:
+// what its warm environments serve, plus what new ones up to the
+// concurrency limit can while also paying their cold start
+func (f *functionModel) capacity() float64 {
+	warmRPS := f.warm / (f.durationMS / 1000)
+	return warmRPS + math.Max(0, f.concurrencyLimit-f.warm)*f.newEnvironmentRPS()
+}
+
+// serve runs a tick's invocations and returns how many started cold. The
+// warm pool grows by the environments started and decays by the idle ones
+// reclaimed.
+func (f *functionModel) serve(rps float64) float64 {
+	warmSeconds, coldSeconds := f.durationMS/1000, (f.durationMS+f.coldStartMS)/1000
+
+	cold := 0.0
+	if warmRPS := f.warm / warmSeconds; rps > warmRPS {
+		cold = math.Min(math.Max(0, f.concurrencyLimit-f.warm), (rps-warmRPS)/f.newEnvironmentRPS())
+		f.warm += cold
+	}
+	f.coldFraction = 0
+	if rps > 0 {
+		f.coldFraction = cold / rps
+	}
+
+	busySeconds := (rps-cold)*warmSeconds + cold*coldSeconds
+	if idle := f.warm - busySeconds; idle > 0 {
+		f.warm -= idle / f.idleSeconds
+	}
+	f.warm = math.Max(f.warm, f.provisioned)
+
+	f.ticks++
+	f.invocations += rps
+	f.gbSeconds += busySeconds * f.memoryGB
+	return cold
+}
+
+// latencyMS is the average invocation time this tick, cold starts included
+func (f *functionModel) latencyMS() float64 {
+	return f.durationMS + f.coldFraction*f.coldStartMS
+}
+
+// concurrency is the environments busy at once this tick
+func (f *functionModel) concurrency(rps float64) float64 {
+	return rps * f.latencyMS() / 1000
+}
+
+// functionCost bills invocations, GB-seconds and provisioned concurrency at
+// the run's average rate
+func functionCost(_ HardwareProvider, node *NodeState, durationSeconds float64) []costItem {
+	f := node.function
+	if f == nil || f.ticks == 0 {
+		return nil
+	}
+	perSecond := durationSeconds / float64(f.ticks)
+	items := []costItem{
+		{costCompute, "serverless_requests", f.invocations * perSecond * functionRequestPriceUSD},
+		{costCompute, "serverless_duration", f.gbSeconds * perSecond * functionGBSecondPriceUSD},
+	}
+	if f.provisioned > 0 {
+		items = append(items, costItem{costCompute, "provisioned_concurrency", f.provisioned * f.memoryGB * durationSeconds * functionProvisionedPriceUSD})
+	}
+	return items
+}
@@ -0,0 +1,69 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// functionInput sends a steady load to one serverless function
+func functionInput(rps int, config map[string]interface{}) *SimulationInput {
+	seed := int64(1)
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "fn", Data: SimNodeData{NodeType: "lambda_function", Config: config}},
+		},
+		Edges:    []SimEdge{{ID: "e1", Source: "client", Target: "fn"}},
+		Workload: WorkloadConfig{RPS: rps, Mode: "constant", DurationSeconds: 20, Seed: &seed},
+	}
+}
+
+func TestFunctionColdStarts(t *testing.T) {
+	output, err := NewEngine(functionInput(100, map[string]interface{}{})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, last := output.TimeSeries[0], output.TimeSeries[len(output.TimeSeries)-1]
+	if first.ColdStarts == 0 {
+		t.Error("first tick: want cold starts from an empty pool")
+	}
+	if last.ColdStarts != 0 {
+		t.Errorf("steady state: got %d cold starts, want a warm pool", last.ColdStarts)
+	}
+	if first.Latency.P50 <= last.Latency.P50 {
+		t.Errorf("cold tick p50 %.1fms, warm %.1fms: want cold starts to add latency", first.Latency.P50, last.Latency.P50)
+	}
+
+	// Provisioned concurrency covering the load never starts cold
+	output, err = NewEngine(functionInput(100, map[string]interface{}{"provisionedConcurrency": 50})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Metrics.ColdStarts != 0 {
+		t.Errorf("provisioned: got %d cold starts, want none", output.Metrics.ColdStarts)
+	}
+}
+
+func TestFunctionThrottlesOverConcurrencyLimit(t *testing.T) {
+	// 10 environments of 100ms invocations serve 100 RPS at most
+	output, err := NewEngine(functionInput(500, map[string]interface{}{"reservedConcurrency": 10, "durationMs": 100})).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Metrics.Throttles == 0 || output.Metrics.ErrorRate < 0.7 {
+		t.Errorf("got %d throttles at %.0f%% errors; want most of 500 RPS throttled", output.Metrics.Throttles, output.Metrics.ErrorRate*100)
+	}
+}
+
+// A function node without base capacity must not divide its way to NaN
+func TestFunctionWithoutBaseCapacity(t *testing.T) {
+	engine := NewEngine(functionInput(100, map[string]interface{}{}))
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	engine.state.NodeStates["fn"].BaseCapacityRPS = 0
+	engine.step()
+	if capacity := engine.state.NodeStates["fn"].CapacityRPS; math.IsNaN(capacity) || math.IsInf(capacity, 0) {
+		t.Errorf("capacity %v, want a finite number", capacity)
+	}
+}
@@ -0,0 +1,136 @@
+package simulation
+
+import "fmt"
+
+// JournalEntry is a change applied after a given tick
+type JournalEntry struct {
+	Tick   int    `json:"tick"`
+	Change Change `json:"change"`
+}
+
+// Snapshot is a point in a run an engine can be restored to. It is the run's
+// input, seed and the changes applied up to the snapshot; those serialize, so
+// a snapshot can be saved and restored by another engine later. State is a
+// copy of the simulation state's exported fields at that point, for
+// inspection only: it isn't serialized, and Restore rebuilds it by replaying.
+type Snapshot struct {
+	Tick    int              `json:"tick"`
+	State   SimulationState  `json:"-"`
+	Input   *SimulationInput `json:"input"`
+	Seed    int64            `json:"seed"`
+	Journal []JournalEntry   `json:"journal"`
+}
+
+// Snapshot captures the run at the current tick
+func (e *Engine) Snapshot() (*Snapshot, error) {
+	if !e.started {
+		return nil, fmt.Errorf("simulation not started")
+	}
+	return &Snapshot{
+		Tick:    e.state.Tick,
+		State:   e.state.exportedCopy(),
+		Input:   e.input,
+		Seed:    e.seed,
+		Journal: append([]JournalEntry{}, e.journal...),
+	}, nil
+}
+
+// Restore puts the engine at a snapshot, taking over the snapshot's input and
+// seed. Restore is a replay, not a state load: the engine is deterministic,
+// so it restarts from the seed and replays the ticks and changes up to the
+// snapshot; the run then continues from there as if nothing after it had
+// happened. The hardware provider doesn't travel with a snapshot: an input
+// without one keeps the engine's.
+func (e *Engine) Restore(snapshot *Snapshot) error {
+	if snapshot == nil || snapshot.Input == nil {
+		return fmt.Errorf("snapshot has no simulation input")
+	}
+	if snapshot.Tick < 0 || snapshot.Tick > snapshot.Input.Workload.DurationSeconds {
+		return fmt.Errorf("snapshot tick %d is outside the run", snapshot.Tick)
+	}
+	for i, entry := range snapshot.Journal {
+		if entry.Tick > snapshot.Tick || (i > 0 && entry.Tick < snapshot.Journal[i-1].Tick) {
+			return fmt.Errorf("snapshot journal is out of order at entry %d", i)
+		}
+	}
+
+	e.input = snapshot.Input
+	e.seed = snapshot.Seed
+	if e.input.Hardware != nil {
+		e.hardware = e.input.Hardware
+	}
+	e.config = e.input.Workload
+	e.rand.Seed(e.seed)
+	if err := e.Start(); err != nil {
+		return err
+	}
+	journal := snapshot.Journal
+	for {
+		for len(journal) > 0 && journal[0].Tick == e.state.Tick {
+			if err := e.ApplyChange(journal[0].Change); err != nil {
+				return fmt.Errorf("replaying %s at tick %d: %w", journal[0].Change.Type, journal[0].Tick, err)
+			}
+			journal = journal[1:]
+		}
+		if e.state.Tick >= snapshot.Tick {
+			return nil
+		}
+		e.step()
+	}
+}
+
+// exportedCopy copies the state's exported fields, so the copy doesn't change
+// as the run goes on. Node states are copied by value.
+func (s *SimulationState) exportedCopy() SimulationState {
+	nodes := make(map[string]*NodeState, len(s.NodeStates))
+	for id, node := range s.NodeStates {
+		copied := *node
+		nodes[id] = &copied
+	}
+	histogram := make(map[float64]float64, len(s.LatencyHistogram))
+	for latency, requests := range s.LatencyHistogram {
+		histogram[latency] = requests
+	}
+	regionLatency := make(map[string][]float64, len(s.RegionLatency))
+	for region, samples := range s.RegionLatency {
+		regionLatency[region] = append([]float64(nil), samples...)
+	}
+	regionTraffic := make(map[string]float64, len(s.RegionTraffic))
+	for region, rps := range s.RegionTraffic {
+		regionTraffic[region] = rps
+	}
+	copyEdges := func(edges map[string][]string) map[string][]string {
+		copied := make(map[string][]string, len(edges))
+		for id, targets := range edges {
+			copied[id] = append([]string(nil), targets...)
+		}
+		return copied
+	}
+
+	return SimulationState{
+		Tick:               s.Tick,
+		CurrentWorkloadRPS: s.CurrentWorkloadRPS,
+		NodeStates:         nodes,
+		NodeOrder:          append([]string(nil), s.NodeOrder...),
+		EdgeMap:            copyEdges(s.EdgeMap),
+		ReverseEdgeMap:     copyEdges(s.ReverseEdgeMap),
+		LatencyHistogram:   histogram,
+		LatencyHistory:     append([]float64(nil), s.LatencyHistory...),
+		ThroughputHistory:  append([]float64(nil), s.ThroughputHistory...),
+		ErrorHistory:       append([]int(nil), s.ErrorHistory...),
+		QueueHistory:       append([]int(nil), s.QueueHistory...),
+		TotalRequests:      s.TotalRequests,
+		SuccessRequests:    s.SuccessRequests,
+		FailedRequests:     s.FailedRequests,
+		RetriedRequests:    s.RetriedRequests,
+		StaleReads:         s.StaleReads,
+		ColdStarts:         s.ColdStarts,
+		Throttles:          s.Throttles,
+		DroppedRequests:    s.DroppedRequests,
+		CacheHits:          s.CacheHits,
+		CacheMisses:        s.CacheMisses,
+		ActiveFailures:     append([]string(nil), s.ActiveFailures...),
+		RegionLatency:      regionLatency,
+		RegionTraffic:      regionTraffic,
+	}
+}
@@ -0,0 +1,217 @@
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// snapshotTestInput is a small randomized design: Poisson arrivals through a
+// load balancer to an API with a cache in front of its database
+func snapshotTestInput() *SimulationInput {
+	seed := int64(42)
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "lb", Data: SimNodeData{NodeType: "load_balancer", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{"replicas": 2}}},
+			{ID: "cache", Data: SimNodeData{NodeType: "cache_redis", Config: map[string]interface{}{}}},
+			{ID: "db", Data: SimNodeData{NodeType: "database_sql", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "lb"},
+			{ID: "e2", Source: "lb", Target: "api"},
+			{ID: "e3", Source: "api", Target: "cache"},
+			{ID: "e4", Source: "cache", Target: "db"},
+		},
+		Workload: WorkloadConfig{
+			RPS:             1500,
+			Mode:            WorkloadPoisson,
+			DurationSeconds: 30,
+			ReadWriteRatio:  ReadWriteRatio{Read: 80, Write: 20},
+			Seed:            &seed,
+		},
+	}
+}
+
+// Restoring a snapshot and going on with the same changes ends exactly where
+// a run that was never interrupted does, whatever happened after the snapshot
+func TestRestoreMatchesUninterruptedRun(t *testing.T) {
+	apiFail := &FailureInjection{Type: FailureNodeFail, NodeID: "api", EndTick: 20}
+	dbDelay := &FailureInjection{Type: FailureNetworkDelay, NodeID: "db", DelayMs: 200}
+
+	tests := []struct {
+		name      string
+		changes   map[int][]Change // Applied after the tick
+		snapshot  int              // Tick to snapshot after
+		divergent map[int][]Change // Applied after the snapshot, then undone by Restore
+		restoreAt int              // Tick the divergent run reaches before Restore
+	}{
+		{
+			name:      "no changes",
+			snapshot:  10,
+			restoreAt: 25,
+		},
+		{
+			name: "changes before the snapshot",
+			changes: map[int][]Change{
+				3: {{Type: ChangeSetRPS, RPS: 3000}},
+				5: {{Type: ChangeInjectFailure, Failure: apiFail}},
+			},
+			snapshot:  12,
+			divergent: map[int][]Change{14: {{Type: ChangeSetReplicas, NodeID: "api", Replicas: 6}}},
+			restoreAt: 18,
+		},
+		{
+			name: "changes on both sides of the snapshot",
+			changes: map[int][]Change{
+				4:  {{Type: ChangeInjectFailure, Failure: dbDelay}},
+				8:  {{Type: ChangeSetReplicas, NodeID: "api", Replicas: 4}},
+				15: {{Type: ChangeClearFailure}, {Type: ChangeSetRPS, RPS: 800}},
+			},
+			snapshot: 8,
+			divergent: map[int][]Change{
+				9:  {{Type: ChangeSetRPS, RPS: 5000}},
+				11: {{Type: ChangeInjectFailure, Failure: apiFail}},
+			},
+			restoreAt: 13,
+		},
+		{
+			name:      "snapshot before the first tick",
+			changes:   map[int][]Change{0: {{Type: ChangeSetRPS, RPS: 2000}}, 20: {{Type: ChangeSetRPS, RPS: 500}}},
+			snapshot:  0,
+			divergent: map[int][]Change{2: {{Type: ChangeClearFailure, Failure: &FailureInjection{}}}},
+			restoreAt: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// apply makes the changes due after a tick; clearing nothing is fine
+			apply := func(e *Engine, changes map[int][]Change) {
+				for _, change := range changes[e.Tick()] {
+					if err := e.ApplyChange(change); err != nil && change.Type != ChangeClearFailure {
+						t.Fatalf("tick %d: %s: %v", e.Tick(), change.Type, err)
+					}
+				}
+			}
+			step := func(e *Engine) {
+				if _, err := e.Step(); err != nil {
+					t.Fatalf("step: %v", err)
+				}
+			}
+			finish := func(e *Engine) []byte {
+				for !e.Done() {
+					step(e)
+					apply(e, tt.changes)
+				}
+				output, err := e.Finalize()
+				if err != nil {
+					t.Fatalf("finalize: %v", err)
+				}
+				data, err := json.Marshal(output)
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				return data
+			}
+
+			want := NewEngine(snapshotTestInput())
+			if err := want.Start(); err != nil {
+				t.Fatal(err)
+			}
+			apply(want, tt.changes)
+			wantJSON := finish(want)
+
+			got := NewEngine(snapshotTestInput())
+			if err := got.Start(); err != nil {
+				t.Fatal(err)
+			}
+			apply(got, tt.changes)
+			for got.Tick() < tt.snapshot {
+				step(got)
+				apply(got, tt.changes)
+			}
+			snapshot, err := got.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for got.Tick() < tt.restoreAt {
+				step(got)
+				apply(got, tt.divergent)
+			}
+			if err := got.Restore(snapshot); err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+			if got.Tick() != tt.snapshot {
+				t.Fatalf("restored to tick %d, want %d", got.Tick(), tt.snapshot)
+			}
+			if gotJSON := finish(got); !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("restored run differs from the uninterrupted one")
+			}
+		})
+	}
+}
+
+// A snapshot saved as JSON restores into another engine and carries on
+// exactly like the run it was taken from
+func TestSnapshotRestoresFromJSON(t *testing.T) {
+	original := NewEngine(snapshotTestInput())
+	if err := original.ApplyChange(Change{Type: ChangeSetRPS, RPS: 2500}); err != nil {
+		t.Fatal(err)
+	}
+	for original.Tick() < 12 {
+		if original.Tick() == 6 {
+			if err := original.ApplyChange(Change{Type: ChangeInjectFailure, Failure: &FailureInjection{Type: FailureNodeFail, NodeID: "api", EndTick: 15}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := original.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot, err := original.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	var loaded Snapshot
+	if err := json.Unmarshal(saved, &loaded); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+
+	restored := NewEngine(&SimulationInput{})
+	if err := restored.Restore(&loaded); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Tick() != snapshot.Tick || restored.Seed() != snapshot.Seed {
+		t.Fatalf("restored to tick %d with seed %d, want tick %d with seed %d", restored.Tick(), restored.Seed(), snapshot.Tick, snapshot.Seed)
+	}
+	finish := func(e *Engine) []byte {
+		for !e.Done() {
+			if _, err := e.Step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		output, err := e.Finalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(output)
+		return data
+	}
+	if !bytes.Equal(finish(restored), finish(original)) {
+		t.Error("run restored from JSON differs from the original")
+	}
+
+	loaded.Journal = append(loaded.Journal, JournalEntry{Tick: loaded.Tick + 1, Change: Change{Type: ChangeSetRPS, RPS: 10}})
+	if err := restored.Restore(&loaded); err == nil {
+		t.Error("restored a journal with changes past the snapshot")
+	}
+	if err := restored.Restore(&Snapshot{}); err == nil {
+		t.Error("restored a snapshot without input")
+	}
+}
@@ -0,0 +1,350 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultFeedbackFactor is the share of traffic that re-enters a cycle through
+// a cycle-closing edge when the workload doesn't specify one (e.g. 10% of calls
+// from B back into A are retries/callbacks rather than fresh requests)
+const DefaultFeedbackFactor = 0.1
+
+// maxFeedbackIterations bounds the fixed-point solve inside a cycle.
+// With a feedback factor < 1 the loop gain shrinks geometrically, so this is
+// only hit for pathological factors close to 1.
+const maxFeedbackIterations = 100
+
+// feedbackTolerance is the RPS delta below which a cycle is considered settled
+const feedbackTolerance = 1e-6
+
+// CycleWarning describes a dependency cycle found in the architecture graph
+type CycleWarning struct {
+	Nodes          []string `json:"nodes"`
+	FeedbackEdges  []string `json:"feedbackEdges"`  // "source->target" edges that close the cycle
+	FeedbackFactor float64  `json:"feedbackFactor"` // Share of traffic re-entering the cycle per pass
+	Amplification  float64  `json:"amplification"`  // (Fresh + fed-back RPS) / fresh RPS entering the cycle (last tick)
+	Message        string   `json:"message"`
+}
+
+// graphTopology is the static processing plan for the architecture graph:
+// strongly connected components in topological order, each component's
+// members in input order, plus the edges that close a cycle.
+type graphTopology struct {
+	components    [][]string
+	componentOf   map[string]int
+	position      map[string]int             // index in the flattened processing order
+	parents       map[string][]string        // target -> distinct sources present in the graph
+	feedbackEdges map[string]map[string]bool // source -> target -> closes a cycle
+}
+
+// buildTopology computes the processing plan using Tarjan's SCC algorithm.
+// Components come out in reverse topological order and are flipped; ties are
+// broken by input order so the plan is stable for a given input.
+func (e *Engine) buildTopology() *graphTopology {
+	inputIndex := make(map[string]int, len(e.state.NodeOrder))
+	for idx, nodeID := range e.state.NodeOrder {
+		inputIndex[nodeID] = idx
+	}
+
+	index := 0
+	indices := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := []string{}
+	components := [][]string{}
+
+	var strongConnect func(nodeID string)
+	strongConnect = func(nodeID string) {
+		indices[nodeID] = index
+		lowLink[nodeID] = index
+		index++
+		stack = append(stack, nodeID)
+		onStack[nodeID] = true
+
+		for _, targetID := range e.state.EdgeMap[nodeID] {
+			if _, exists := e.state.NodeStates[targetID]; !exists {
+				continue
+			}
+			if _, visited := indices[targetID]; !visited {
+				strongConnect(targetID)
+				lowLink[nodeID] = minInt(lowLink[nodeID], lowLink[targetID])
+			} else if onStack[targetID] {
+				lowLink[nodeID] = minInt(lowLink[nodeID], indices[targetID])
+			}
+		}
+
+		if lowLink[nodeID] == indices[nodeID] {
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == nodeID {
+					break
+				}
+			}
+			sort.Slice(component, func(i, j int) bool {
+				return inputIndex[component[i]] < inputIndex[component[j]]
+			})
+			components = append(components, component)
+		}
+	}
+
+	for _, nodeID := range e.state.NodeOrder {
+		if _, visited := indices[nodeID]; !visited {
+			strongConnect(nodeID)
+		}
+	}
+
+	// Reverse into topological order
+	for i, j := 0, len(components)-1; i < j; i, j = i+1, j-1 {
+		components[i], components[j] = components[j], components[i]
+	}
+
+	topo := &graphTopology{
+		components:    components,
+		componentOf:   make(map[string]int),
+		position:      make(map[string]int),
+		parents:       make(map[string][]string),
+		feedbackEdges: make(map[string]map[string]bool),
+	}
+
+	pos := 0
+	for compIdx, component := range components {
+		for _, nodeID := range component {
+			topo.componentOf[nodeID] = compIdx
+			topo.position[nodeID] = pos
+			pos++
+		}
+	}
+
+	for _, nodeID := range e.state.NodeOrder {
+		seen := make(map[string]bool)
+		for _, parentID := range e.state.ReverseEdgeMap[nodeID] {
+			if _, exists := e.state.NodeStates[parentID]; exists && !seen[parentID] {
+				seen[parentID] = true
+				topo.parents[nodeID] = append(topo.parents[nodeID], parentID)
+			}
+		}
+	}
+
+	// An edge closes a cycle when it stays inside its component and points at
+	// a node processed no later than its source (self-loops included)
+	for _, sourceID := range e.state.NodeOrder {
+		for _, targetID := range e.state.EdgeMap[sourceID] {
+			if _, exists := e.state.NodeStates[targetID]; !exists {
+				continue
+			}
+			if topo.componentOf[sourceID] == topo.componentOf[targetID] && topo.position[targetID] <= topo.position[sourceID] {
+				if topo.feedbackEdges[sourceID] == nil {
+					topo.feedbackEdges[sourceID] = make(map[string]bool)
+				}
+				topo.feedbackEdges[sourceID][targetID] = true
+			}
+		}
+	}
+
+	return topo
+}
+
+// isCyclic reports whether a component contains a cycle (more than one node, or a self-loop)
+func (t *graphTopology) isCyclic(component []string) bool {
+	if len(component) > 1 {
+		return true
+	}
+	return t.feedbackEdges[component[0]][component[0]]
+}
+
+// feedbackFactor returns the configured cycle feedback factor, clamped to [0, 0.99]
+func (e *Engine) feedbackFactor() float64 {
+	factor := e.config.FeedbackFactor
+	if factor <= 0 {
+		factor = DefaultFeedbackFactor
+	}
+	return math.Min(factor, 0.99)
+}
+
+// propagateTraffic computes every node's incoming and outgoing RPS for one tick
+// by walking components in topological order. Acyclic nodes are settled in a
+// single visit; cyclic components are solved by fixed-point iteration where
+// cycle-closing edges only carry feedbackFactor of their flow. retryFlows
+// (source -> target, may be nil) is retried traffic re-sent on an edge; it
+// goes through the edge's guards and packet loss like any other call.
+func (e *Engine) propagateTraffic(entryTraffic map[string]trafficMix, retryFlows map[string]map[string]trafficMix) *tickTraffic {
+	incoming := make(map[string]trafficMix)
+	outgoing := make(map[string]trafficMix)
+	flows := make(map[string]map[string]trafficMix)
+	shed := make(map[string]map[string]trafficMix)
+	lost := make(map[string]map[string]trafficMix)
+	unrouted := make(map[string]trafficMix)
+	retried := make(map[string]map[string]trafficMix)
+	origins := make(map[string]map[string]float64)
+	originFlows := make(map[string]map[string]map[string]float64)
+	topo := e.topology
+	factor := e.feedbackFactor()
+
+	settle := func(nodeID string, total trafficMix) trafficMix {
+		out := trafficMix{}
+		if total.Total() > 0 || isQueue(e.state.NodeStates[nodeID].Type) {
+			out = e.calculateNodeOutgoing(e.state.NodeStates[nodeID], total)
+		}
+		incoming[nodeID] = total
+		outgoing[nodeID] = out
+		origins[nodeID] = e.incomingOrigins(nodeID, flows, originFlows, origins)
+		delete(unrouted, nodeID)
+		if e.isOriginAware(nodeID) && len(origins[nodeID]) > 0 {
+			flows[nodeID], originFlows[nodeID] = e.splitByOrigin(nodeID, out, origins[nodeID])
+		} else {
+			flows[nodeID], unrouted[nodeID] = e.splitTraffic(nodeID, out)
+			delete(originFlows, nodeID)
+		}
+		sent := make(map[string]float64, len(retryFlows[nodeID]))
+		for targetID, retry := range retryFlows[nodeID] {
+			flows[nodeID][targetID] = flows[nodeID][targetID].add(retry)
+			sent[targetID] = flows[nodeID][targetID].Total()
+		}
+		delete(shed, nodeID)
+		for targetID, guard := range e.guards[nodeID] {
+			if flow := flows[nodeID][targetID]; flow.Total() > 0 {
+				admitted := guard.admit(flow, e.state.NodeStates[targetID])
+				if shed[nodeID] == nil {
+					shed[nodeID] = make(map[string]trafficMix)
+				}
+				shed[nodeID][targetID] = trafficMix{Reads: flow.Reads - admitted.Reads, Writes: flow.Writes - admitted.Writes}
+				flows[nodeID][targetID] = admitted
+			}
+		}
+		delete(lost, nodeID)
+		for targetID, loss := range e.edgeLoss[nodeID] {
+			if flow := flows[nodeID][targetID]; flow.Total() > 0 {
+				if lost[nodeID] == nil {
+					lost[nodeID] = make(map[string]trafficMix)
+				}
+				lost[nodeID][targetID] = flow.scale(loss)
+				flows[nodeID][targetID] = flow.scale(1 - loss)
+			}
+		}
+		// Retries get through in the same proportion as the rest of the edge
+		delete(retried, nodeID)
+		for targetID, retry := range retryFlows[nodeID] {
+			if sent[targetID] > 0 {
+				if retried[nodeID] == nil {
+					retried[nodeID] = make(map[string]trafficMix)
+				}
+				retried[nodeID][targetID] = retry.scale(flows[nodeID][targetID].Total() / sent[targetID])
+			}
+		}
+		return out
+	}
+
+	for compIdx, component := range topo.components {
+		// Traffic entering from outside the component is final at this point
+		external := make(map[string]trafficMix, len(component))
+		for _, nodeID := range component {
+			external[nodeID] = entryTraffic[nodeID]
+			for _, parentID := range topo.parents[nodeID] {
+				if topo.componentOf[parentID] != compIdx {
+					external[nodeID] = external[nodeID].add(flows[parentID][nodeID])
+				}
+			}
+		}
+
+		if !topo.isCyclic(component) {
+			settle(component[0], external[component[0]])
+			continue
+		}
+
+		for iteration := 0; iteration < maxFeedbackIterations; iteration++ {
+			maxDelta := 0.0
+			for _, nodeID := range component {
+				total := external[nodeID]
+				for _, parentID := range topo.parents[nodeID] {
+					if topo.componentOf[parentID] != compIdx {
+						continue
+					}
+					flow := flows[parentID][nodeID]
+					if topo.feedbackEdges[parentID][nodeID] {
+						flow = flow.scale(factor)
+					}
+					total = total.add(flow)
+				}
+
+				prevIn, prevOut := incoming[nodeID], outgoing[nodeID]
+				out := settle(nodeID, total)
+				maxDelta = math.Max(maxDelta, math.Abs(total.Total()-prevIn.Total()))
+				maxDelta = math.Max(maxDelta, math.Abs(out.Total()-prevOut.Total()))
+			}
+			if maxDelta < feedbackTolerance {
+				break
+			}
+		}
+
+		// Amplification = (fresh traffic + traffic fed back around the cycle) / fresh traffic
+		externalTotal, feedbackTotal := 0.0, 0.0
+		for _, nodeID := range component {
+			externalTotal += external[nodeID].Total()
+			for _, parentID := range topo.parents[nodeID] {
+				if topo.feedbackEdges[parentID][nodeID] {
+					feedbackTotal += flows[parentID][nodeID].Total() * factor
+				}
+			}
+		}
+		if externalTotal > 0 {
+			e.cycleAmplification[compIdx] = (externalTotal + feedbackTotal) / externalTotal
+		}
+	}
+
+	return &tickTraffic{incoming: incoming, outgoing: outgoing, flows: flows, shed: shed, lost: lost, unrouted: unrouted, retried: retried, origins: origins, originFlows: originFlows}
+}
+
+// cycleWarnings summarizes the cycles in the graph for the simulation output
+func (e *Engine) cycleWarnings() []CycleWarning {
+	warnings := []CycleWarning{}
+	if e.topology == nil {
+		return warnings
+	}
+
+	factor := e.feedbackFactor()
+	for compIdx, component := range e.topology.components {
+		if !e.topology.isCyclic(component) {
+			continue
+		}
+
+		feedbackEdges := []string{}
+		for _, sourceID := range component {
+			for _, targetID := range e.state.EdgeMap[sourceID] {
+				if e.topology.feedbackEdges[sourceID][targetID] {
+					feedbackEdges = append(feedbackEdges, sourceID+"->"+targetID)
+				}
+			}
+		}
+
+		amplification := e.cycleAmplification[compIdx]
+		if amplification == 0 {
+			amplification = 1
+		}
+		amplification = math.Round(amplification*1000) / 1000
+
+		warnings = append(warnings, CycleWarning{
+			Nodes:          component,
+			FeedbackEdges:  feedbackEdges,
+			FeedbackFactor: factor,
+			Amplification:  amplification,
+			Message: fmt.Sprintf("Dependency cycle detected (%s): %.0f%% of traffic on %s re-enters the cycle, amplifying load %.2fx",
+				strings.Join(component, " ↔ "), factor*100, strings.Join(feedbackEdges, ", "), amplification),
+		})
+	}
+
+	return warnings
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,75 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// cycleInput sends a steady load into api, which calls auth, which calls
+// back into api
+func cycleInput(feedbackFactor float64) *SimulationInput {
+	seed := int64(1)
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{"replicas": 10}}},
+			{ID: "auth", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{"replicas": 10}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "auth"},
+			{ID: "e3", Source: "auth", Target: "api"},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 5, Seed: &seed, FeedbackFactor: feedbackFactor},
+	}
+}
+
+// Traffic fed back around a cycle settles at the geometric sum 1/(1-f)
+func TestCycleFeedbackConverges(t *testing.T) {
+	for _, factor := range []float64{0, 0.5, 0.9} {
+		output, err := NewEngine(cycleInput(factor)).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if factor == 0 {
+			factor = DefaultFeedbackFactor
+		}
+		want := 1 / (1 - factor)
+
+		if len(output.Cycles) != 1 {
+			t.Fatalf("f=%.1f: got %d cycles, want 1", factor, len(output.Cycles))
+		}
+		cycle := output.Cycles[0]
+		if len(cycle.FeedbackEdges) != 1 || cycle.FeedbackEdges[0] != "auth->api" || cycle.FeedbackFactor != factor {
+			t.Errorf("f=%.1f: got feedback %v at %.2f, want auth->api at %.2f", factor, cycle.FeedbackEdges, cycle.FeedbackFactor, factor)
+		}
+		if math.Abs(cycle.Amplification-want) > 0.001 {
+			t.Errorf("f=%.1f: amplification %.3f, want %.3f", factor, cycle.Amplification, want)
+		}
+		if api := lastTickMetrics(t, output, "api"); math.Abs(api.RPSIn-100*want) > 0.1 {
+			t.Errorf("f=%.1f: api got %.2f RPS, want %.2f", factor, api.RPSIn, 100*want)
+		}
+	}
+}
+
+// A factor of 1 or more would never settle; it is clamped below 1
+func TestCycleFeedbackIsClamped(t *testing.T) {
+	output, err := NewEngine(cycleInput(5)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle := output.Cycles[0]
+	if cycle.FeedbackFactor != 0.99 || math.IsInf(cycle.Amplification, 0) || math.IsNaN(cycle.Amplification) {
+		t.Errorf("got factor %.2f and amplification %v, want 0.99 and a finite amplification", cycle.FeedbackFactor, cycle.Amplification)
+	}
+}
+
+func TestAcyclicGraphHasNoCycles(t *testing.T) {
+	output, err := NewEngine(validationInput()).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Cycles) != 0 {
+		t.Errorf("got cycles %v in a chain", output.Cycles)
+	}
+}
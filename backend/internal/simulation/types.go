@@ -6,11 +6,35 @@ import "time"
 type WorkloadConfig struct {
 	RPS             int                `json:"rps"`
 	ReadWriteRatio  ReadWriteRatio     `json:"readWriteRatio"`
-	Mode            string             `json:"mode"` // "constant", "burst", "spike"
+	Mode            string             `json:"mode"`            // "constant", "burst", "spike", "ramp", "diurnal", "poisson", "piecewise", "trace"
+	Shape           *WorkloadShape     `json:"shape,omitempty"` // Parameters for the shaped modes
 	Regions         []string           `json:"regions"`
-	DurationSeconds int                `json:"durationSeconds"`
+	UserRegions     map[string]float64 `json:"userDistribution,omitempty"` // User region -> share of traffic (weights, normalized)
+	DurationSeconds int                `json:"durationSeconds"`            // Simulated seconds, one tick each
 	AutoScaling     *AutoScalingConfig `json:"autoScaling,omitempty"`
 	Failures        []FailureInjection `json:"failures,omitempty"`
+	Seed            *int64             `json:"seed,omitempty"`           // Optional RNG seed; same input + seed = same output
+	FeedbackFactor  float64            `json:"feedbackFactor,omitempty"` // Share of traffic re-entering a dependency cycle (default 0.1)
+}
+
+// WorkloadShape parameterizes the shaped workload modes. Only the fields of
+// the selected mode are read.
+type WorkloadShape struct {
+	StartRPS      float64         `json:"startRps,omitempty"`      // Ramp: RPS at the first tick
+	RampSeconds   int             `json:"rampSeconds,omitempty"`   // Ramp: time to reach rps (default: whole run)
+	Steps         int             `json:"steps,omitempty"`         // Ramp: climb in this many equal steps instead of linearly
+	PeriodSeconds int             `json:"periodSeconds,omitempty"` // Diurnal: cycle length (default: whole run)
+	Amplitude     float64         `json:"amplitude,omitempty"`     // Diurnal: swing around rps as a fraction of it (default 0.5)
+	PhaseSeconds  int             `json:"phaseSeconds,omitempty"`  // Diurnal: shifts where in the cycle the run starts
+	Points        []WorkloadPoint `json:"points,omitempty"`        // Piecewise/trace: RPS schedule
+	Interpolate   bool            `json:"interpolate,omitempty"`   // Piecewise: ramp linearly between points instead of holding
+	Scale         float64         `json:"scale,omitempty"`         // Piecewise/trace: multiplies every point (replay a trace at 2x)
+}
+
+// WorkloadPoint is the RPS from a tick onward in a piecewise schedule or trace
+type WorkloadPoint struct {
+	Tick int     `json:"tick"`
+	RPS  float64 `json:"rps"`
 }
 
 // ReadWriteRatio defines read/write distribution
@@ -29,14 +53,55 @@ type AutoScalingConfig struct {
 	MaxReplicas     int     `json:"maxReplicas"`
 }
 
+// AutoScalingPolicy is a per-node autoscaling policy, read from the node's
+// "autoscaling" config. It overrides the workload-wide AutoScalingConfig.
+type AutoScalingPolicy struct {
+	Enabled                bool          `json:"enabled"`
+	Metric                 string        `json:"metric"`                           // "cpu" (default), "rps", "queue_depth", "load"
+	TargetValue            float64       `json:"targetValue,omitempty"`            // Target tracking: desired metric value per replica
+	Steps                  []ScalingStep `json:"steps,omitempty"`                  // Step scaling: used instead of target tracking when set
+	MinReplicas            int           `json:"minReplicas,omitempty"`            // Default 1
+	MaxReplicas            int           `json:"maxReplicas,omitempty"`            // 0 = unbounded
+	CooldownSeconds        int           `json:"cooldownSeconds,omitempty"`        // Minimum time between scale-outs
+	ScaleInCooldownSeconds int           `json:"scaleInCooldownSeconds,omitempty"` // Minimum time after any scaling before scaling in (default: cooldownSeconds)
+	ScaleInProtection      bool          `json:"scaleInProtection,omitempty"`      // Never remove replicas
+	ProvisioningDelayTicks *int          `json:"provisioningDelayTicks,omitempty"` // Ticks a new replica boots before serving (default 3)
+}
+
+// ScalingStep adjusts the replica count while lowerBound <= metric < upperBound
+type ScalingStep struct {
+	LowerBound *float64 `json:"lowerBound,omitempty"`
+	UpperBound *float64 `json:"upperBound,omitempty"`
+	Adjustment int      `json:"adjustment"` // Replicas to add (negative removes)
+}
+
+// FailureType names a kind of injected fault (see the Failure* constants)
+type FailureType string
+
 // FailureInjection defines fault scenarios
 type FailureInjection struct {
-	Type      string `json:"type"` // "nodeFail", "regionFail", "cacheFail", "dbFail", "networkDelay"
-	NodeID    string `json:"nodeId,omitempty"`
-	Region    string `json:"region,omitempty"`
-	DelayMs   int    `json:"delayMs,omitempty"`
-	StartTick int    `json:"startTick,omitempty"`
-	EndTick   int    `json:"endTick,omitempty"`
+	Type        FailureType      `json:"type"`
+	NodeID      string           `json:"nodeId,omitempty"`
+	EdgeID      string           `json:"edgeId,omitempty"` // packetLoss: the edge dropping packets (default: every edge into nodeId)
+	Region      string           `json:"region,omitempty"`
+	Zone        string           `json:"availabilityZone,omitempty"` // With regionFail/networkDelay: only that AZ of the region
+	DelayMs     int              `json:"delayMs,omitempty"`
+	SkewMs      int              `json:"skewMs,omitempty"`      // clockSkew: how far the node's clock is off
+	Severity    float64          `json:"severity,omitempty"`    // 0-100; what it scales depends on the type (see DefaultFaultSeverity)
+	Probability float64          `json:"probability,omitempty"` // Chance each occurrence is actually injected (0 = always)
+	StartTick   int              `json:"startTick,omitempty"`
+	EndTick     int              `json:"endTick,omitempty"`
+	Schedule    *FailureSchedule `json:"schedule,omitempty"` // Repeat the fault between startTick and endTick
+}
+
+// FailureSchedule turns a failure into a game-day experiment that recurs on a
+// fixed interval or at random
+type FailureSchedule struct {
+	Type            string `json:"type"`                    // "recurring" or "random"
+	IntervalSeconds int    `json:"intervalSeconds"`         // Recurring: period between starts; random: mean gap between starts
+	DurationSeconds int    `json:"durationSeconds"`         // How long each occurrence lasts (default 10)
+	JitterSeconds   int    `json:"jitterSeconds,omitempty"` // Recurring: delay each start by up to this much
+	Occurrences     int    `json:"occurrences,omitempty"`   // Stop after this many (0 = until endTick)
 }
 
 // SimulationInput contains the architecture and workload (enhanced for Module 5)
@@ -45,6 +110,9 @@ type SimulationInput struct {
 	Edges     []SimEdge      `json:"edges"`
 	Workload  WorkloadConfig `json:"workload"`
 	SLAConfig *SLAConfig     `json:"slaConfig,omitempty"`
+
+	// Hardware sizes and bills the nodes; nil uses the built-in tables
+	Hardware HardwareProvider `json:"-"`
 }
 
 // SimNode represents a node in the architecture
@@ -64,9 +132,49 @@ type SimNodeData struct {
 
 // SimEdge represents a connection between nodes
 type SimEdge struct {
-	ID     string `json:"id"`
-	Source string `json:"source"`
-	Target string `json:"target"`
+	ID     string      `json:"id"`
+	Source string      `json:"source"`
+	Target string      `json:"target"`
+	Data   SimEdgeData `json:"data"`
+}
+
+// SimEdgeData contains edge routing configuration
+type SimEdgeData struct {
+	Weight        *float64 `json:"weight,omitempty"`        // Relative share of the parent's traffic (e.g. 90 / 10); default 1
+	RoutingPolicy string   `json:"routingPolicy,omitempty"` // "weighted", "round_robin", "least_loaded", "failover", "mirror"
+	Priority      int      `json:"priority,omitempty"`      // Failover order: lowest value is the primary
+	MirrorPercent float64  `json:"mirrorPercent,omitempty"` // Share of parent traffic copied on a mirror edge (0-100, default 100)
+
+	// Caller-side timeout/retry policy; unset fields fall back to the source node's config
+	TimeoutMs         float64 `json:"timeoutMs,omitempty"`         // Calls slower than this fail on the caller side
+	Retries           *int    `json:"retries,omitempty"`           // Retries after the first attempt (0 disables)
+	BackoffMs         float64 `json:"backoffMs,omitempty"`         // Delay before the first retry
+	BackoffMultiplier float64 `json:"backoffMultiplier,omitempty"` // Delay growth per retry (default 2 = exponential)
+	Jitter            float64 `json:"jitter,omitempty"`            // 0-1 share of the delay that is randomized
+
+	// Resilience policies guarding the target; shed calls fail fast at the caller
+	CircuitBreaker *CircuitBreakerConfig `json:"circuitBreaker,omitempty"`
+	RateLimit      *RateLimitConfig      `json:"rateLimit,omitempty"`
+	Bulkhead       *BulkheadConfig       `json:"bulkhead,omitempty"`
+}
+
+// CircuitBreakerConfig trips the edge open when the target fails too often
+type CircuitBreakerConfig struct {
+	ErrorThresholdPercent float64 `json:"errorThresholdPercent,omitempty"` // Failing calls that trip the breaker (default 50)
+	MinRPS                float64 `json:"minRPS,omitempty"`                // Minimum calls per tick before tripping (default 10)
+	OpenSeconds           int     `json:"openSeconds,omitempty"`           // Time open before going half-open (default 5)
+	HalfOpenPercent       float64 `json:"halfOpenPercent,omitempty"`       // Calls let through as probes while half-open (default 10)
+}
+
+// RateLimitConfig is a token bucket refilled at RPS up to Burst tokens
+type RateLimitConfig struct {
+	RPS   float64 `json:"rps"`
+	Burst float64 `json:"burst,omitempty"` // Bucket size (default: one second of RPS)
+}
+
+// BulkheadConfig caps concurrent calls to the target
+type BulkheadConfig struct {
+	MaxConcurrent int `json:"maxConcurrent"`
 }
 
 // SimulationOutput contains the results (enhanced for Module 5)
@@ -76,7 +184,12 @@ type SimulationOutput struct {
 	Bottlenecks   []Bottleneck      `json:"bottlenecks"`
 	SLAViolations []string          `json:"slaViolations"`
 	CostMetrics   CostMetrics       `json:"costMetrics"`
-	Duration      time.Duration     `json:"duration"`
+	CriticalPaths []CriticalPath    `json:"criticalPaths"`      // Slowest client->leaf paths with per-hop latency
+	Cycles        []CycleWarning    `json:"cycles,omitempty"`   // Dependency cycles and their feedback amplification
+	Faults        []FaultReport     `json:"faults,omitempty"`   // Every injected fault occurrence and its blast radius
+	Warnings      []string          `json:"warnings,omitempty"` // Non-fatal issues found while simulating
+	Duration      time.Duration     `json:"duration"`           // Simulated time the ticks cover, not compute time, so replays are byte-identical
+	Seed          int64             `json:"seed"`               // Seed that drove this run (pass it back in workload.seed to replay)
 	Success       bool              `json:"success"`
 	Error         string            `json:"error,omitempty"`
 }
@@ -91,7 +204,15 @@ type AggregateMetrics struct {
 	TotalRequests      int                `json:"totalRequests"`
 	SuccessfulRequests int                `json:"successfulRequests"`
 	FailedRequests     int                `json:"failedRequests"`
+	RetriedRequests    int                `json:"retriedRequests"`              // Failed attempts that were retried
+	RetryAmplification map[string]float64 `json:"retryAmplification,omitempty"` // Node -> total load / load it would see without retries
 	AutoscalingEvents  []AutoscalingEvent `json:"autoscalingEvents"`
+	OriginLatency      map[string]float64 `json:"originLatency,omitempty"`           // User region -> average end-to-end latency, access network included
+	StaleReads         int                `json:"staleReads,omitempty"`              // Reads served by lagging async replicas
+	ColdStarts         int                `json:"coldStarts,omitempty"`              // Function invocations that started an environment
+	Throttles          int                `json:"throttles,omitempty"`               // Function invocations rejected over the concurrency limit
+	WriteDowntime      map[string]int     `json:"writeUnavailableSeconds,omitempty"` // Primary database -> seconds writes failed
+	Failovers          []FailoverEvent    `json:"failoverEvents,omitempty"`
 }
 
 // LatencyMetrics contains latency percentiles
@@ -120,11 +241,19 @@ type TimeSeriesPoint struct {
 	NetworkLatencyMs   float64                `json:"networkLatencyMs"`
 	RegionLatencyMap   map[string]float64     `json:"regionLatencyMap"`
 	RegionTrafficMap   map[string]float64     `json:"regionTrafficMap"`
-	RegionErrorRateMap map[string]float64     `json:"regionErrorRateMap"` // Error rate percentage per region
+	RegionErrorRateMap map[string]float64     `json:"regionErrorRateMap"`         // Error rate percentage per region
+	ZoneLatencyMap     map[string]float64     `json:"zoneLatencyMap,omitempty"`   // Average node latency per "region/az"
+	OriginLatencyMap   map[string]float64     `json:"originLatencyMap,omitempty"` // User region -> end-to-end latency its users saw
+	WriteUnavailable   []string               `json:"writeUnavailable,omitempty"` // Primary databases not accepting writes this tick
+	ColdStarts         int                    `json:"coldStarts,omitempty"`       // Function invocations that started an environment this tick
+	Throttles          int                    `json:"throttles,omitempty"`        // Function invocations throttled this tick
 	NodeMetrics        map[string]NodeMetrics `json:"nodeMetrics"`
 	FailuresActive     []string               `json:"failuresActive"`
-	SLAStatus          string                 `json:"slaStatus"`     // GOOD/WARNING/FAIL
-	ScalingEvents      []AutoscalingEvent     `json:"scalingEvents"` // Auto-scaling events at this tick
+	SLAStatus          string                 `json:"slaStatus"`                   // GOOD/WARNING/FAIL
+	ScalingEvents      []AutoscalingEvent     `json:"scalingEvents"`               // Auto-scaling events at this tick
+	BreakerStates      map[string]string      `json:"breakerStates,omitempty"`     // Edge ID -> closed/open/half_open
+	RateLimiterTokens  map[string]float64     `json:"rateLimiterTokens,omitempty"` // Edge ID -> tokens left in the bucket
+	BulkheadInFlight   map[string]float64     `json:"bulkheadInFlight,omitempty"`  // Edge ID -> concurrent calls
 }
 
 // NodeMetrics represents detailed metrics for a single node
@@ -139,11 +268,24 @@ type NodeMetrics struct {
 	NetworkPercent float64 `json:"networkPercent,omitempty"` // NEW: Network utilization
 	Errors         int     `json:"errors"`
 	QueueDepth     int     `json:"queueDepth"`
+	QueueWaitMs    float64 `json:"queueWaitMs,omitempty"`  // Age of messages delivered this tick
+	DeadLettered   int     `json:"deadLettered,omitempty"` // Messages moved to the DLQ so far
 	CacheHitRate   float64 `json:"cacheHitRate"`
-	Status         string  `json:"status"`               // normal/warning/danger/failed
-	SuccessRate    float64 `json:"successRate"`          // percentage of successful requests (0-100)
-	Replicas       int     `json:"replicas"`             // Current replica count (for auto-scaling visualization)
-	Bottleneck     string  `json:"bottleneck,omitempty"` // "cpu", "memory", "disk", "network", "none"
+	Status         string  `json:"status"`                         // normal/warning/danger/failed
+	SuccessRate    float64 `json:"successRate"`                    // percentage of successful requests (0-100)
+	ReadRPS        float64 `json:"readRPS"`                        // Reads arriving at the node
+	WriteRPS       float64 `json:"writeRPS"`                       // Writes arriving at the node
+	RetryRPS       float64 `json:"retryRPS,omitempty"`             // Retried calls that reached the node
+	Amplification  float64 `json:"retryAmplification"`             // Load this tick / load without retries
+	ReplicationLag float64 `json:"replicationLagMs,omitempty"`     // Async replicas: lag behind the primary
+	StaleReadRPS   float64 `json:"staleReadRPS,omitempty"`         // Async replicas: potentially stale reads served
+	ColdStarts     int     `json:"coldStarts,omitempty"`           // Functions: invocations that started an environment
+	Throttles      int     `json:"throttles,omitempty"`            // Functions: invocations rejected over the concurrency limit
+	Concurrency    float64 `json:"concurrency,omitempty"`          // Functions: environments busy at once
+	WarmPool       int     `json:"warmEnvironments,omitempty"`     // Functions: initialized environments, provisioned included
+	Replicas       int     `json:"replicas"`                       // Current replica count (for auto-scaling visualization)
+	Provisioning   int     `json:"provisioningReplicas,omitempty"` // Replicas booting, not yet serving
+	Bottleneck     string  `json:"bottleneck,omitempty"`           // "cpu", "memory", "disk", "network", "none"
 }
 
 // Bottleneck represents a detected performance bottleneck
@@ -181,6 +323,40 @@ type AutoscalingEvent struct {
 	OldValue int    `json:"oldValue"`
 	NewValue int    `json:"newValue"`
 	Reason   string `json:"reason"`
+
+	Metric      string  `json:"metric,omitempty"`    // Metric the policy tracked ("cpu", "rps", "queue_depth", "load")
+	MetricValue float64 `json:"metricValue"`         // Value that triggered the decision
+	ReadyTick   int     `json:"readyTick,omitempty"` // Scale-out: tick the new replicas start serving
+}
+
+// FaultReport is one occurrence of an injected fault
+type FaultReport struct {
+	Type        FailureType  `json:"type"`
+	Target      string       `json:"target,omitempty"` // Node, edge, region or "region/az" the fault hit
+	Severity    float64      `json:"severity,omitempty"`
+	StartTick   int          `json:"startTick"`
+	EndTick     int          `json:"endTick"`
+	Injected    bool         `json:"injected"` // False when the occurrence was skipped by its probability
+	Events      []string     `json:"events,omitempty"`
+	BlastRadius *BlastRadius `json:"blastRadius,omitempty"`
+}
+
+// BlastRadius measures what a fault degraded while it was active, compared
+// with the fault-free ticks of the run
+type BlastRadius struct {
+	AffectedNodes      []string `json:"affectedNodes"`        // Nodes that failed, erred, slowed down or lost their traffic
+	ErrorRateIncrease  float64  `json:"errorRateIncrease"`    // Percentage points
+	P99LatencyIncrease float64  `json:"p99LatencyIncreaseMs"` // Milliseconds
+	FailedRequests     int      `json:"failedRequests"`       // Requests that failed while the fault was active
+}
+
+// FailoverEvent records a database standby/replica being promoted after its primary failed
+type FailoverEvent struct {
+	Tick            int    `json:"tick"`
+	NodeID          string `json:"nodeId"`               // Primary whose endpoint failed over
+	PromotedID      string `json:"promotedId,omitempty"` // Replica node promoted (empty for a Multi-AZ standby)
+	DowntimeSeconds int    `json:"downtimeSeconds"`      // Writes were unavailable this long before promotion
+	Reason          string `json:"reason"`
 }
 
 // NodeState tracks runtime state of a node (enhanced for Module 5)
@@ -190,6 +366,9 @@ type NodeState struct {
 	InstanceType    string // e.g., "c5.2xlarge", "db.m5.large"
 	StorageType     string // e.g., "gp3", "io2" (NEW - for disk performance)
 	LBType          string // e.g., "alb", "nlb"
+	QueueType       string // e.g., "sqs-standard", "kafka-medium"
+	CDNType         string // e.g., "cloudfront-basic"
+	SearchType      string // e.g., "es-medium" ("" = self-managed)
 	AccessType      string // e.g., "internal", "external" (NEW)
 	CapacityRPS     float64
 	BaseCapacityRPS float64 // ORIGINAL capacity (for restoring after throttle)
@@ -200,6 +379,7 @@ type NodeState struct {
 	TTL             int
 	Consistency     string
 	Region          string
+	Zone            string // Availability zone within Region ("" = not placed in a specific AZ)
 	CurrentLoad     float64
 	RPSIn           float64
 	RPSOut          float64
@@ -211,8 +391,29 @@ type NodeState struct {
 	DiskIOUsage     float64 // NEW - for disk I/O tracking
 	ErrorCount      int
 	Failed          bool
-	Partitioned     bool // NEW - Network partition (drops all outgoing traffic)
-	ReadRatio       int  // Percentage of operations that are reads (0-100)
+	Partitioned     bool    // NEW - Network partition (drops all outgoing traffic)
+	ReadRatio       int     // Percentage of operations that are reads (0-100); follows the observed mix once traffic arrives
+	Role            string  // "primary" (default) or "replica" - read replicas only accept reads
+	ReadRPS         float64 // Reads arriving this tick
+	WriteRPS        float64 // Writes arriving this tick
+	ConsumerRate    float64 // Queues: messages/sec consumers pull (0 = downstream capacity)
+	QueueWaitMS     float64 // Queues: age of messages delivered this tick
+	DeadLettered    int     // Queues: messages moved to the DLQ so far
+	RejectedRPS     float64 // Requests this node failed this tick (down, overloaded, read-only)
+	RetryRPS        float64 // Retried calls that reached this node this tick
+	Amplification   float64 // Load this tick / load it would see without retries (retry storms)
+	ReplicaLagMS    float64 // Async replicas: how far behind the primary they are
+	StaleReadRPS    float64 // Async replicas: reads served this tick that may be stale
+	ColdStarts      float64 // Functions: invocations this tick that started an environment
+	ThrottledRPS    float64 // Functions: invocations rejected this tick over the concurrency limit
+
+	backlog  []queueBatch   // Queues: FIFO of messages waiting for consumers
+	scaling  *scalingState  // Autoscaling policy and history (nil = fixed replicas)
+	cache    *cacheModel    // Caches/CDNs: what the hit ratio is derived from
+	function *functionModel // Serverless functions: warm pool and concurrency
+	faults   nodeFaults     // Fault effects injected this tick
+	memory   float64        // MemoryUsage before leaked memory
+	network  float64        // Cross-region/AZ part of LatencyMS (average over incoming edges)
 }
 
 // SimulationState tracks the entire simulation state (enhanced for Module 5)
@@ -220,19 +421,37 @@ type SimulationState struct {
 	Tick               int
 	CurrentWorkloadRPS float64
 	NodeStates         map[string]*NodeState
+	NodeOrder          []string            // Node IDs in input order (deterministic iteration over NodeStates)
 	EdgeMap            map[string][]string // source -> targets
 	ReverseEdgeMap     map[string][]string // target -> sources
-	LatencyHistory     []float64
+	LatencyHistogram   map[float64]float64 // End-to-end latency (ms, 0.01 buckets) -> requests that saw it
 	ThroughputHistory  []float64
 	ErrorHistory       []int
 	QueueHistory       []int
 	TotalRequests      int
 	SuccessRequests    int
 	FailedRequests     int
+	RetriedRequests    int
+	StaleReads         int // Reads served by lagging async replicas
+	ColdStarts         int // Function invocations that started an environment
+	Throttles          int // Function invocations rejected over the concurrency limit
 	DroppedRequests    int
 	CacheHits          int
 	CacheMisses        int
 	ActiveFailures     []string
 	RegionLatency      map[string][]float64
 	RegionTraffic      map[string]float64
+
+	// Deprecated: LatencyHistory holds every node's latency each tick, which
+	// isn't what requests see end to end. Use LatencyHistogram.
+	LatencyHistory []float64
+
+	paths         map[string]*pathStats  // Path key ("a>b>c") -> latency accumulated across ticks
+	retries       map[int][]pendingRetry // Tick -> retries due then
+	attemptedLoad map[string]float64     // Node -> RPS summed over the run, retries included
+	freshLoad     map[string]float64     // Node -> RPS summed over the run, without retries
+	originLatency map[string]float64     // User region -> latency * RPS summed over the run
+	originRPS     map[string]float64     // User region -> RPS summed over the run
+	writeDowntime map[string]int         // Primary database -> seconds it couldn't take writes
+	failovers     []FailoverEvent
 }
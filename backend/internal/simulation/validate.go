@@ -0,0 +1,396 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"   // The input can't be simulated as meant
+	SeverityWarning = "warning" // It runs, but probably not the way it was meant to
+)
+
+// Diagnostic codes
+const (
+	DiagNoNodes              = "no_nodes"
+	DiagMissingNodeID        = "missing_node_id"
+	DiagDuplicateNodeID      = "duplicate_node_id"
+	DiagMissingNodeType      = "missing_node_type"
+	DiagUnknownNodeType      = "unknown_node_type"
+	DiagUnknownInstanceType  = "unknown_instance_type"
+	DiagInvalidReplicas      = "invalid_replicas"
+	DiagUnknownEdgeSource    = "unknown_edge_source"
+	DiagUnknownEdgeTarget    = "unknown_edge_target"
+	DiagSelfLoop             = "self_loop"
+	DiagDuplicateEdge        = "duplicate_edge"
+	DiagConnectionNotAllowed = "connection_not_allowed"
+	DiagNoEntryNode          = "no_entry_node"
+	DiagNoReachablePath      = "no_reachable_path"
+	DiagUnreachableNode      = "unreachable_node"
+	DiagInvalidRPS           = "invalid_rps"
+	DiagMissingSchedule      = "missing_schedule"
+	DiagUnknownWorkloadMode  = "unknown_workload_mode"
+	DiagInvalidReadWrite     = "invalid_read_write_ratio"
+	DiagUnknownFailureType   = "unknown_failure_type"
+	DiagUnknownFailureNode   = "unknown_failure_node"
+	DiagUnknownFailureEdge   = "unknown_failure_edge"
+)
+
+// Diagnostic is one problem found in a simulation input, pointing at the
+// node, edge and field it concerns
+type Diagnostic struct {
+	Severity   string `json:"severity"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	NodeID     string `json:"nodeId,omitempty"`
+	EdgeID     string `json:"edgeId,omitempty"`
+	Field      string `json:"field,omitempty"`      // Path into the input, e.g. nodes[2].data.config.instanceType
+	Suggestion string `json:"suggestion,omitempty"` // How to fix it
+}
+
+// ValidationResult is every diagnostic found in an input
+type ValidationResult struct {
+	Valid    bool         `json:"valid"` // No errors; warnings don't block a run
+	Errors   []Diagnostic `json:"errors"`
+	Warnings []Diagnostic `json:"warnings"`
+}
+
+// ConnectionRule allows edges from one node type to another. Types that
+// appear in no rule aren't checked.
+type ConnectionRule struct {
+	SourceType string `json:"sourceType"`
+	TargetType string `json:"targetType"`
+}
+
+// workloadModes are the workload modes generateWorkload knows
+var workloadModes = []string{"constant", "burst", "spike", WorkloadRamp, WorkloadDiurnal, WorkloadPoisson, WorkloadPiecewise, WorkloadTrace}
+
+// failureTypes are the failures the chaos engine can inject
+var failureTypes = []string{
+	string(FailureNodeFail), string(FailureRegionFail), string(FailureAZOutage), string(FailureCacheFail),
+	string(FailureDBFail), string(FailureNetworkDelay), string(FailureNodeLatency), string(FailureThrottle),
+	string(FailurePartition), string(FailurePacketLoss), string(FailureSlowDisk), string(FailureMemoryLeak),
+	string(FailureCPUSteal), string(FailureDependencyErrors), string(FailureDNS), string(FailureClockSkew),
+}
+
+// validator collects the diagnostics of one input
+type validator struct {
+	input  *SimulationInput
+	result *ValidationResult
+	nodes  map[string]string // Node ID -> type
+	order  []string          // Node IDs in input order, each once
+}
+
+// ValidateInput checks an input without simulating it: the graph (edges to
+// missing nodes, node and instance types, whether traffic reaches anything,
+// the connection rules) and the workload. Rules may be nil.
+func ValidateInput(input *SimulationInput, rules []ConnectionRule) *ValidationResult {
+	v := &validator{
+		input:  input,
+		result: &ValidationResult{Errors: []Diagnostic{}, Warnings: []Diagnostic{}},
+		nodes:  make(map[string]string),
+	}
+	v.checkNodes()
+	v.checkEdges(rules)
+	v.checkReachability()
+	v.checkWorkload()
+	v.result.Valid = len(v.result.Errors) == 0
+	return v.result
+}
+
+// add records a diagnostic under its severity
+func (v *validator) add(d Diagnostic) {
+	if d.Severity == SeverityError {
+		v.result.Errors = append(v.result.Errors, d)
+	} else {
+		v.result.Warnings = append(v.result.Warnings, d)
+	}
+}
+
+// checkNodes checks node IDs, types and the instance types they run on
+func (v *validator) checkNodes() {
+	if len(v.input.Nodes) == 0 {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagNoNodes, Message: "The architecture has no nodes", Field: "nodes",
+			Suggestion: "Add at least a client and a node for it to call"})
+		return
+	}
+
+	for i, node := range v.input.Nodes {
+		path := fmt.Sprintf("nodes[%d]", i)
+		if node.ID == "" {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagMissingNodeID, Message: "Node has no ID", Field: path + ".id"})
+			continue
+		}
+		if _, exists := v.nodes[node.ID]; exists {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagDuplicateNodeID, NodeID: node.ID, Field: path + ".id",
+				Message:    fmt.Sprintf("Node ID %q is used more than once", node.ID),
+				Suggestion: "Give every node its own ID; later nodes replace earlier ones with the same ID"})
+		} else {
+			v.order = append(v.order, node.ID)
+		}
+		nodeType := node.Data.NodeType
+		v.nodes[node.ID] = nodeType
+
+		switch {
+		case nodeType == "":
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagMissingNodeType, NodeID: node.ID, Field: path + ".data.nodeType",
+				Message: fmt.Sprintf("Node %q has no type", node.ID)})
+		case !isKnownNodeType(nodeType):
+			v.add(Diagnostic{Severity: SeverityWarning, Code: DiagUnknownNodeType, NodeID: node.ID, Field: path + ".data.nodeType",
+				Message:    fmt.Sprintf("Unknown node type %q; it is simulated with default capacity (1000 RPS, 10 ms)", nodeType),
+				Suggestion: suggestion(nodeType, knownNodeTypes())})
+		}
+
+		v.checkInstanceType(node, path)
+		if replicas, ok := node.Data.Config["replicas"]; ok && getInt(node.Data.Config, "replicas", 1) < 1 {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagInvalidReplicas, NodeID: node.ID, Field: path + ".data.config.replicas",
+				Message: fmt.Sprintf("Node %q has %v replicas", node.ID, replicas), Suggestion: "Use at least 1 replica"})
+		}
+	}
+}
+
+// checkInstanceType warns about instance types the hardware catalog doesn't
+// have, which silently fall back to a default size
+func (v *validator) checkInstanceType(node SimNode, path string) {
+	instanceType := getString(node.Data.Config, "instanceType", "")
+	if instanceType == "" {
+		return
+	}
+
+	table := instanceTableOf(node.Data.NodeType)
+	if table == nil {
+		return
+	}
+	known := table.instanceTypes(v.input.hardware())
+	if contains(known, instanceType) {
+		return
+	}
+	v.add(Diagnostic{Severity: SeverityWarning, Code: DiagUnknownInstanceType, NodeID: node.ID, Field: path + ".data.config.instanceType",
+		Message:    fmt.Sprintf("Unknown instance type %q for %s; it is simulated as a %s", instanceType, node.Data.NodeType, table.fallback),
+		Suggestion: suggestion(instanceType, known)})
+}
+
+// checkEdges checks that edges join existing nodes, and the connection rules
+func (v *validator) checkEdges(rules []ConnectionRule) {
+	allowed := make(map[string][]string) // Source type -> allowed target types
+	ruled := make(map[string]bool)       // Types some rule mentions
+	for _, rule := range rules {
+		allowed[rule.SourceType] = append(allowed[rule.SourceType], rule.TargetType)
+		ruled[rule.SourceType], ruled[rule.TargetType] = true, true
+	}
+
+	seen := make(map[string]string) // "source->target" -> first edge ID
+	for i, edge := range v.input.Edges {
+		path := fmt.Sprintf("edges[%d]", i)
+		sourceType, sourceOK := v.nodes[edge.Source]
+		targetType, targetOK := v.nodes[edge.Target]
+		if !sourceOK {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagUnknownEdgeSource, EdgeID: edge.ID, Field: path + ".source",
+				Message:    fmt.Sprintf("Edge %q starts at node %q, which doesn't exist", edge.ID, edge.Source),
+				Suggestion: suggestion(edge.Source, v.order)})
+		}
+		if !targetOK {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagUnknownEdgeTarget, EdgeID: edge.ID, Field: path + ".target",
+				Message:    fmt.Sprintf("Edge %q ends at node %q, which doesn't exist", edge.ID, edge.Target),
+				Suggestion: suggestion(edge.Target, v.order)})
+		}
+		if !sourceOK || !targetOK {
+			continue
+		}
+
+		if edge.Source == edge.Target {
+			v.add(Diagnostic{Severity: SeverityWarning, Code: DiagSelfLoop, NodeID: edge.Source, EdgeID: edge.ID, Field: path,
+				Message:    fmt.Sprintf("Edge %q connects node %q to itself", edge.ID, edge.Source),
+				Suggestion: "Remove the edge; a node calling itself only feeds back its own load"})
+		}
+		key := edge.Source + "->" + edge.Target
+		if first, exists := seen[key]; exists {
+			v.add(Diagnostic{Severity: SeverityWarning, Code: DiagDuplicateEdge, EdgeID: edge.ID, Field: path,
+				Message:    fmt.Sprintf("Edge %q duplicates edge %q from %q to %q", edge.ID, first, edge.Source, edge.Target),
+				Suggestion: "Remove one of them; each copy takes its own share of the traffic"})
+		} else {
+			seen[key] = edge.ID
+		}
+
+		// The catalog only speaks for the types it knows
+		if !ruled[sourceType] || !ruled[targetType] || contains(allowed[sourceType], targetType) {
+			continue
+		}
+		fix := fmt.Sprintf("Nothing may be called from %s", sourceType)
+		if targets := allowed[sourceType]; len(targets) > 0 {
+			sorted := append([]string(nil), targets...)
+			sort.Strings(sorted)
+			fix = fmt.Sprintf("%s may connect to: %s", sourceType, strings.Join(sorted, ", "))
+		}
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagConnectionNotAllowed, NodeID: edge.Source, EdgeID: edge.ID, Field: path,
+			Message:    fmt.Sprintf("A %s can't connect to a %s (edge %q)", sourceType, targetType, edge.ID),
+			Suggestion: fix})
+	}
+}
+
+// checkReachability follows the edges from the entry nodes, the way the
+// engine routes traffic, to find what never receives any
+func (v *validator) checkReachability() {
+	if len(v.order) == 0 {
+		return
+	}
+
+	next := make(map[string][]string)
+	incoming := make(map[string]int)
+	for _, edge := range v.input.Edges {
+		_, sourceOK := v.nodes[edge.Source]
+		_, targetOK := v.nodes[edge.Target]
+		if sourceOK && targetOK {
+			next[edge.Source] = append(next[edge.Source], edge.Target)
+			incoming[edge.Target]++
+		}
+	}
+
+	// Same entry points as findEntryNodes: clients, else nodes nothing calls
+	entries := []string{}
+	for _, nodeID := range v.order {
+		if isClientType(v.nodes[nodeID]) {
+			entries = append(entries, nodeID)
+		}
+	}
+	if len(entries) == 0 {
+		for _, nodeID := range v.order {
+			if incoming[nodeID] == 0 {
+				entries = append(entries, nodeID)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagNoEntryNode, Field: "nodes",
+			Message:    "No node receives the workload: there is no client and every node is called by another",
+			Suggestion: "Add a client node and connect it to the front of the architecture"})
+		return
+	}
+
+	reached := make(map[string]bool)
+	queue := append([]string(nil), entries...)
+	for _, nodeID := range entries {
+		reached[nodeID] = true
+	}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, target := range next[nodeID] {
+			if !reached[target] {
+				reached[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	served := false
+	for nodeID := range reached {
+		served = served || !isClientType(v.nodes[nodeID])
+	}
+	if !served {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagNoReachablePath, NodeID: entries[0], Field: "edges",
+			Message:    "No path leads from a client to any node, so no request is served",
+			Suggestion: fmt.Sprintf("Add an edge from %q to the node that should receive its traffic", entries[0])})
+		return
+	}
+	for i, node := range v.input.Nodes {
+		if node.ID == "" || reached[node.ID] {
+			continue
+		}
+		reached[node.ID] = true // Report duplicates once
+		v.add(Diagnostic{Severity: SeverityWarning, Code: DiagUnreachableNode, NodeID: node.ID, Field: fmt.Sprintf("nodes[%d]", i),
+			Message:    fmt.Sprintf("Node %q is not reachable from any client and receives no traffic", node.ID),
+			Suggestion: "Connect it to a node that receives traffic, or remove it"})
+	}
+}
+
+// checkWorkload checks the RPS, mode, read/write mix and failures
+func (v *validator) checkWorkload() {
+	workload := v.input.Workload
+	scheduled := workload.Mode == WorkloadPiecewise || workload.Mode == WorkloadTrace
+	if scheduled && workload.Shape.LastTick() == 0 {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagMissingSchedule, Field: "workload.shape.points",
+			Message:    fmt.Sprintf("Workload mode %s needs shape.points", workload.Mode),
+			Suggestion: "Add {tick, rps} points, or upload a trace"})
+	}
+	if workload.RPS <= 0 && !scheduled {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagInvalidRPS, Field: "workload.rps",
+			Message: "RPS must be greater than 0"})
+	}
+	if workload.Mode != "" && !contains(workloadModes, workload.Mode) {
+		v.add(Diagnostic{Severity: SeverityWarning, Code: DiagUnknownWorkloadMode, Field: "workload.mode",
+			Message:    fmt.Sprintf("Unknown workload mode %q; the RPS is held constant", workload.Mode),
+			Suggestion: suggestion(workload.Mode, workloadModes)})
+	}
+	if workload.ReadWriteRatio.Read < 0 || workload.ReadWriteRatio.Write < 0 {
+		v.add(Diagnostic{Severity: SeverityError, Code: DiagInvalidReadWrite, Field: "workload.readWriteRatio",
+			Message: "Read and write shares can't be negative"})
+	}
+
+	edges := make(map[string]bool)
+	for _, edge := range v.input.Edges {
+		edges[edge.ID] = true
+	}
+	for i, failure := range workload.Failures {
+		path := fmt.Sprintf("workload.failures[%d]", i)
+		if !contains(failureTypes, string(failure.Type)) {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagUnknownFailureType, Field: path + ".type",
+				Message:    fmt.Sprintf("Unknown failure type %q", failure.Type),
+				Suggestion: suggestion(string(failure.Type), failureTypes)})
+		}
+		if _, ok := v.nodes[failure.NodeID]; failure.NodeID != "" && !ok {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagUnknownFailureNode, NodeID: failure.NodeID, Field: path + ".nodeId",
+				Message:    fmt.Sprintf("Failure targets node %q, which doesn't exist", failure.NodeID),
+				Suggestion: suggestion(failure.NodeID, v.order)})
+		}
+		if failure.EdgeID != "" && !edges[failure.EdgeID] {
+			v.add(Diagnostic{Severity: SeverityError, Code: DiagUnknownFailureEdge, EdgeID: failure.EdgeID, Field: path + ".edgeId",
+				Message: fmt.Sprintf("Failure targets edge %q, which doesn't exist", failure.EdgeID)})
+		}
+	}
+}
+
+// suggestion proposes the candidate closest to a mistyped value, or lists
+// the candidates when none is close
+func suggestion(value string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		d := editDistance(strings.ToLower(value), strings.ToLower(candidate))
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	if bestDistance <= maxInt(2, len(value)/3) {
+		return fmt.Sprintf("Did you mean %q?", best)
+	}
+	if len(candidates) > 12 {
+		return ""
+	}
+	return "Use one of: " + strings.Join(candidates, ", ")
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"reflect"
+	"testing"
+)
+
+// validationInput is a valid client -> api -> db chain to break in tests
+func validationInput() *SimulationInput {
+	return &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+			{ID: "db", Data: SimNodeData{NodeType: "database_postgres", Config: map[string]interface{}{}}},
+		},
+		Edges: []SimEdge{
+			{ID: "e1", Source: "client", Target: "api"},
+			{ID: "e2", Source: "api", Target: "db"},
+		},
+		Workload: WorkloadConfig{RPS: 100, Mode: "constant", DurationSeconds: 10},
+	}
+}
+
+func TestValidateInputCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(*SimulationInput)
+		rules    []ConnectionRule
+		errors   []string
+		warnings []string
+	}{
+		{name: "valid", change: func(*SimulationInput) {}},
+		{
+			name:   "no nodes",
+			change: func(in *SimulationInput) { in.Nodes, in.Edges = nil, nil },
+			errors: []string{DiagNoNodes},
+		},
+		{
+			name:   "duplicate node",
+			change: func(in *SimulationInput) { in.Nodes = append(in.Nodes, in.Nodes[2]) },
+			errors: []string{DiagDuplicateNodeID},
+		},
+		{
+			name: "unknown node and instance types",
+			change: func(in *SimulationInput) {
+				in.Nodes[1].Data.Config["instanceType"] = "t9.huge"
+				in.Nodes[2].Data.NodeType = "databse"
+			},
+			warnings: []string{DiagUnknownInstanceType, DiagUnknownNodeType},
+		},
+		{
+			name:   "invalid replicas",
+			change: func(in *SimulationInput) { in.Nodes[1].Data.Config["replicas"] = 0 },
+			errors: []string{DiagInvalidReplicas},
+		},
+		{
+			name: "dangling edge",
+			change: func(in *SimulationInput) {
+				in.Edges = append(in.Edges, SimEdge{ID: "e3", Source: "api", Target: "cache"})
+			},
+			errors: []string{DiagUnknownEdgeTarget},
+		},
+		{
+			name: "self loop",
+			change: func(in *SimulationInput) {
+				in.Edges = append(in.Edges, SimEdge{ID: "e3", Source: "api", Target: "api"})
+			},
+			warnings: []string{DiagSelfLoop},
+		},
+		{
+			name:   "connection rules",
+			change: func(*SimulationInput) {},
+			rules: []ConnectionRule{
+				{SourceType: "client", TargetType: "api_server"},
+				{SourceType: "api_server", TargetType: "cache_redis"},
+				{SourceType: "cache_redis", TargetType: "database_postgres"},
+			},
+			errors: []string{DiagConnectionNotAllowed},
+		},
+		{
+			name:     "unreachable node",
+			change:   func(in *SimulationInput) { in.Edges = in.Edges[:1] },
+			warnings: []string{DiagUnreachableNode},
+		},
+		{
+			name:   "workload",
+			change: func(in *SimulationInput) { in.Workload.RPS = 0; in.Workload.ReadWriteRatio.Write = -1 },
+			errors: []string{DiagInvalidRPS, DiagInvalidReadWrite},
+		},
+		{
+			name:   "missing schedule",
+			change: func(in *SimulationInput) { in.Workload.Mode = WorkloadTrace },
+			errors: []string{DiagMissingSchedule},
+		},
+		{
+			name: "failures",
+			change: func(in *SimulationInput) {
+				in.Workload.Failures = []FailureInjection{{Type: "meteor"}, {Type: FailureNodeFail, NodeID: "gone"}}
+			},
+			errors: []string{DiagUnknownFailureType, DiagUnknownFailureNode},
+		},
+	}
+
+	codes := func(diagnostics []Diagnostic) []string {
+		list := []string{}
+		for _, d := range diagnostics {
+			list = append(list, d.Code)
+		}
+		return list
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := validationInput()
+			tt.change(input)
+			result := ValidateInput(input, tt.rules)
+			errors, warnings := tt.errors, tt.warnings
+			if errors == nil {
+				errors = []string{}
+			}
+			if warnings == nil {
+				warnings = []string{}
+			}
+			if got := codes(result.Errors); !reflect.DeepEqual(got, errors) {
+				t.Errorf("errors %v, want %v", got, errors)
+			}
+			if got := codes(result.Warnings); !reflect.DeepEqual(got, warnings) {
+				t.Errorf("warnings %v, want %v", got, warnings)
+			}
+			if result.Valid != (len(errors) == 0) {
+				t.Errorf("valid = %v with errors %v", result.Valid, errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package simulation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workload modes beyond the built-in "constant", "burst" and "spike"
+const (
+	WorkloadRamp      = "ramp"      // Linear or stepped climb from shape.startRps to rps
+	WorkloadDiurnal   = "diurnal"   // Sinusoidal day/night cycle around rps
+	WorkloadPoisson   = "poisson"   // Poisson-distributed arrivals with mean rps
+	WorkloadPiecewise = "piecewise" // Explicit {tick, rps} schedule
+	WorkloadTrace     = "trace"     // Recorded RPS trace replayed tick by tick
+)
+
+// MaxTracePoints caps the size of an uploaded trace
+const MaxTracePoints = 100000
+
+// DefaultDiurnalAmplitude is the day/night swing around the mean when none is given
+const DefaultDiurnalAmplitude = 0.5
+
+// poissonNormalThreshold is the mean above which Poisson arrivals are drawn
+// from the normal approximation instead of Knuth's product method
+const poissonNormalThreshold = 30.0
+
+// shapedWorkload returns the RPS a shaped workload mode asks for at a tick.
+// ok is false for modes that aren't shapes.
+func (e *Engine) shapedWorkload(tick int) (rps float64, ok bool) {
+	baseRPS := float64(e.config.RPS)
+	shape := e.config.Shape
+	if shape == nil {
+		shape = &WorkloadShape{}
+	}
+
+	switch e.config.Mode {
+	case WorkloadRamp:
+		return shape.rampRPS(tick, baseRPS, e.config.DurationSeconds), true
+	case WorkloadDiurnal:
+		return shape.diurnalRPS(tick, baseRPS, e.config.DurationSeconds), true
+	case WorkloadPoisson:
+		return e.poissonArrivals(baseRPS), true
+	case WorkloadPiecewise:
+		return shape.scheduledRPS(tick, shape.Interpolate), true
+	case WorkloadTrace:
+		return shape.scheduledRPS(tick, false), true
+	default:
+		return 0, false
+	}
+}
+
+// rampRPS climbs from startRps to the workload rps over rampSeconds, then holds.
+// With steps set the climb happens in that many equal jumps.
+func (s *WorkloadShape) rampRPS(tick int, targetRPS float64, duration int) float64 {
+	rampTicks := s.RampSeconds
+	if rampTicks <= 0 {
+		rampTicks = duration
+	}
+	if rampTicks <= 0 {
+		return targetRPS
+	}
+
+	progress := math.Min(1, float64(tick-1)/math.Max(1, float64(rampTicks-1)))
+	if s.Steps > 0 {
+		progress = math.Floor(progress*float64(s.Steps)) / float64(s.Steps)
+	}
+	return s.StartRPS + (targetRPS-s.StartRPS)*progress
+}
+
+// diurnalRPS swings around the workload rps on a sine wave, peaking a quarter
+// period in (shifted by phaseSeconds)
+func (s *WorkloadShape) diurnalRPS(tick int, meanRPS float64, duration int) float64 {
+	period := s.PeriodSeconds
+	if period <= 0 {
+		period = duration
+	}
+	if period <= 0 {
+		return meanRPS
+	}
+	amplitude := s.Amplitude
+	if amplitude <= 0 {
+		amplitude = DefaultDiurnalAmplitude
+	}
+	amplitude = math.Min(1, amplitude)
+
+	angle := 2 * math.Pi * float64(tick-1+s.PhaseSeconds) / float64(period)
+	return math.Max(0, meanRPS*(1+amplitude*math.Sin(angle)))
+}
+
+// poissonArrivals draws one second of arrivals with the given mean
+func (e *Engine) poissonArrivals(mean float64) float64 {
+	if mean <= 0 {
+		return 0
+	}
+	if mean > poissonNormalThreshold {
+		return math.Max(0, math.Round(mean+math.Sqrt(mean)*e.rand.NormFloat64()))
+	}
+
+	limit := math.Exp(-mean)
+	arrivals, product := 0, e.rand.Float64()
+	for product > limit {
+		arrivals++
+		product *= e.rand.Float64()
+	}
+	return float64(arrivals)
+}
+
+// scheduledRPS looks a tick up in the shape's points. Between points the
+// previous value holds, or is linearly interpolated when interpolate is set;
+// before the first and after the last point their values hold.
+func (s *WorkloadShape) scheduledRPS(tick int, interpolate bool) float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	scale := s.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+
+	// First point past the tick
+	next := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].Tick > tick })
+	switch {
+	case next == 0:
+		return s.Points[0].RPS * scale
+	case next == len(s.Points) || !interpolate:
+		return s.Points[next-1].RPS * scale
+	}
+
+	prev, upcoming := s.Points[next-1], s.Points[next]
+	progress := float64(tick-prev.Tick) / float64(upcoming.Tick-prev.Tick)
+	return (prev.RPS + (upcoming.RPS-prev.RPS)*progress) * scale
+}
+
+// normalized returns the shape with its points sorted by tick so schedules
+// can be given in any order. A shape out of order is copied rather than
+// sorted in place: it belongs to the caller, and engines may share it.
+func (s *WorkloadShape) normalized() *WorkloadShape {
+	if s == nil || sort.SliceIsSorted(s.Points, func(i, j int) bool { return s.Points[i].Tick < s.Points[j].Tick }) {
+		return s
+	}
+	sorted := *s
+	sorted.Points = append([]WorkloadPoint(nil), s.Points...)
+	sort.SliceStable(sorted.Points, func(i, j int) bool { return sorted.Points[i].Tick < sorted.Points[j].Tick })
+	return &sorted
+}
+
+// LastTick returns the tick of the shape's last point (0 without points),
+// which is the natural duration of a replayed trace
+func (s *WorkloadShape) LastTick() int {
+	if s == nil || len(s.Points) == 0 {
+		return 0
+	}
+	last := 0
+	for _, point := range s.Points {
+		if point.Tick > last {
+			last = point.Tick
+		}
+	}
+	return last
+}
+
+// ParseWorkloadTrace reads a recorded RPS trace from CSV or JSON.
+//
+// CSV rows are either "rps", "tick,rps" or "timestamp,rps"; a non-numeric
+// first row is treated as a header. Timestamps are RFC 3339, or Unix seconds
+// when the first header column names a time. JSON is either an
+// array of numbers or an array of {"tick", "rps"} / {"timestamp", "rps"}
+// objects. Traces without ticks start at tick 1; timestamps become seconds
+// since the first sample.
+func ParseWorkloadTrace(data []byte, format string) ([]WorkloadPoint, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("trace is empty")
+	}
+	if format == "" {
+		format = "csv"
+		if trimmed[0] == '[' || trimmed[0] == '{' {
+			format = "json"
+		}
+	}
+
+	var points []WorkloadPoint
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		points, err = parseJSONTrace(trimmed)
+	case "csv":
+		points, err = parseCSVTrace(trimmed)
+	default:
+		return nil, fmt.Errorf("unsupported trace format %q (use csv or json)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("trace has no samples")
+	}
+	if len(points) > MaxTracePoints {
+		return nil, fmt.Errorf("trace has %d samples, the limit is %d", len(points), MaxTracePoints)
+	}
+	for _, point := range points {
+		if point.RPS < 0 || math.IsNaN(point.RPS) || math.IsInf(point.RPS, 0) {
+			return nil, fmt.Errorf("invalid rps %v at tick %d", point.RPS, point.Tick)
+		}
+	}
+
+	shape := &WorkloadShape{Points: points}
+	return shape.normalized().Points, nil
+}
+
+// traceSample is one JSON trace entry before its timestamp is resolved
+type traceSample struct {
+	Tick      *int            `json:"tick"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	RPS       float64         `json:"rps"`
+}
+
+func parseJSONTrace(data []byte) ([]WorkloadPoint, error) {
+	// Accept {"points": [...]} as well as a bare array
+	if data[0] == '{' {
+		var wrapper struct {
+			Points json.RawMessage `json:"points"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil || len(wrapper.Points) == 0 {
+			return nil, fmt.Errorf("JSON trace object must have a \"points\" array")
+		}
+		data = bytes.TrimSpace(wrapper.Points)
+	}
+
+	var values []float64
+	if err := json.Unmarshal(data, &values); err == nil {
+		points := make([]WorkloadPoint, len(values))
+		for i, value := range values {
+			points[i] = WorkloadPoint{Tick: i + 1, RPS: value}
+		}
+		return points, nil
+	}
+
+	var samples []traceSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("invalid JSON trace: %w", err)
+	}
+
+	points := make([]WorkloadPoint, len(samples))
+	var times []float64
+	for i, sample := range samples {
+		points[i] = WorkloadPoint{Tick: i + 1, RPS: sample.RPS}
+		switch {
+		case sample.Tick != nil:
+			points[i].Tick = *sample.Tick
+		case len(sample.Timestamp) > 0:
+			raw := strings.Trim(string(sample.Timestamp), `"`)
+			seconds, err := parseTraceTimestamp(raw)
+			if err != nil {
+				return nil, fmt.Errorf("sample %d: %w", i+1, err)
+			}
+			times = append(times, seconds)
+		}
+	}
+	if len(times) > 0 {
+		if len(times) != len(samples) {
+			return nil, fmt.Errorf("either every sample or none must have a timestamp")
+		}
+		ticksFromTimes(points, times)
+	}
+	return points, nil
+}
+
+func parseCSVTrace(data []byte) ([]WorkloadPoint, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var points []WorkloadPoint
+	var times []float64
+	timestamped := false // Set by a "timestamp"/"time" header on the first column
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV trace: %w", err)
+		}
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+
+		rps, err := strconv.ParseFloat(strings.TrimSpace(record[len(record)-1]), 64)
+		if err != nil {
+			if row == 1 {
+				timestamped = len(record) > 1 && strings.Contains(strings.ToLower(record[0]), "time")
+				continue // Header
+			}
+			return nil, fmt.Errorf("row %d: invalid rps %q", row, record[len(record)-1])
+		}
+
+		point := WorkloadPoint{Tick: len(points) + 1, RPS: rps}
+		if len(record) > 1 {
+			key := strings.TrimSpace(record[0])
+			if tick, err := strconv.Atoi(key); err == nil && !timestamped {
+				point.Tick = tick
+			} else {
+				seconds, err := parseTraceTimestamp(key)
+				if err != nil {
+					return nil, fmt.Errorf("row %d: %w", row, err)
+				}
+				times = append(times, seconds)
+			}
+		}
+		points = append(points, point)
+	}
+
+	if len(times) > 0 {
+		if len(times) != len(points) {
+			return nil, fmt.Errorf("either every row or none must have a timestamp")
+		}
+		ticksFromTimes(points, times)
+	}
+	return points, nil
+}
+
+// parseTraceTimestamp reads an RFC 3339 time or Unix seconds
+func parseTraceTimestamp(raw string) (float64, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return seconds, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q (use RFC 3339 or Unix seconds)", raw)
+	}
+	return float64(t.UnixNano()) / 1e9, nil
+}
+
+// ticksFromTimes makes the earliest sample tick 1 and the rest seconds after it
+func ticksFromTimes(points []WorkloadPoint, times []float64) {
+	first := times[0]
+	for _, t := range times {
+		first = math.Min(first, t)
+	}
+	for i := range points {
+		points[i].Tick = 1 + int(math.Round(times[i]-first))
+	}
+}
@@ -0,0 +1,35 @@
+package simulation
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Schedules given out of order run sorted, without reordering the caller's points
+func TestScheduleIsSortedWithoutTouchingInput(t *testing.T) {
+	seed := int64(1)
+	points := []WorkloadPoint{{Tick: 6, RPS: 300}, {Tick: 1, RPS: 100}, {Tick: 3, RPS: 200}}
+	given := append([]WorkloadPoint(nil), points...)
+	input := &SimulationInput{
+		Nodes: []SimNode{
+			{ID: "client", Data: SimNodeData{NodeType: "client", Config: map[string]interface{}{}}},
+			{ID: "api", Data: SimNodeData{NodeType: "api_server", Config: map[string]interface{}{}}},
+		},
+		Edges:    []SimEdge{{ID: "e1", Source: "client", Target: "api"}},
+		Workload: WorkloadConfig{Mode: WorkloadPiecewise, DurationSeconds: 6, Seed: &seed, Shape: &WorkloadShape{Points: points}},
+	}
+
+	output, err := NewEngine(input).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := output.TimeSeries[3].IncomingRPS; got != 200 {
+		t.Errorf("tick 4 ran at %v RPS, want 200", got)
+	}
+	if _, err := RunMonteCarlo(input, MonteCarloConfig{Iterations: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input.Workload.Shape.Points, given) {
+		t.Errorf("input points reordered to %v", input.Workload.Shape.Points)
+	}
+}